@@ -0,0 +1,212 @@
+// Package apispec serves OpenAPI documents of services and validates requests against them.
+// Services embed their documents and render rejected requests as their own problems.
+package apispec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// DefaultMaxBodySize limits JSON bodies read for validation when Config.MaxBodySize is zero
+const DefaultMaxBodySize = 1 << 20
+
+// FieldError describes invalid field of request body or query parameter
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// ValidationError is a request which does not match the document
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return "request does not match API specification"
+}
+
+// Config of Validator
+type Config struct {
+	//MaxBodySize limits JSON bodies read for validation, DefaultMaxBodySize when zero
+	MaxBodySize int64
+	//Reject responds to rejected request. Err is *ValidationError or the error of reading body,
+	//*http.MaxBytesError when the body is too large.
+	Reject func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Load parses and validates OpenAPI document
+func Load(data []byte) (*openapi3.T, error) {
+	const fn = "apispec.Load"
+
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return doc, nil
+}
+
+// Handler serves the document as JSON
+func Handler(doc *openapi3.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	}
+}
+
+// Validator returns middleware which checks path, query parameters and JSON bodies of requests against the document.
+// Requests of routes missing in the document are passed as is, router responds to them.
+// Bodies of other content types (CSV, JSON Lines) are streamed to handlers unchecked.
+func Validator(doc *openapi3.T, cfg Config) (func(http.Handler) http.Handler, error) {
+	const fn = "apispec.Validator"
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	maxBodySize := cfg.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			//validated request is a copy, chi routes "/path/" as "/path" and clients may omit Content-Type of JSON bodies
+			req := r.Clone(r.Context())
+			if path := strings.TrimSuffix(req.URL.Path, "/"); path != "" {
+				req.URL.Path = path
+			}
+
+			route, pathParams, err := router.FindRoute(req)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			contentType := req.Header.Get("Content-Type")
+			if contentType == "" && hasJSONBody(route.Operation) {
+				contentType = "application/json"
+				req.Header.Set("Content-Type", contentType)
+			}
+
+			//JSON body is read whole by validation, so it is limited before
+			validateBody := isJSON(contentType)
+			if validateBody && r.Body != nil && r.Body != http.NoBody {
+				body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+				if err != nil {
+					cfg.Reject(w, r, err)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				req.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					ExcludeRequestBody: !validateBody,
+					MultiError:         true,
+					AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				},
+			})
+			if err != nil {
+				cfg.Reject(w, r, &ValidationError{Fields: fieldErrors(err)})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+func hasJSONBody(operation *openapi3.Operation) bool {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return false
+	}
+	return operation.RequestBody.Value.Content.Get("application/json") != nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// fieldErrors converts validation errors to field errors
+func fieldErrors(err error) []FieldError {
+	//errors.As is not used here, MultiError matches any error it contains
+	if multiErr, ok := err.(openapi3.MultiError); ok {
+		var fields []FieldError
+		for _, err := range multiErr {
+			fields = append(fields, fieldErrors(err)...)
+		}
+		return fields
+	}
+
+	reqErr, ok := err.(*openapi3filter.RequestError)
+	if !ok {
+		return []FieldError{{Field: "request", Code: "invalid", Message: err.Error()}}
+	}
+
+	field := "body"
+	if reqErr.Parameter != nil {
+		field = reqErr.Parameter.Name
+	}
+
+	if schemaErrs, ok := reqErr.Err.(openapi3.MultiError); ok {
+		fields := make([]FieldError, 0, len(schemaErrs))
+		for _, err := range schemaErrs {
+			fields = append(fields, schemaFieldError(field, reqErr, err))
+		}
+		return fields
+	}
+
+	return []FieldError{schemaFieldError(field, reqErr, reqErr.Err)}
+}
+
+func schemaFieldError(field string, reqErr *openapi3filter.RequestError, err error) FieldError {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			if reqErr.Parameter != nil {
+				field += "."
+			} else {
+				field = ""
+			}
+			field += strings.Join(pointer, ".")
+		}
+		return FieldError{Field: field, Code: schemaErr.SchemaField, Message: schemaErr.Reason}
+	}
+
+	if errors.Is(err, openapi3filter.ErrInvalidRequired) {
+		return FieldError{Field: field, Code: "required", Message: fmt.Sprintf("%s is required", field)}
+	}
+
+	msg := reqErr.Reason
+	if err != nil {
+		msg = err.Error()
+	}
+
+	return FieldError{Field: field, Code: "invalid", Message: msg}
+}
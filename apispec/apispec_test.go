@@ -0,0 +1,136 @@
+package apispec
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const doc = `
+openapi: 3.0.3
+info:
+  title: test
+  version: "1"
+paths:
+  /wallets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            minimum: 1
+      responses:
+        "200":
+          description: Wallets
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                  minLength: 2
+          text/csv:
+            schema:
+              type: string
+      responses:
+        "201":
+          description: Created
+`
+
+// echo responds with the body it receives
+func echo(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Write(body)
+}
+
+func TestValidator(t *testing.T) {
+	spec, err := Load([]byte(doc))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var rejected error
+	validator, err := Validator(spec, Config{
+		MaxBodySize: 64,
+		Reject: func(w http.ResponseWriter, r *http.Request, err error) {
+			rejected = err
+			w.WriteHeader(http.StatusBadRequest)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Validator: %v", err)
+	}
+	h := validator(http.HandlerFunc(echo))
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		wantFields  []FieldError //nil when the request is passed
+		wantLarge   bool
+	}{
+		{name: "valid body", method: http.MethodPost, target: "/wallets", contentType: "application/json", body: `{"name":"alice"}`},
+		{name: "body without content type", method: http.MethodPost, target: "/wallets", body: `{"name":"alice"}`},
+		{name: "unknown route", method: http.MethodDelete, target: "/accounts", body: "anything"},
+		{name: "large CSV is not read", method: http.MethodPost, target: "/wallets", contentType: "text/csv", body: strings.Repeat("alice\n", 100)},
+		{
+			name: "invalid body", method: http.MethodPost, target: "/wallets", contentType: "application/json", body: `{"name":"a"}`,
+			wantFields: []FieldError{{Field: "name", Code: "minLength", Message: "minimum string length is 2"}},
+		},
+		{
+			name: "invalid query", method: http.MethodGet, target: "/wallets?limit=0",
+			wantFields: []FieldError{{Field: "limit", Code: "minimum", Message: "number must be at least 1"}},
+		},
+		{
+			name: "large JSON", method: http.MethodPost, target: "/wallets", contentType: "application/json",
+			body: `{"name":"` + strings.Repeat("a", 100) + `"}`, wantLarge: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejected = nil
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if tt.wantLarge {
+				var maxBytesErr *http.MaxBytesError
+				if !errors.As(rejected, &maxBytesErr) {
+					t.Errorf("rejected with %v, want MaxBytesError", rejected)
+				}
+				return
+			}
+
+			if tt.wantFields == nil {
+				if rejected != nil || rec.Body.String() != tt.body {
+					t.Errorf("rejected with %v, handler got %q", rejected, rec.Body.String())
+				}
+				return
+			}
+
+			var invalid *ValidationError
+			if !errors.As(rejected, &invalid) {
+				t.Fatalf("rejected with %v, want ValidationError", rejected)
+			}
+			if len(invalid.Fields) != len(tt.wantFields) || invalid.Fields[0] != tt.wantFields[0] {
+				t.Errorf("fields = %+v, want %+v", invalid.Fields, tt.wantFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kafka messages tell their encoding by content-type header, messages without it are JSON
+const (
+	ContentTypeHeader   = "content-type"
+	ContentTypeProtobuf = "application/protobuf"
+)
+
+// Encodings selected in configs
+const (
+	EncodingJSON     = "json"
+	EncodingProtobuf = "protobuf"
+)
+
+var (
+	ErrUnknownContentType = errors.New("unknown content type")
+	ErrUnknownEncoding    = errors.New("unknown encoding")
+)
+
+// Codec encodes events for producers and decodes them for consumers
+type Codec interface {
+	ContentType() string
+	Encode(event Event) ([]byte, error)
+	Decode(value []byte) (Message, error)
+}
+
+var (
+	JSON     Codec = jsonCodec{}
+	Protobuf Codec = protobufCodec{}
+)
+
+// CodecByName returns codec of encoding json or protobuf, JSON when name is empty
+func CodecByName(name string) (Codec, error) {
+	switch name {
+	case "", EncodingJSON:
+		return JSON, nil
+	case EncodingProtobuf:
+		return Protobuf, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownEncoding, name)
+	}
+}
+
+// CodecFor returns codec of content type, JSON when it is empty
+func CodecFor(contentType string) (Codec, error) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "", ContentTypeJSON, "application/cloudevents+json":
+		return JSON, nil
+	case ContentTypeProtobuf:
+		return Protobuf, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownContentType, contentType)
+	}
+}
+
+// DecodeMessage decodes value with codec of content type
+func DecodeMessage(contentType string, value []byte) (Message, error) {
+	codec, err := CodecFor(contentType)
+	if err != nil {
+		return Message{}, err
+	}
+	return codec.Decode(value)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonCodec) Encode(event Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func (jsonCodec) Decode(value []byte) (Message, error) {
+	return Decode(value)
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnknownType is returned by Message.DecodePayload for types which are not in Types
+var ErrUnknownType = errors.New("unknown event type")
+
+// Message is an event received by consumer, its payload is decoded when the type is known.
+// Legacy events have only type and payload, their other attributes are empty.
+// Payload is JSON data, it is empty in messages decoded from protobuf.
+type Message struct {
+	SpecVersion   string          `json:"specversion"`
+	ID            string          `json:"id"`
+	Source        string          `json:"source"`
+	Type          string          `json:"type"`
+	Subject       string          `json:"subject"`
+	Time          time.Time       `json:"time"`
+	SchemaVersion string          `json:"schemaversion"`
+	CorrelationID string          `json:"correlationid"`
+	PartitionKey  string          `json:"partitionkey"`
+	Sequence      int64           `json:"sequence"`
+	Payload       json.RawMessage `json:"data"`
+
+	decoded any //payload decoded by protobuf codec
+}
+
+// legacyEvent is the format of events sent before the envelope
+type legacyEvent struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Decode decodes JSON event of both formats, they are told apart by specversion attribute
+func Decode(value []byte) (Message, error) {
+	var msg Message
+	if err := json.Unmarshal(value, &msg); err != nil {
+		return Message{}, err
+	}
+
+	if msg.SpecVersion == "" {
+		var legacy legacyEvent
+		if err := json.Unmarshal(value, &legacy); err != nil {
+			return Message{}, err
+		}
+		return Message{Type: legacy.Type, Payload: legacy.Payload}, nil
+	}
+
+	if !strings.HasPrefix(msg.SpecVersion, "1.") {
+		return Message{}, fmt.Errorf("unsupported specversion %q", msg.SpecVersion)
+	}
+	if err := checkSchemaVersion(msg); err != nil {
+		return Message{}, err
+	}
+
+	return msg, nil
+}
+
+func checkSchemaVersion(msg Message) error {
+	if msg.SchemaVersion != "" && msg.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("unsupported schema version %q of event %s", msg.SchemaVersion, msg.ID)
+	}
+	return nil
+}
+
+// DecodePayload returns pointer to payload of the message type
+func (m Message) DecodePayload() (any, error) {
+	if m.decoded != nil {
+		return m.decoded, nil
+	}
+
+	payload, ok := NewPayload(m.Type)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, m.Type)
+	}
+	if err := json.Unmarshal(m.Payload, payload); err != nil {
+		return nil, fmt.Errorf("invalid payload of %s: %w", m.Type, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,155 @@
+// Package events is the contract of events which the wallet service sends to kafka
+// and other services consume. Both sides must use it instead of their own copies.
+package events
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+)
+
+const (
+	EventWalletCreated     = "Wallet_Created"
+	EventWalletDeleted     = "Wallet_Deleted"
+	EventWalletDeposited   = "Wallet_Deposited"
+	EventWalletWithdrawn   = "Wallet_Withdrawn"
+	EventWalletTransferred = "Wallet_Transfered"
+	EventWalletReceived    = "Wallet_Received"
+	EventLedgerMismatch    = "Ledger_Mismatch"
+)
+
+// Types are all event types
+var Types = []string{
+	EventWalletCreated,
+	EventWalletDeleted,
+	EventWalletDeposited,
+	EventWalletWithdrawn,
+	EventWalletTransferred,
+	EventWalletReceived,
+	EventLedgerMismatch,
+}
+
+// Attributes of events envelope
+const (
+	SpecVersion     = "1.0" //CloudEvents specification
+	Source          = "/wallet"
+	SchemaVersion   = "1" //version of payloads, changed on incompatible changes
+	ContentTypeJSON = "application/json"
+)
+
+// Event is an envelope of CloudEvents JSON format, Payload is its data.
+// Schema version, correlation ID, partition key and sequence are CloudEvents extension attributes.
+// Events before the envelope were {"type", "payload"}, Decode still reads them.
+//
+// Events are keyed by the wallet they are about, so events of a wallet stay in one partition and in order.
+// Sequence numbers events of the key wallet from 1 without gaps. A transfer is two events: Wallet_Transfered
+// is keyed and numbered by the sender wallet and Wallet_Received by the recipient one.
+// Events which are not about a wallet have neither key nor sequence.
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"` //wallet ID
+	Time            time.Time `json:"time,omitzero"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	SchemaVersion   string    `json:"schemaversion,omitempty"`
+	CorrelationID   string    `json:"correlationid,omitempty"`
+	PartitionKey    string    `json:"partitionkey,omitempty"` //kafka message key
+	Sequence        int64     `json:"sequence,omitempty"`
+	Payload         any       `json:"data"`
+}
+
+// New returns event with new ID about subject, it is keyed by subject and has no sequence number
+func New(eventType, subject, correlationID string, payload any) Event {
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              newID(),
+		Source:          Source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: ContentTypeJSON,
+		SchemaVersion:   SchemaVersion,
+		CorrelationID:   correlationID,
+		PartitionKey:    subject,
+		Payload:         payload,
+	}
+}
+
+// newID returns random UUID, consumers deduplicate events by it
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40 //version 4
+	b[8] = b[8]&0x3f | 0x80 //RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+type WalletCreatedPayload struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type WalletDeletedPayload struct {
+	ID string `json:"id"`
+}
+
+type WalletDepositedPayload struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+type WalletWithdrawnPayload struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+type WalletTransferredPayload struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	TransferTo string  `json:"transfer_to"`
+	Amount     float64 `json:"amount"`
+}
+
+// WalletReceivedPayload is the recipient side of transfer, the transfer itself is described by Wallet_Transfered
+type WalletReceivedPayload struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	TransferFrom string  `json:"transfer_from"`
+	Amount       float64 `json:"amount"`
+}
+
+// LedgerMismatchPayload is an alert of the reconciliation job
+type LedgerMismatchPayload struct {
+	ReportID      string  `json:"report_id"`
+	Discrepancies int     `json:"discrepancies"`
+	TotalBalance  float64 `json:"total_balance"`
+	Expected      float64 `json:"expected"`
+}
+
+// NewPayload returns pointer to zero payload of event type, false for unknown types
+func NewPayload(eventType string) (any, bool) {
+	switch eventType {
+	case EventWalletCreated:
+		return &WalletCreatedPayload{}, true
+	case EventWalletDeleted:
+		return &WalletDeletedPayload{}, true
+	case EventWalletDeposited:
+		return &WalletDepositedPayload{}, true
+	case EventWalletWithdrawn:
+		return &WalletWithdrawnPayload{}, true
+	case EventWalletTransferred:
+		return &WalletTransferredPayload{}, true
+	case EventWalletReceived:
+		return &WalletReceivedPayload{}, true
+	case EventLedgerMismatch:
+		return &LedgerMismatchPayload{}, true
+	default:
+		return nil, false
+	}
+}
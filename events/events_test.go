@@ -0,0 +1,127 @@
+package events_test
+
+import (
+	"errors"
+	"events"
+	"events/eventstest"
+	"reflect"
+	"testing"
+)
+
+func TestSamplesCoverTypes(t *testing.T) {
+	samples := eventstest.Samples()
+	legacy := eventstest.Legacy()
+	if len(samples) != len(events.Types) || len(legacy) != len(events.Types) {
+		t.Fatalf("%d samples and %d legacy events for %d types", len(samples), len(legacy), len(events.Types))
+	}
+
+	for i, eventType := range events.Types {
+		if samples[i].Type != eventType {
+			t.Errorf("sample %d has type %s, want %s", i, samples[i].Type, eventType)
+		}
+		if _, ok := events.NewPayload(eventType); !ok {
+			t.Errorf("no payload of %s", eventType)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, codec := range []events.Codec{events.JSON, events.Protobuf} {
+		for _, event := range eventstest.Samples() {
+			t.Run(codec.ContentType()+"/"+event.Type, func(t *testing.T) {
+				value, err := codec.Encode(event)
+				if err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+
+				msg, err := events.DecodeMessage(codec.ContentType(), value)
+				if err != nil {
+					t.Fatalf("DecodeMessage: %v", err)
+				}
+
+				if msg.ID != event.ID || msg.Type != event.Type || msg.Subject != event.Subject ||
+					msg.CorrelationID != event.CorrelationID || !msg.Time.Equal(event.Time) ||
+					msg.PartitionKey != event.PartitionKey || msg.Sequence != event.Sequence ||
+					msg.SpecVersion != events.SpecVersion || msg.SchemaVersion != events.SchemaVersion {
+					t.Errorf("envelope = %+v, want %+v", msg, event)
+				}
+
+				assertPayload(t, msg, event)
+			})
+		}
+	}
+}
+
+func TestCodecFor(t *testing.T) {
+	for contentType, want := range map[string]events.Codec{
+		"":                                events.JSON,
+		"application/json":                events.JSON,
+		"application/json; charset=utf-8": events.JSON,
+		"application/cloudevents+json":    events.JSON,
+		"application/protobuf":            events.Protobuf,
+	} {
+		codec, err := events.CodecFor(contentType)
+		if err != nil || codec != want {
+			t.Errorf("CodecFor(%q) = %v, %v, want %v", contentType, codec, err, want)
+		}
+	}
+
+	if _, err := events.CodecFor("application/avro"); !errors.Is(err, events.ErrUnknownContentType) {
+		t.Errorf("CodecFor of avro error = %v, want ErrUnknownContentType", err)
+	}
+	if _, err := events.CodecByName("avro"); !errors.Is(err, events.ErrUnknownEncoding) {
+		t.Errorf("CodecByName of avro error = %v, want ErrUnknownEncoding", err)
+	}
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	samples := eventstest.Samples()
+
+	for i, value := range eventstest.Legacy() {
+		t.Run(samples[i].Type, func(t *testing.T) {
+			msg, err := events.Decode(value)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if msg.Type != samples[i].Type || msg.ID != "" {
+				t.Errorf("envelope = %+v, want legacy %s", msg, samples[i].Type)
+			}
+
+			assertPayload(t, msg, samples[i])
+		})
+	}
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	for _, value := range []string{
+		`{"specversion":"2.0","id":"1","type":"Wallet_Created","data":{}}`,
+		`{"specversion":"1.0","id":"1","type":"Wallet_Created","schemaversion":"2","data":{}}`,
+		`not json`,
+	} {
+		if _, err := events.Decode([]byte(value)); err == nil {
+			t.Errorf("Decode(%s) succeeded", value)
+		}
+	}
+
+	msg, err := events.Decode([]byte(`{"specversion":"1.0","id":"1","type":"Wallet_Renamed","data":{}}`))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if _, err := msg.DecodePayload(); !errors.Is(err, events.ErrUnknownType) {
+		t.Errorf("DecodePayload error = %v, want ErrUnknownType", err)
+	}
+}
+
+func assertPayload(t *testing.T, msg events.Message, event events.Event) {
+	t.Helper()
+
+	payload, err := msg.DecodePayload()
+	if err != nil {
+		t.Fatalf("DecodePayload: %v", err)
+	}
+
+	got := reflect.ValueOf(payload).Elem().Interface()
+	if !reflect.DeepEqual(got, event.Payload) {
+		t.Errorf("payload = %+v, want %+v", got, event.Payload)
+	}
+}
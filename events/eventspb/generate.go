@@ -0,0 +1,4 @@
+// Package eventspb contains protobuf messages of wallet events generated from api/proto
+package eventspb
+
+//go:generate sh -c "cd .. && buf generate"
@@ -0,0 +1,69 @@
+// Package eventstest provides events of every type for contract tests of producers and consumers
+package eventstest
+
+import "events"
+
+// Samples returns an event of every type in events.Types, payloads have all fields set
+func Samples() []events.Event {
+	samples := []events.Event{
+		events.New(events.EventWalletCreated, "wallet-1", "req-1", events.WalletCreatedPayload{
+			ID:   "wallet-1",
+			Name: "Main",
+		}),
+		events.New(events.EventWalletDeleted, "wallet-1", "req-2", events.WalletDeletedPayload{
+			ID: "wallet-1",
+		}),
+		events.New(events.EventWalletDeposited, "wallet-1", "req-3", events.WalletDepositedPayload{
+			ID:     "wallet-1",
+			Name:   "Main",
+			Amount: 100.5,
+		}),
+		events.New(events.EventWalletWithdrawn, "wallet-1", "req-4", events.WalletWithdrawnPayload{
+			ID:     "wallet-1",
+			Name:   "Main",
+			Amount: 20.25,
+		}),
+		events.New(events.EventWalletTransferred, "wallet-1", "req-5", events.WalletTransferredPayload{
+			ID:         "wallet-1",
+			Name:       "Main",
+			TransferTo: "wallet-2",
+			Amount:     30,
+		}),
+		events.New(events.EventWalletReceived, "wallet-2", "req-5", events.WalletReceivedPayload{
+			ID:           "wallet-2",
+			Name:         "Savings",
+			TransferFrom: "wallet-1",
+			Amount:       30,
+		}),
+		events.New(events.EventLedgerMismatch, "", "", events.LedgerMismatchPayload{
+			ReportID:      "report-1",
+			Discrepancies: 2,
+			TotalBalance:  150,
+			Expected:      160,
+		}),
+	}
+
+	//events of every wallet in order
+	seqs := make(map[string]int64)
+	for i := range samples {
+		if subject := samples[i].Subject; subject != "" {
+			seqs[subject]++
+			samples[i].Sequence = seqs[subject]
+		}
+	}
+
+	return samples
+}
+
+// Legacy returns events of every type in the format before the envelope
+func Legacy() [][]byte {
+	return [][]byte{
+		[]byte(`{"type":"Wallet_Created","payload":{"id":"wallet-1","name":"Main"}}`),
+		[]byte(`{"type":"Wallet_Deleted","payload":{"id":"wallet-1"}}`),
+		[]byte(`{"type":"Wallet_Deposited","payload":{"id":"wallet-1","name":"Main","amount":100.5}}`),
+		[]byte(`{"type":"Wallet_Withdrawn","payload":{"id":"wallet-1","name":"Main","amount":20.25}}`),
+		[]byte(`{"type":"Wallet_Transfered","payload":{"id":"wallet-1","name":"Main","transfer_to":"wallet-2","amount":30}}`),
+		[]byte(`{"type":"Wallet_Received","payload":{"id":"wallet-2","name":"Savings","transfer_from":"wallet-1","amount":30}}`),
+		[]byte(`{"type":"Ledger_Mismatch","payload":{"report_id":"report-1","discrepancies":2,"total_balance":150,"expected":160}}`),
+	}
+}
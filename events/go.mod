@@ -0,0 +1,5 @@
+module events
+
+go 1.24.0
+
+require google.golang.org/protobuf v1.36.5
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"events/eventspb"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (protobufCodec) Encode(event Event) ([]byte, error) {
+	msg := &eventspb.Event{
+		SpecVersion:   event.SpecVersion,
+		Id:            event.ID,
+		Source:        event.Source,
+		Type:          event.Type,
+		Subject:       event.Subject,
+		SchemaVersion: event.SchemaVersion,
+		CorrelationId: event.CorrelationID,
+		PartitionKey:  event.PartitionKey,
+		Sequence:      event.Sequence,
+	}
+	if !event.Time.IsZero() {
+		msg.Time = timestamppb.New(event.Time)
+	}
+
+	//payloads may be given by value or by pointer
+	payload := reflect.Indirect(reflect.ValueOf(event.Payload))
+	if !payload.IsValid() {
+		return nil, fmt.Errorf("event %s has no payload", event.Type)
+	}
+
+	switch p := payload.Interface().(type) {
+	case WalletCreatedPayload:
+		msg.Payload = &eventspb.Event_WalletCreated{WalletCreated: &eventspb.WalletCreated{
+			Id:   p.ID,
+			Name: p.Name,
+		}}
+	case WalletDeletedPayload:
+		msg.Payload = &eventspb.Event_WalletDeleted{WalletDeleted: &eventspb.WalletDeleted{
+			Id: p.ID,
+		}}
+	case WalletDepositedPayload:
+		msg.Payload = &eventspb.Event_WalletDeposited{WalletDeposited: &eventspb.WalletDeposited{
+			Id:     p.ID,
+			Name:   p.Name,
+			Amount: p.Amount,
+		}}
+	case WalletWithdrawnPayload:
+		msg.Payload = &eventspb.Event_WalletWithdrawn{WalletWithdrawn: &eventspb.WalletWithdrawn{
+			Id:     p.ID,
+			Name:   p.Name,
+			Amount: p.Amount,
+		}}
+	case WalletTransferredPayload:
+		msg.Payload = &eventspb.Event_WalletTransferred{WalletTransferred: &eventspb.WalletTransferred{
+			Id:         p.ID,
+			Name:       p.Name,
+			TransferTo: p.TransferTo,
+			Amount:     p.Amount,
+		}}
+	case WalletReceivedPayload:
+		msg.Payload = &eventspb.Event_WalletReceived{WalletReceived: &eventspb.WalletReceived{
+			Id:           p.ID,
+			Name:         p.Name,
+			TransferFrom: p.TransferFrom,
+			Amount:       p.Amount,
+		}}
+	case LedgerMismatchPayload:
+		msg.Payload = &eventspb.Event_LedgerMismatch{LedgerMismatch: &eventspb.LedgerMismatch{
+			ReportId:      p.ReportID,
+			Discrepancies: int32(p.Discrepancies),
+			TotalBalance:  p.TotalBalance,
+			Expected:      p.Expected,
+		}}
+	default:
+		return nil, fmt.Errorf("%w %q: payload %T has no protobuf message", ErrUnknownType, event.Type, event.Payload)
+	}
+
+	return proto.Marshal(msg)
+}
+
+func (protobufCodec) Decode(value []byte) (Message, error) {
+	var event eventspb.Event
+	if err := proto.Unmarshal(value, &event); err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{
+		SpecVersion:   event.GetSpecVersion(),
+		ID:            event.GetId(),
+		Source:        event.GetSource(),
+		Type:          event.GetType(),
+		Subject:       event.GetSubject(),
+		SchemaVersion: event.GetSchemaVersion(),
+		CorrelationID: event.GetCorrelationId(),
+		PartitionKey:  event.GetPartitionKey(),
+		Sequence:      event.GetSequence(),
+	}
+	if event.GetTime() != nil {
+		msg.Time = event.GetTime().AsTime()
+	}
+
+	if err := checkSchemaVersion(msg); err != nil {
+		return Message{}, err
+	}
+
+	switch p := event.GetPayload().(type) {
+	case *eventspb.Event_WalletCreated:
+		msg.decoded = &WalletCreatedPayload{
+			ID:   p.WalletCreated.GetId(),
+			Name: p.WalletCreated.GetName(),
+		}
+	case *eventspb.Event_WalletDeleted:
+		msg.decoded = &WalletDeletedPayload{
+			ID: p.WalletDeleted.GetId(),
+		}
+	case *eventspb.Event_WalletDeposited:
+		msg.decoded = &WalletDepositedPayload{
+			ID:     p.WalletDeposited.GetId(),
+			Name:   p.WalletDeposited.GetName(),
+			Amount: p.WalletDeposited.GetAmount(),
+		}
+	case *eventspb.Event_WalletWithdrawn:
+		msg.decoded = &WalletWithdrawnPayload{
+			ID:     p.WalletWithdrawn.GetId(),
+			Name:   p.WalletWithdrawn.GetName(),
+			Amount: p.WalletWithdrawn.GetAmount(),
+		}
+	case *eventspb.Event_WalletTransferred:
+		msg.decoded = &WalletTransferredPayload{
+			ID:         p.WalletTransferred.GetId(),
+			Name:       p.WalletTransferred.GetName(),
+			TransferTo: p.WalletTransferred.GetTransferTo(),
+			Amount:     p.WalletTransferred.GetAmount(),
+		}
+	case *eventspb.Event_WalletReceived:
+		msg.decoded = &WalletReceivedPayload{
+			ID:           p.WalletReceived.GetId(),
+			Name:         p.WalletReceived.GetName(),
+			TransferFrom: p.WalletReceived.GetTransferFrom(),
+			Amount:       p.WalletReceived.GetAmount(),
+		}
+	case *eventspb.Event_LedgerMismatch:
+		msg.decoded = &LedgerMismatchPayload{
+			ReportID:      p.LedgerMismatch.GetReportId(),
+			Discrepancies: int(p.LedgerMismatch.GetDiscrepancies()),
+			TotalBalance:  p.LedgerMismatch.GetTotalBalance(),
+			Expected:      p.LedgerMismatch.GetExpected(),
+		}
+	default:
+		//payload added by a newer producer, consumers skip unknown types
+		return msg, nil
+	}
+
+	//payload must be of the event type, otherwise consumers would apply it as another event
+	if want, ok := NewPayload(msg.Type); !ok || reflect.TypeOf(want) != reflect.TypeOf(msg.decoded) {
+		return Message{}, fmt.Errorf("event %s of type %s has payload %T", msg.ID, msg.Type, msg.decoded)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,254 @@
+// Package registry keeps versions of event schemas in files and checks that new versions are backward compatible,
+// so consumers decoding old messages and producers sending new ones keep understanding each other.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrIncompatible is returned by Register and CheckBackward, it wraps every violation
+var ErrIncompatible = errors.New("schema is not backward compatible")
+
+// Field is a field of protobuf message
+type Field struct {
+	Number   int32  `json:"number"`
+	Name     string `json:"name"` //also the JSON name of payload field
+	Kind     string `json:"kind"`
+	Message  string `json:"message,omitempty"` //full name of message and enum kinds
+	Repeated bool   `json:"repeated,omitempty"`
+	Oneof    string `json:"oneof,omitempty"`
+}
+
+// Schema is a version of protobuf message
+type Schema struct {
+	Message         string   `json:"message"`
+	Version         int      `json:"version"`
+	Fields          []Field  `json:"fields"`
+	ReservedNumbers []int32  `json:"reserved_numbers,omitempty"`
+	ReservedNames   []string `json:"reserved_names,omitempty"`
+}
+
+// Describe returns schema of message descriptor, its version is 0 until registered
+func Describe(md protoreflect.MessageDescriptor) Schema {
+	schema := Schema{Message: string(md.FullName())}
+
+	fields := md.Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		field := Field{
+			Number:   int32(fd.Number()),
+			Name:     string(fd.Name()),
+			Kind:     fd.Kind().String(),
+			Repeated: fd.Cardinality() == protoreflect.Repeated,
+		}
+		if fd.Message() != nil {
+			field.Message = string(fd.Message().FullName())
+		}
+		if fd.Enum() != nil {
+			field.Message = string(fd.Enum().FullName())
+		}
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			field.Oneof = string(oneof.Name())
+		}
+		schema.Fields = append(schema.Fields, field)
+	}
+	slices.SortFunc(schema.Fields, func(a, b Field) int { return int(a.Number - b.Number) })
+
+	ranges := md.ReservedRanges()
+	for i := range ranges.Len() {
+		r := ranges.Get(i)
+		for n := r[0]; n < r[1]; n++ {
+			schema.ReservedNumbers = append(schema.ReservedNumbers, int32(n))
+		}
+	}
+	names := md.ReservedNames()
+	for i := range names.Len() {
+		schema.ReservedNames = append(schema.ReservedNames, string(names.Get(i)))
+	}
+
+	return schema
+}
+
+// sameFields reports whether schemas describe the same message regardless of versions
+func sameFields(a, b Schema) bool {
+	a.Version, b.Version = 0, 0
+	return reflect.DeepEqual(a, b)
+}
+
+// CheckBackward checks that messages of the old schema are read with the new one and vice versa:
+// fields keep their numbers, names and types, removed fields are reserved and reserved ones are not reused.
+func CheckBackward(old, new Schema) error {
+	var violations []error
+	violate := func(format string, args ...any) {
+		violations = append(violations, fmt.Errorf(format, args...))
+	}
+
+	newFields := make(map[int32]Field, len(new.Fields))
+	for _, field := range new.Fields {
+		newFields[field.Number] = field
+	}
+
+	for _, was := range old.Fields {
+		field, ok := newFields[was.Number]
+		if !ok {
+			if !slices.Contains(new.ReservedNumbers, was.Number) || !slices.Contains(new.ReservedNames, was.Name) {
+				violate("field %s = %d is removed without reserving its number and name", was.Name, was.Number)
+			}
+			continue
+		}
+
+		switch {
+		case field.Name != was.Name:
+			violate("field %d is renamed from %s to %s, JSON payloads would lose it", was.Number, was.Name, field.Name)
+		case field.Kind != was.Kind || field.Message != was.Message:
+			violate("field %s = %d changed type from %s to %s", was.Name, was.Number, typeName(was), typeName(field))
+		case field.Repeated != was.Repeated:
+			violate("field %s = %d changed cardinality", was.Name, was.Number)
+		case field.Oneof != was.Oneof:
+			violate("field %s = %d moved from oneof %q to %q", was.Name, was.Number, was.Oneof, field.Oneof)
+		}
+	}
+
+	for _, field := range new.Fields {
+		if slices.Contains(old.ReservedNumbers, field.Number) || slices.Contains(old.ReservedNames, field.Name) {
+			violate("field %s = %d reuses reserved number or name", field.Name, field.Number)
+		}
+	}
+	for _, number := range old.ReservedNumbers {
+		if !slices.Contains(new.ReservedNumbers, number) {
+			violate("reserved number %d is released", number)
+		}
+	}
+	for _, name := range old.ReservedNames {
+		if !slices.Contains(new.ReservedNames, name) {
+			violate("reserved name %s is released", name)
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: %s: %w", ErrIncompatible, new.Message, errors.Join(violations...))
+	}
+	return nil
+}
+
+func typeName(field Field) string {
+	if field.Message != "" {
+		return field.Message
+	}
+	return field.Kind
+}
+
+// Registry keeps schemas in dir as <message>/v<version>.json files
+type Registry struct {
+	dir string
+}
+
+func Open(dir string) *Registry {
+	return &Registry{dir: dir}
+}
+
+// Versions returns registered schemas of message, oldest first
+func (r *Registry) Versions(message string) ([]Schema, error) {
+	const fn = "registry.Versions"
+
+	entries, err := os.ReadDir(filepath.Join(r.dir, message))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var schemas []Schema
+	for _, entry := range entries {
+		if entry.IsDir() || !isVersionFile(entry.Name()) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(r.dir, message, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		var schema Schema
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", fn, entry.Name(), err)
+		}
+		schemas = append(schemas, schema)
+	}
+	slices.SortFunc(schemas, func(a, b Schema) int { return a.Version - b.Version })
+
+	return schemas, nil
+}
+
+// Latest returns the last registered schema of message, false when there is none
+func (r *Registry) Latest(message string) (Schema, bool, error) {
+	schemas, err := r.Versions(message)
+	if err != nil || len(schemas) == 0 {
+		return Schema{}, false, err
+	}
+	return schemas[len(schemas)-1], true, nil
+}
+
+// Check returns the latest registered version of schema and whether schema is registered.
+// An error wrapping ErrIncompatible is returned when schema can't be registered.
+func (r *Registry) Check(schema Schema) (Schema, bool, error) {
+	latest, ok, err := r.Latest(schema.Message)
+	if err != nil || !ok {
+		return latest, false, err
+	}
+	if sameFields(latest, schema) {
+		return latest, true, nil
+	}
+	return latest, false, CheckBackward(latest, schema)
+}
+
+// Register saves schema as the next version of its message unless it is registered already.
+// Incompatible schemas are not saved.
+func (r *Registry) Register(schema Schema) (Schema, error) {
+	const fn = "registry.Register"
+
+	latest, registered, err := r.Check(schema)
+	if err != nil {
+		return Schema{}, fmt.Errorf("%s: %w", fn, err)
+	}
+	if registered {
+		return latest, nil
+	}
+
+	schema.Version = latest.Version + 1
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return Schema{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(r.dir, schema.Message), 0o755); err != nil {
+		return Schema{}, fmt.Errorf("%s: %w", fn, err)
+	}
+	name := filepath.Join(r.dir, schema.Message, "v"+strconv.Itoa(schema.Version)+".json")
+	if err := os.WriteFile(name, append(data, '\n'), 0o644); err != nil {
+		return Schema{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return schema, nil
+}
+
+func isVersionFile(name string) bool {
+	version, ok := strings.CutPrefix(strings.TrimSuffix(name, ".json"), "v")
+	if !ok || !strings.HasSuffix(name, ".json") {
+		return false
+	}
+	_, err := strconv.Atoi(version)
+	return err == nil
+}
@@ -0,0 +1,102 @@
+package registry_test
+
+import (
+	"errors"
+	"events/registry"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func wallet(fields ...registry.Field) registry.Schema {
+	return registry.Schema{Message: "test.Wallet", Fields: fields}
+}
+
+var (
+	id      = registry.Field{Number: 1, Name: "id", Kind: "string"}
+	name    = registry.Field{Number: 2, Name: "name", Kind: "string"}
+	balance = registry.Field{Number: 3, Name: "balance", Kind: "double"}
+)
+
+func TestCheckBackward(t *testing.T) {
+	removedName := wallet(id)
+	removedName.ReservedNumbers = []int32{2}
+	removedName.ReservedNames = []string{"name"}
+
+	reuse := removedName
+	reuse.Fields = []registry.Field{id, {Number: 2, Name: "title", Kind: "string"}}
+
+	tests := []struct {
+		name     string
+		old, new registry.Schema
+		violates string
+	}{
+		{name: "same", old: wallet(id, name), new: wallet(id, name)},
+		{name: "added field", old: wallet(id, name), new: wallet(id, name, balance)},
+		{name: "removed reserved field", old: wallet(id, name), new: removedName},
+		{name: "removed field", old: wallet(id, name), new: wallet(id), violates: "removed without reserving"},
+		{name: "renamed field", old: wallet(id, name), new: wallet(id, registry.Field{Number: 2, Name: "title", Kind: "string"}), violates: "renamed"},
+		{name: "changed type", old: wallet(id, balance), new: wallet(id, registry.Field{Number: 3, Name: "balance", Kind: "string"}), violates: "changed type"},
+		{name: "changed cardinality", old: wallet(id), new: wallet(registry.Field{Number: 1, Name: "id", Kind: "string", Repeated: true}), violates: "cardinality"},
+		{name: "moved to oneof", old: wallet(id), new: wallet(registry.Field{Number: 1, Name: "id", Kind: "string", Oneof: "key"}), violates: "oneof"},
+		{name: "reused reserved", old: removedName, new: reuse, violates: "reuses reserved"},
+		{name: "released reserved", old: removedName, new: wallet(id), violates: "is released"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.CheckBackward(tt.old, tt.new)
+			if tt.violates == "" {
+				if err != nil {
+					t.Fatalf("CheckBackward: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, registry.ErrIncompatible) || !strings.Contains(err.Error(), tt.violates) {
+				t.Fatalf("CheckBackward error = %v, want violation %q", err, tt.violates)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	dir := t.TempDir()
+	reg := registry.Open(dir)
+
+	if _, ok, err := reg.Latest("test.Wallet"); ok || err != nil {
+		t.Fatalf("Latest of empty registry = %v, %v", ok, err)
+	}
+
+	first, err := reg.Register(wallet(id, name))
+	if err != nil || first.Version != 1 {
+		t.Fatalf("Register = v%d, %v, want v1", first.Version, err)
+	}
+
+	again, err := reg.Register(wallet(id, name))
+	if err != nil || again.Version != 1 {
+		t.Fatalf("Register of the same schema = v%d, %v, want v1", again.Version, err)
+	}
+
+	second, err := reg.Register(wallet(id, name, balance))
+	if err != nil || second.Version != 2 {
+		t.Fatalf("Register of compatible schema = v%d, %v, want v2", second.Version, err)
+	}
+
+	if _, err := reg.Register(wallet(id)); !errors.Is(err, registry.ErrIncompatible) {
+		t.Fatalf("Register of incompatible schema error = %v, want ErrIncompatible", err)
+	}
+
+	versions, err := reg.Versions("test.Wallet")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("Versions = %d, %v, want 2", len(versions), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test.Wallet", "v3.json")); !os.IsNotExist(err) {
+		t.Errorf("incompatible schema is saved: %v", err)
+	}
+
+	_, registered, err := reg.Check(wallet(id, name, balance))
+	if err != nil || !registered {
+		t.Errorf("Check of the latest schema = %v, %v", registered, err)
+	}
+}
@@ -0,0 +1,96 @@
+package events_test
+
+import (
+	"events"
+	"events/eventspb"
+	"events/eventstest"
+	"events/registry"
+	"flag"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var update = flag.Bool("update", false, "register changed schemas of events")
+
+// TestSchemaRegistry fails when protobuf messages of events changed and the change is not registered in schemas,
+// or is not backward compatible with the registered version. Compatible changes are registered with -update.
+func TestSchemaRegistry(t *testing.T) {
+	reg := registry.Open("schemas")
+
+	messages := eventspb.File_wallet_events_v1_events_proto.Messages()
+	for i := range messages.Len() {
+		schema := registry.Describe(messages.Get(i))
+
+		t.Run(schema.Message, func(t *testing.T) {
+			if *update {
+				registered, err := reg.Register(schema)
+				if err != nil {
+					t.Fatalf("Register: %v", err)
+				}
+				t.Logf("%s is v%d", schema.Message, registered.Version)
+				return
+			}
+
+			latest, registered, err := reg.Check(schema)
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if latest.Version == 0 {
+				t.Fatalf("%s is not registered, register it with go test -run TestSchemaRegistry -update", schema.Message)
+			}
+			if !registered {
+				t.Fatalf("%s differs from registered v%d, register it with go test -run TestSchemaRegistry -update", schema.Message, latest.Version)
+			}
+		})
+	}
+}
+
+// TestPayloadFields checks that JSON names of payload fields are the names of protobuf fields,
+// so the registry guards JSON encoding too
+func TestPayloadFields(t *testing.T) {
+	payloads := (&eventspb.Event{}).ProtoReflect().Descriptor().Oneofs().ByName("payload").Fields()
+
+	for _, event := range eventstest.Samples() {
+		encoded, err := events.Protobuf.Encode(event)
+		if err != nil {
+			t.Fatalf("Encode %s: %v", event.Type, err)
+		}
+
+		msg := &eventspb.Event{}
+		if err := proto.Unmarshal(encoded, msg); err != nil {
+			t.Fatalf("Unmarshal %s: %v", event.Type, err)
+		}
+		field := msg.ProtoReflect().WhichOneof(payloads.Get(0).ContainingOneof())
+		if field == nil {
+			t.Fatalf("%s has no payload", event.Type)
+		}
+
+		if got, want := jsonNames(reflect.TypeOf(event.Payload)), protoNames(field.Message()); !slices.Equal(got, want) {
+			t.Errorf("%s payload fields = %v, protobuf %s fields = %v", event.Type, got, field.Message().FullName(), want)
+		}
+	}
+}
+
+func jsonNames(payload reflect.Type) []string {
+	var names []string
+	for i := range payload.NumField() {
+		name, _, _ := strings.Cut(payload.Field(i).Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func protoNames(md protoreflect.MessageDescriptor) []string {
+	var names []string
+	for i := range md.Fields().Len() {
+		names = append(names, string(md.Fields().Get(i).Name()))
+	}
+	slices.Sort(names)
+	return names
+}
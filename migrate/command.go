@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// ErrUnknownCommand is returned for migrate commands other than up, down and status
+var ErrUnknownCommand = errors.New("unknown migrate command, use up, down or status")
+
+// Command is "migrate" command of services.
+//
+//	app migrate [up|down|status] [-steps n]
+type Command struct {
+	Name  string //up, down or status
+	Steps int    //migrations reverted by down
+}
+
+// ParseCommand parses arguments of "migrate" command, up is the default
+func ParseCommand(args []string) (Command, error) {
+	command := Command{Name: "up"}
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command.Name, args = args[0], args[1:]
+	}
+
+	switch command.Name {
+	case "up", "down", "status":
+	default:
+		return command, ErrUnknownCommand
+	}
+
+	flags := flag.NewFlagSet("migrate "+command.Name, flag.ContinueOnError)
+	flags.IntVar(&command.Steps, "steps", 1, "number of migrations to revert by down")
+
+	if err := flags.Parse(args); err != nil {
+		return command, err
+	}
+
+	return command, nil
+}
+
+// Run runs command with m and prints applied or reverted migrations, status is printed as a table
+func (c Command) Run(ctx context.Context, m *Migrator, out io.Writer) error {
+	switch c.Name {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "down":
+		reverted, err := m.Down(ctx, c.Steps)
+		for _, migration := range reverted {
+			fmt.Fprintf(out, "reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		states, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, state := range states {
+			appliedAt := "pending"
+			if !state.AppliedAt.IsZero() {
+				appliedAt = state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", state.Version, state.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return ErrUnknownCommand
+	}
+}
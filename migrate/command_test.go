@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    Command
+		wantErr bool
+	}{
+		{args: nil, want: Command{Name: "up", Steps: 1}},
+		{args: []string{"up"}, want: Command{Name: "up", Steps: 1}},
+		{args: []string{"status"}, want: Command{Name: "status", Steps: 1}},
+		{args: []string{"down", "-steps", "3"}, want: Command{Name: "down", Steps: 3}},
+		{args: []string{"-steps", "2"}, want: Command{Name: "up", Steps: 2}},
+		{args: []string{"redo"}, wantErr: true},
+		{args: []string{"down", "-steps", "many"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCommand(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCommand(%q) error = %v, want error %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseCommand(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+
+	if _, err := ParseCommand([]string{"redo"}); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("unknown command: got %v, want %v", err, ErrUnknownCommand)
+	}
+}
+
+func TestCommandRun(t *testing.T) {
+	ctx := context.Background()
+	m := newMigrator(t, openSQLite(t, filepath.Join(t.TempDir(), "test.db")), scripts())
+
+	run := func(args ...string) string {
+		t.Helper()
+
+		command, err := ParseCommand(args)
+		if err != nil {
+			t.Fatalf("ParseCommand(%q): %v", args, err)
+		}
+
+		var out bytes.Buffer
+		if err := command.Run(ctx, m, &out); err != nil {
+			t.Fatalf("Run(%q): %v", args, err)
+		}
+		return out.String()
+	}
+
+	if got, want := run("up"), "applied 0001_create_a\napplied 0002_create_b\napplied 0003_fill_a\n"; got != want {
+		t.Errorf("up printed %q, want %q", got, want)
+	}
+	if got, want := run("down", "-steps", "1"), "reverted 0003_fill_a\n"; got != want {
+		t.Errorf("down printed %q, want %q", got, want)
+	}
+
+	status := strings.Split(strings.TrimSpace(run("status")), "\n")
+	if len(status) != 4 || !strings.HasPrefix(status[0], "VERSION") {
+		t.Fatalf("status printed %q", status)
+	}
+	if fields := strings.Fields(status[1]); len(fields) != 3 || fields[0] != "0001" || fields[1] != "create_a" || fields[2] == "pending" {
+		t.Errorf("status of applied migration = %q", status[1])
+	}
+	if fields := strings.Fields(status[3]); len(fields) != 3 || fields[0] != "0003" || fields[1] != "fill_a" || fields[2] != "pending" {
+		t.Errorf("status of reverted migration = %q", status[3])
+	}
+}
@@ -0,0 +1,8 @@
+module migrate
+
+go 1.24.0
+
+require (
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.24
+)
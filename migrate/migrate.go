@@ -0,0 +1,338 @@
+// Package migrate applies versioned SQL migrations embedded into services.
+// Services sharing a database keep applied versions in their own tables.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// SQL dialects of migration sets
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+var (
+	ErrUnknownDialect = errors.New("unknown migration dialect")
+	ErrInvalidTable   = errors.New("invalid migrations table name")
+	ErrNoDownScript   = errors.New("migration has no down script")
+	ErrUnknownVersion = errors.New("database has migration unknown to this build")
+)
+
+// Migration is a pair of scripts NNNN_name.up.sql and NNNN_name.down.sql
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State is a migration with its applying time, AppliedAt is zero for pending migrations
+type State struct {
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+}
+
+// Config tells migrations of services sharing a database apart
+type Config struct {
+	Table   string //keeps applied versions
+	LockKey int64  //postgres advisory lock held while migrations are applied
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	config     Config
+	migrations []Migration
+}
+
+var (
+	fileName  = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	tableName = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+)
+
+// New reads migration scripts from the root of fsys
+func New(db *sql.DB, dialect string, fsys fs.FS, config Config) (*Migrator, error) {
+	const fn = "migrate.New"
+
+	if dialect != Postgres && dialect != SQLite {
+		return nil, fmt.Errorf("%s: %w: %q", fn, ErrUnknownDialect, dialect)
+	}
+	if !tableName.MatchString(config.Table) {
+		return nil, fmt.Errorf("%s: %w: %q", fn, ErrInvalidTable, config.Table)
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	byVersion := map[int64]*Migration{}
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid version of %s: %w", fn, entry.Name(), err)
+		}
+
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%s: version %d has different names %q and %q", fn, version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("%s: migration %d_%s has no up script", fn, migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		config:     config,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies all pending migrations in version order
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	const fn = "migrate.Up"
+
+	var done []Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			err := m.apply(ctx, conn, migration.Up, func(tx execer) error {
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO `+m.config.Table+` (version, name, applied_at) VALUES (`+m.arg(1)+`, `+m.arg(2)+`, `+m.arg(3)+`)`,
+					migration.Version, migration.Name, time.Now().UTC().Format(time.RFC3339Nano),
+				)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return done, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	const fn = "migrate.Down"
+
+	var done []Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("%d_%s: %w", migration.Version, migration.Name, ErrNoDownScript)
+			}
+
+			err := m.apply(ctx, conn, migration.Down, func(tx execer) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM `+m.config.Table+` WHERE version = `+m.arg(1), migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return done, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return done, nil
+}
+
+// Status lists all known migrations
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	const fn = "migrate.Status"
+
+	var states []State
+
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			states = append(states, State{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: applied[migration.Version],
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return states, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// locked runs fn on a dedicated connection while other instances are locked out.
+// Postgres uses session advisory lock, every migration is applied in its own transaction.
+// SQLite has no such locks, so fn runs inside one BEGIN IMMEDIATE transaction holding the write lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]time.Time) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	switch m.dialect {
+	case Postgres:
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, m.config.LockKey); err != nil {
+			return fmt.Errorf("failed to acquire lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, m.config.LockKey)
+	case SQLite:
+		if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+			return fmt.Errorf("failed to acquire lock: %w", err)
+		}
+		defer func() {
+			if err != nil {
+				conn.ExecContext(context.Background(), `ROLLBACK`)
+				return
+			}
+			if _, err = conn.ExecContext(ctx, `COMMIT`); err != nil {
+				err = fmt.Errorf("failed to commit: %w", err)
+			}
+		}()
+	}
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.config.Table+`(
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TEXT NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM `+m.config.Table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt string
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		if !known[version] {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+
+		applied[version], _ = time.Parse(time.RFC3339Nano, appliedAt)
+	}
+
+	return applied, rows.Err()
+}
+
+// apply runs script and records it. Postgres migrations are transactional one by one,
+// SQLite ones are already inside the locking transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, record func(tx execer) error) error {
+	if m.dialect == SQLite {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		return record(conn)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) arg(n int) string {
+	if m.dialect == Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
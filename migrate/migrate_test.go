@@ -0,0 +1,367 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// dsnEnv points to a disposable postgres database for tests of advisory lock
+const dsnEnv = "MIGRATE_TEST_POSTGRES_DSN"
+
+var testConfig = Config{Table: "test_schema_migrations", LockKey: 0x74657374}
+
+func scripts() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_a.up.sql":   {Data: []byte(`CREATE TABLE a(id INTEGER);`)},
+		"0001_create_a.down.sql": {Data: []byte(`DROP TABLE a;`)},
+		"0002_create_b.up.sql":   {Data: []byte(`CREATE TABLE b(id INTEGER);`)},
+		"0002_create_b.down.sql": {Data: []byte(`DROP TABLE b;`)},
+		"0003_fill_a.up.sql":     {Data: []byte(`INSERT INTO a(id) VALUES (1), (2);`)},
+		"0003_fill_a.down.sql":   {Data: []byte(`DELETE FROM a;`)},
+		"README.md":              {Data: []byte(`not a migration`)},
+	}
+}
+
+func openSQLite(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func newMigrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *Migrator {
+	t.Helper()
+
+	m, err := New(db, SQLite, fsys, testConfig)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return m
+}
+
+func versions(migrations []Migration) []int64 {
+	var v []int64
+	for _, migration := range migrations {
+		v = append(v, migration.Version)
+	}
+	return v
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count); err != nil {
+		t.Fatalf("check table %s: %v", name, err)
+	}
+	return count > 0
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+	m := newMigrator(t, db, scripts())
+
+	states, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(states) != 3 {
+		t.Fatalf("Status has %d migrations, want 3", len(states))
+	}
+	for _, state := range states {
+		if !state.AppliedAt.IsZero() {
+			t.Errorf("migration %d is applied before Up", state.Version)
+		}
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if got := versions(applied); len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Errorf("Up applied %v, want [1 2 3]", got)
+	}
+
+	applied, err = m.Up(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("second Up applied %v, %v, want nothing", versions(applied), err)
+	}
+
+	reverted, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if got := versions(reverted); len(got) != 2 || got[0] != 3 || got[1] != 2 {
+		t.Errorf("Down reverted %v, want [3 2]", got)
+	}
+	if tableExists(t, db, "b") || !tableExists(t, db, "a") {
+		t.Errorf("Down 2 must drop b and keep a")
+	}
+
+	states, err = m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, state := range states {
+		if applied := !state.AppliedAt.IsZero(); applied != (state.Version == 1) {
+			t.Errorf("migration %d applied = %v", state.Version, applied)
+		}
+	}
+
+	applied, err = m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if got := versions(applied); len(got) != 2 || got[0] != 2 {
+		t.Errorf("Up after Down applied %v, want [2 3]", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+
+	if _, err := New(db, "mysql", scripts(), testConfig); !errors.Is(err, ErrUnknownDialect) {
+		t.Errorf("unknown dialect: got %v, want %v", err, ErrUnknownDialect)
+	}
+
+	for _, table := range []string{"", "a; DROP TABLE a", "1table"} {
+		if _, err := New(db, SQLite, scripts(), Config{Table: table}); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("table %q: got %v, want %v", table, err, ErrInvalidTable)
+		}
+	}
+
+	fsys := scripts()
+	fsys["0002_other_name.down.sql"] = &fstest.MapFile{Data: []byte(`SELECT 1;`)}
+	if _, err := New(db, SQLite, fsys, testConfig); err == nil {
+		t.Errorf("different names of one version are accepted")
+	}
+
+	fsys = scripts()
+	delete(fsys, "0002_create_b.up.sql")
+	if _, err := New(db, SQLite, fsys, testConfig); err == nil {
+		t.Errorf("migration without up script is accepted")
+	}
+}
+
+// TestUnknownVersion checks that a build older than the database refuses to migrate it
+func TestUnknownVersion(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+
+	if _, err := newMigrator(t, db, scripts()).Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	old := scripts()
+	delete(old, "0003_fill_a.up.sql")
+	delete(old, "0003_fill_a.down.sql")
+	m := newMigrator(t, db, old)
+
+	if _, err := m.Up(ctx); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Up: got %v, want %v", err, ErrUnknownVersion)
+	}
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Down: got %v, want %v", err, ErrUnknownVersion)
+	}
+	if _, err := m.Status(ctx); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Status: got %v, want %v", err, ErrUnknownVersion)
+	}
+}
+
+func TestNoDownScript(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+
+	fsys := scripts()
+	delete(fsys, "0003_fill_a.down.sql")
+	m := newMigrator(t, db, fsys)
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if reverted, err := m.Down(ctx, 1); !errors.Is(err, ErrNoDownScript) || len(reverted) != 0 {
+		t.Errorf("Down: got %v, %v, want %v", versions(reverted), err, ErrNoDownScript)
+	}
+}
+
+// TestFailedMigration checks that SQLite migrations of one Up are rolled back together
+func TestFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+
+	fsys := scripts()
+	fsys["0003_fill_a.up.sql"] = &fstest.MapFile{Data: []byte(`INSERT INTO missing(id) VALUES (1);`)}
+
+	if _, err := newMigrator(t, db, fsys).Up(ctx); err == nil || !strings.Contains(err.Error(), "fill_a") {
+		t.Fatalf("Up: got %v, want failure of fill_a", err)
+	}
+	if tableExists(t, db, "a") {
+		t.Errorf("migrations before the failed one are not rolled back")
+	}
+}
+
+// TestSeparateTables checks that services sharing a database don't see migrations of each other
+func TestSeparateTables(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+
+	if _, err := newMigrator(t, db, scripts()).Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	other := fstest.MapFS{"0001_create_c.up.sql": {Data: []byte(`CREATE TABLE c(id INTEGER);`)}}
+	m, err := New(db, SQLite, other, Config{Table: "other_schema_migrations"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 1 {
+		t.Errorf("Up of other table: got %v, %v, want [1]", versions(applied), err)
+	}
+}
+
+// TestConcurrentUp checks that migrations run by several instances at once are applied once
+func TestConcurrentUp(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	const instances = 5
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		applied []int64
+	)
+	for range instances {
+		m := newMigrator(t, openSQLite(t, path), scripts())
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			done, err := m.Up(ctx)
+			if err != nil {
+				t.Errorf("Up: %v", err)
+			}
+
+			mu.Lock()
+			applied = append(applied, versions(done)...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(applied) != 3 {
+		t.Errorf("instances applied %v, want every migration once", applied)
+	}
+}
+
+// TestLockBusy checks that migrations are not applied while another instance holds the lock
+func TestLockBusy(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	holder, err := openSQLite(t, path).Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	defer holder.Close()
+	if _, err := holder.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		t.Fatalf("BEGIN IMMEDIATE: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=50")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	m := newMigrator(t, db, scripts())
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "failed to acquire lock") {
+		t.Fatalf("Up under lock: got %v, want lock failure", err)
+	}
+
+	if _, err := holder.ExecContext(ctx, `ROLLBACK`); err != nil {
+		t.Fatalf("ROLLBACK: %v", err)
+	}
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 3 {
+		t.Errorf("Up after unlock: got %v, %v", versions(applied), err)
+	}
+}
+
+// TestAdvisoryLock checks that postgres instances wait for the advisory lock of each other
+func TestAdvisoryLock(t *testing.T) {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+
+	ctx := context.Background()
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"0001_create_lock_test.up.sql":   {Data: []byte(`CREATE TABLE lock_test(id INTEGER);`)},
+		"0001_create_lock_test.down.sql": {Data: []byte(`DROP TABLE lock_test;`)},
+	}
+	cleanup := func() {
+		db.ExecContext(ctx, `DROP TABLE IF EXISTS lock_test`)
+		db.ExecContext(ctx, `DROP TABLE IF EXISTS `+testConfig.Table)
+	}
+	cleanup()
+	defer cleanup()
+
+	holder, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	defer holder.Close()
+	if _, err := holder.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, testConfig.LockKey); err != nil {
+		t.Fatalf("pg_advisory_lock: %v", err)
+	}
+
+	m, err := New(db, Postgres, fsys, testConfig)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.Up(ctx)
+		done <- err
+	}()
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass('lock_test') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatalf("check table: %v", err)
+	}
+	if exists {
+		t.Errorf("migration is applied while the lock is held")
+	}
+
+	if _, err := holder.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, testConfig.LockKey); err != nil {
+		t.Fatalf("pg_advisory_unlock: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Up after unlock: %v", err)
+	}
+}
@@ -2,11 +2,26 @@ package main
 
 import (
 	"log"
+	"os"
 	"stats/internal/app"
 )
 
 func main() {
-	if err := app.Run(); err != nil {
+	var err error
+
+	//subcommands, without arguments the server is started
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			err = app.Migrate(os.Args[2:])
+		default:
+			log.Fatalf("Unknown command %q. Available commands: migrate", os.Args[1])
+		}
+	} else {
+		err = app.Run()
+	}
+
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -1,10 +1,12 @@
 package app
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
 	"stats/internal/config"
+	"stats/internal/http/openapi"
 	"stats/internal/kafka"
 	logger "stats/internal/logger/slog"
 	chirouter "stats/internal/router/chi"
@@ -30,6 +32,14 @@ func Run() error {
 	}
 	defer storage.Close()
 
+	//Apply schema migrations
+	if config.DBServer.AutoMigrate {
+		if err := migrateUp(context.Background(), storage, log); err != nil {
+			log.Error("Can't migrate storage: ", logger.Err(err))
+			os.Exit(1)
+		}
+	}
+
 	//Init kafka consumer
 	kafkaConsumer, err := kafka.NewConsumer(config.Brokers, config.Topic, storage, log)
 	if err != nil {
@@ -44,9 +54,23 @@ func Run() error {
 	// Init service
 	statsService := service.New(storage)
 
+	// Load API specification
+	spec, err := openapi.Load()
+	if err != nil {
+		log.Error("Can't load API specification: ", logger.Err(err))
+		os.Exit(1)
+	}
+	validator, err := openapi.Validator(spec)
+	if err != nil {
+		log.Error("Can't init request validator: ", logger.Err(err))
+		os.Exit(1)
+	}
+
 	// Init router
 	router := chi.NewRouter()
+	chirouter.InitMiddleware(router, validator)
 	chirouter.InitWallet(router, statsService)
+	chirouter.InitOpenAPI(router, spec)
 
 	srv := &http.Server{
 		Addr:         config.Address,
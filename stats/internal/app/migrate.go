@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"migrate"
+	"os"
+	"stats/internal/config"
+	"stats/internal/storage/postgre"
+)
+
+// Migrate applies or reverts schema migrations.
+//
+//	app migrate [up|down|status] [-steps n]
+func Migrate(args []string) error {
+	command, err := migrate.ParseCommand(args)
+	if err != nil {
+		return err
+	}
+
+	//Load config
+	config := config.MustLoad()
+
+	//Init storage
+	storage, err := postgre.New(config.DBServer.Host, config.DBServer.Port)
+	if err != nil {
+		return fmt.Errorf("can't init storage: %w", err)
+	}
+	defer storage.Close()
+
+	migrator, err := storage.Migrator()
+	if err != nil {
+		return err
+	}
+
+	return command.Run(context.Background(), migrator, os.Stdout)
+}
+
+type migratable interface {
+	Migrator() (*migrate.Migrator, error)
+}
+
+// migrateUp applies pending migrations at startup
+func migrateUp(ctx context.Context, storage migratable, log *slog.Logger) error {
+	migrator, err := storage.Migrator()
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Info("Migration applied", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+	}
+
+	return err
+}
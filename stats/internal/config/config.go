@@ -29,6 +29,8 @@ type Kafka struct {
 type DBServer struct {
 	Host string `yaml:"host" env-required:"true"`
 	Port int    `yaml:"port" env-required:"true"`
+	//AutoMigrate applies pending migrations at startup, otherwise run "migrate" command
+	AutoMigrate bool `yaml:"auto_migrate" env-default:"true"`
 }
 
 const configPath = "internal/config/local.yaml"
@@ -16,7 +16,6 @@ type Response struct {
 	Deposited  float64 `json:"deposited"`
 	Withdrawn  float64 `json:"withdrawn"`
 	Transfered float64 `json:"transfered"`
-	ErrCode    string  `json:"err_code,omitempty"`
 }
 
 type StatsRecipient interface {
@@ -28,7 +27,7 @@ func GetStatsHandler(recipient StatsRecipient) http.HandlerFunc {
 
 		stats, err := recipient.GetStats(r.Context())
 		if err != nil {
-			render.JSON(w, r, Response{ErrCode: err.Error()})
+			RespondError(w, r, err)
 			return
 		}
 
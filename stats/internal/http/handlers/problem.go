@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix makes problem type URI from error code
+const problemTypePrefix = "urn:stats:problem:"
+
+// Problem is an error response body, RFC 7807.
+// Code is stable and identifies the problem, Detail is a human readable explanation of this occurrence.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes invalid field of request body or query parameter
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// requestError is an invalid request along with its invalid fields
+type requestError struct {
+	msg    string
+	fields []FieldError
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+// InvalidFields is an error of request validation, it is rendered with the list of invalid fields
+func InvalidFields(msg string, fields []FieldError) error {
+	return &requestError{msg: msg, fields: fields}
+}
+
+// RespondError renders request validation errors as bad requests and too large bodies as 413,
+// other errors are logged and reported as internal
+func RespondError(w http.ResponseWriter, r *http.Request, err error) {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		WriteProblem(w, r, Problem{
+			Type:   problemTypePrefix + "validation_failed",
+			Title:  "Invalid argument",
+			Status: http.StatusBadRequest,
+			Detail: reqErr.msg,
+			Code:   "validation_failed",
+			Errors: reqErr.fields,
+		})
+		return
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		WriteProblem(w, r, Problem{
+			Type:   problemTypePrefix + "request_too_large",
+			Title:  "Request too large",
+			Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("Request body is larger than %d bytes", maxBytesErr.Limit),
+			Code:   "request_too_large",
+		})
+		return
+	}
+
+	slog.Error("Request failed",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+		slog.String("error", err.Error()),
+	)
+
+	WriteProblem(w, r, Problem{
+		Type:   problemTypePrefix + "internal_error",
+		Title:  "Internal error",
+		Status: http.StatusInternalServerError,
+		Code:   "internal_error",
+	})
+}
+
+// WriteProblem renders problem as application/problem+json, request ID and path are filled from request
+func WriteProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	problem.Instance = r.URL.Path
+	problem.RequestID = middleware.GetReqID(r.Context())
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// NotFoundHandler reports unknown routes as problem
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, Problem{
+		Type:   problemTypePrefix + "route_not_found",
+		Title:  "Not found",
+		Status: http.StatusNotFound,
+		Detail: fmt.Sprintf("No route for %s %s", r.Method, r.URL.Path),
+		Code:   "route_not_found",
+	})
+}
+
+// MethodNotAllowedHandler reports unsupported methods of known routes as problem
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, Problem{
+		Type:   problemTypePrefix + "method_not_allowed",
+		Title:  "Method not allowed",
+		Status: http.StatusMethodNotAllowed,
+		Detail: fmt.Sprintf("Method %s is not allowed for %s", r.Method, r.URL.Path),
+		Code:   "method_not_allowed",
+	})
+}
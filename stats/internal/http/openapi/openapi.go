@@ -0,0 +1,44 @@
+package openapi
+
+import (
+	"apispec"
+	_ "embed"
+	"errors"
+	"net/http"
+	handler "stats/internal/http/handlers"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Load parses and validates the embedded OpenAPI document
+func Load() (*openapi3.T, error) {
+	return apispec.Load(spec)
+}
+
+// Handler serves the document as JSON
+func Handler(doc *openapi3.T) http.HandlerFunc {
+	return apispec.Handler(doc)
+}
+
+// Validator returns middleware which checks requests against the document, see apispec.Validator.
+// Invalid requests are responded as validation_failed problems, too large JSON bodies as request_too_large.
+func Validator(doc *openapi3.T) (func(http.Handler) http.Handler, error) {
+	return apispec.Validator(doc, apispec.Config{Reject: reject})
+}
+
+func reject(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid *apispec.ValidationError
+	if !errors.As(err, &invalid) {
+		handler.RespondError(w, r, err)
+		return
+	}
+
+	fields := make([]handler.FieldError, len(invalid.Fields))
+	for i, field := range invalid.Fields {
+		fields[i] = handler.FieldError(field)
+	}
+	handler.RespondError(w, r, handler.InvalidFields("Request does not match API specification", fields))
+}
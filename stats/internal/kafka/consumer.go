@@ -2,8 +2,8 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"events"
 	"fmt"
 	"log"
 	"log/slog"
@@ -99,8 +99,9 @@ func (c *Consumer) Close() error {
 
 // consumerHandler реализует sarama.ConsumerGroupHandler
 type consumerHandler struct {
-	logger  *slog.Logger
-	storage storage.Storage
+	logger    *slog.Logger
+	storage   storage.Storage
+	sequences sequenceTracker
 }
 
 func (h *consumerHandler) Setup(sarama.ConsumerGroupSession) error {
@@ -117,8 +118,8 @@ func (h *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 	for message := range claim.Messages() {
 		startTime := time.Now()
 
-		var event Event
-		if err := json.Unmarshal(message.Value, &event); err != nil {
+		event, err := events.DecodeMessage(contentType(message), message.Value)
+		if err != nil {
 			slog.Error("Message unmarshal failed",
 				logger.Err(err),
 				slog.String("topic", message.Topic),
@@ -128,6 +129,8 @@ func (h *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 			continue
 		}
 
+		h.checkSequence(event, message)
+
 		tx, err := h.storage.BeginTx(context.Background())
 		if err != nil {
 			slog.Error("BeginTx error",
@@ -160,6 +163,8 @@ func (h *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 		session.Commit()
 
 		h.logger.Debug("Message processed",
+			slog.String("event_id", event.ID),
+			slog.String("correlation_id", event.CorrelationID),
 			slog.String("topic", message.Topic),
 			slog.Int64("partition", int64(message.Partition)),
 			slog.Int64("offset", message.Offset),
@@ -169,50 +174,80 @@ func (h *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 	return nil
 }
 
-func (h *consumerHandler) handleEvent(event Event, tx storage.Transaction) error {
-	switch event.Type {
-	case EventWalletCreated:
-		var payload WalletCreatedPayload
-		if err := json.Unmarshal(event.Payload, &payload); err != nil {
-			return err
+// contentType returns content-type header of message, it is empty for JSON events of old producers
+func contentType(message *sarama.ConsumerMessage) string {
+	for _, header := range message.Headers {
+		if header != nil && string(header.Key) == events.ContentTypeHeader {
+			return string(header.Value)
 		}
+	}
+	return ""
+}
+
+// checkSequence reports gaps and reordering of wallet events, such events are still processed
+func (h *consumerHandler) checkSequence(event events.Message, message *sarama.ConsumerMessage) {
+	result, expected := h.sequences.observe(event.PartitionKey, event.Sequence)
+	if result == seqOK {
+		return
+	}
+
+	var msg string
+	switch result {
+	case seqGap:
+		msg = "Wallet events are missing"
+	case seqDuplicate:
+		msg = "Wallet event is duplicated"
+	case seqReordered:
+		msg = "Wallet event is out of order"
+	}
+
+	h.logger.Warn(msg,
+		slog.String("event_id", event.ID),
+		slog.String("wallet_id", event.PartitionKey),
+		slog.Int64("expected_sequence", expected),
+		slog.Int64("sequence", event.Sequence),
+		slog.Int64("partition", int64(message.Partition)),
+		slog.Int64("offset", message.Offset),
+	)
+}
+
+func (h *consumerHandler) handleEvent(event events.Message, tx storage.Transaction) error {
+	payload, err := event.DecodePayload()
+	if errors.Is(err, events.ErrUnknownType) {
+		log.Printf("Unknown event type: %s", event.Type)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch payload := payload.(type) {
+	case *events.WalletCreatedPayload:
 		log.Printf("Wallet created: ID=%s", payload.ID)
 		return tx.UpdateStats(context.Background(), storage.OpCreate)
 
-	case EventWalletDeposited:
-		var payload WalletDepositedPayload
-		if err := json.Unmarshal(event.Payload, &payload); err != nil {
-			return err
-		}
+	case *events.WalletDepositedPayload:
 		log.Printf("Deposit: ID=%s, Amount=%.2f", payload.ID, payload.Amount)
 		return tx.UpdateStats(context.Background(), storage.OpDeposit, payload.Amount)
 
-	case EventWalletWithdrawn:
-		var payload WalletWithdrawnPayload
-		if err := json.Unmarshal(event.Payload, &payload); err != nil {
-			return err
-		}
+	case *events.WalletWithdrawnPayload:
 		log.Printf("Withdrawal: ID=%s, Amount=%.2f", payload.ID, payload.Amount)
 		return tx.UpdateStats(context.Background(), storage.OpWithdraw, payload.Amount)
 
-	case EventWalletTransferred:
-		var payload WalletTransferredPayload
-		if err := json.Unmarshal(event.Payload, &payload); err != nil {
-			return err
-		}
+	case *events.WalletTransferredPayload:
 		log.Printf("Transfer: From=%s, To=%s, Amount=%.2f", payload.ID, payload.TransferTo, payload.Amount)
 		return tx.UpdateStats(context.Background(), storage.OpTransfer, payload.Amount)
 
-	case EventWalletDeleted:
-		var payload WalletDeletedPayload
-		if err := json.Unmarshal(event.Payload, &payload); err != nil {
-			return err
-		}
+	case *events.WalletReceivedPayload:
+		//the transfer is counted by Wallet_Transfered, the event only advances the recipient's sequence
+		return nil
+
+	case *events.WalletDeletedPayload:
 		log.Printf("Wallet deleted: ID=%s", payload.ID)
 		return tx.UpdateStats(context.Background(), storage.OpDelete)
 
 	default:
-		log.Printf("Unknown event type: %s", event.Type)
+		//alerts like Ledger_Mismatch don't change stats
 		return nil
 	}
 }
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"events"
+	"events/eventstest"
+	"io"
+	"log/slog"
+	"reflect"
+	"stats/internal/storage"
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+// update is a call of Transaction.UpdateStats
+type update struct {
+	operation string
+	amount    []float64
+}
+
+// recordingTx records updates of stats
+type recordingTx struct {
+	updates []update
+}
+
+func (tx *recordingTx) Commit() error   { return nil }
+func (tx *recordingTx) Rollback() error { return nil }
+
+func (tx *recordingTx) UpdateStats(ctx context.Context, operation string, amount ...float64) error {
+	tx.updates = append(tx.updates, update{operation: operation, amount: amount})
+	return nil
+}
+
+func (tx *recordingTx) GetStats(ctx context.Context) (*storage.Stats, error) {
+	return &storage.Stats{}, nil
+}
+
+// wantUpdates are stats updates caused by eventstest samples
+var wantUpdates = map[string][]update{
+	events.EventWalletCreated:     {{operation: storage.OpCreate}},
+	events.EventWalletDeleted:     {{operation: storage.OpDelete}},
+	events.EventWalletDeposited:   {{operation: storage.OpDeposit, amount: []float64{100.5}}},
+	events.EventWalletWithdrawn:   {{operation: storage.OpWithdraw, amount: []float64{20.25}}},
+	events.EventWalletTransferred: {{operation: storage.OpTransfer, amount: []float64{30}}},
+	events.EventWalletReceived:    nil,
+	events.EventLedgerMismatch:    nil,
+}
+
+// TestConsumerContract checks that events of every type sent by the wallet service update stats
+func TestConsumerContract(t *testing.T) {
+	for _, eventType := range events.Types {
+		if _, ok := wantUpdates[eventType]; !ok {
+			t.Errorf("no expected updates of %s", eventType)
+		}
+	}
+
+	samples := eventstest.Samples()
+	legacy := eventstest.Legacy()
+
+	for _, codec := range []events.Codec{events.JSON, events.Protobuf} {
+		for _, event := range samples {
+			value, err := codec.Encode(event)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			t.Run(codec.ContentType()+"/"+event.Type, func(t *testing.T) {
+				assertUpdates(t, newMessage(codec.ContentType(), value), wantUpdates[event.Type])
+			})
+		}
+	}
+
+	for i, value := range legacy {
+		t.Run("legacy/"+samples[i].Type, func(t *testing.T) {
+			assertUpdates(t, newMessage("", value), wantUpdates[samples[i].Type])
+		})
+	}
+}
+
+// newMessage returns message as it is received from kafka, without header when content type is empty
+func newMessage(contentType string, value []byte) *sarama.ConsumerMessage {
+	message := &sarama.ConsumerMessage{Topic: "wallet-events", Value: value}
+	if contentType != "" {
+		message.Headers = []*sarama.RecordHeader{{Key: []byte(events.ContentTypeHeader), Value: []byte(contentType)}}
+	}
+	return message
+}
+
+func assertUpdates(t *testing.T, message *sarama.ConsumerMessage, want []update) {
+	t.Helper()
+
+	event, err := events.DecodeMessage(contentType(message), message.Value)
+	if err != nil {
+		t.Fatalf("DecodeMessage: %v", err)
+	}
+
+	handler := &consumerHandler{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	tx := &recordingTx{}
+	if err := handler.handleEvent(event, tx); err != nil {
+		t.Fatalf("handleEvent: %v", err)
+	}
+
+	if !reflect.DeepEqual(tx.updates, want) {
+		t.Errorf("updates = %+v, want %+v", tx.updates, want)
+	}
+}
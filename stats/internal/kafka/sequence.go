@@ -0,0 +1,54 @@
+package kafka
+
+import "sync"
+
+type seqResult int
+
+const (
+	seqOK        seqResult = iota
+	seqGap                 //some events of the wallet are missing
+	seqDuplicate           //event was already seen
+	seqReordered           //event came after a later one
+)
+
+// sequenceTracker remembers last sequence number of every wallet, zero value is ready to use.
+// Numbers are kept in memory, so first event of a wallet after start is taken as is
+type sequenceTracker struct {
+	mu   sync.Mutex
+	last map[string]int64
+}
+
+// observe checks seq of wallet event against the last seen one and returns expected number.
+// Events without key or sequence are not checked
+func (s *sequenceTracker) observe(key string, seq int64) (seqResult, int64) {
+	if key == "" || seq == 0 {
+		return seqOK, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last == nil {
+		s.last = make(map[string]int64)
+	}
+
+	last, ok := s.last[key]
+	if !ok {
+		s.last[key] = seq
+		return seqOK, seq
+	}
+
+	expected := last + 1
+	switch {
+	case seq == expected:
+		s.last[key] = seq
+		return seqOK, expected
+	case seq > expected:
+		s.last[key] = seq
+		return seqGap, expected
+	case seq == last:
+		return seqDuplicate, expected
+	default:
+		return seqReordered, expected
+	}
+}
@@ -0,0 +1,32 @@
+package kafka
+
+import "testing"
+
+func TestSequenceTracker(t *testing.T) {
+	var tracker sequenceTracker
+
+	steps := []struct {
+		key      string
+		seq      int64
+		want     seqResult
+		expected int64
+	}{
+		{key: "a", seq: 5, want: seqOK, expected: 5}, //first event of a wallet is a baseline
+		{key: "a", seq: 6, want: seqOK, expected: 6},
+		{key: "b", seq: 1, want: seqOK, expected: 1},
+		{key: "a", seq: 8, want: seqGap, expected: 7},
+		{key: "a", seq: 7, want: seqReordered, expected: 9},
+		{key: "a", seq: 8, want: seqDuplicate, expected: 9},
+		{key: "a", seq: 9, want: seqOK, expected: 9},
+		{key: "b", seq: 2, want: seqOK, expected: 2},
+		{key: "", seq: 3, want: seqOK},
+		{key: "b", seq: 0, want: seqOK},
+	}
+
+	for i, step := range steps {
+		got, expected := tracker.observe(step.key, step.seq)
+		if got != step.want || expected != step.expected {
+			t.Errorf("step %d: observe(%q, %d) = %d, %d, want %d, %d", i, step.key, step.seq, got, expected, step.want, step.expected)
+		}
+	}
+}
@@ -4,18 +4,32 @@ import (
 	//"stats/internal/http/handlers"
 	//"stats/internal/service"
 
+	"net/http"
 	handler "stats/internal/http/handlers"
+	"stats/internal/http/openapi"
 	"stats/internal/service"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 )
 
-func InitWallet(r *chi.Mux, s *service.StatsService) {
+// InitMiddleware must be called before other Init functions, chi does not accept middlewares after routes
+func InitMiddleware(r *chi.Mux, validator func(http.Handler) http.Handler) {
 
 	r.Use(middleware.RequestID) //трейсинг запросов
 	r.Use(middleware.Logger)    //логирование запросов
 	r.Use(middleware.Recoverer) //отлов паник
+	r.Use(validator)            //проверка запросов по спецификации
+
+	r.NotFound(handler.NotFoundHandler)
+	r.MethodNotAllowed(handler.MethodNotAllowedHandler)
+}
 
+func InitWallet(r *chi.Mux, s *service.StatsService) {
 	r.Get("/stats/wallets", handler.GetStatsHandler(s))
 }
+
+func InitOpenAPI(r *chi.Mux, doc *openapi3.T) {
+	r.Get("/openapi.json", openapi.Handler(doc))
+}
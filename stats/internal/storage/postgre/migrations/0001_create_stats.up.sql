@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS stats(
+	id TEXT PRIMARY KEY,
+	total INTEGER NOT NULL,
+	active INTEGER NOT NULL,
+	inactive INTEGER NOT NULL,
+	deposited FLOAT NOT NULL,
+	withdrawn FLOAT NOT NULL,
+	transfered FLOAT NOT NULL,
+	operation VARCHAR(24) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL);
+
+-- Если таблица пустая - вставляем дефолтную запись
+INSERT INTO stats (id, total, active, inactive, deposited, withdrawn, transfered, operation)
+SELECT substr(md5(random()::text), 1, 16), 0, 0, 0, 0.0, 0.0, 0.0, 'STATS BEGIN'
+WHERE NOT EXISTS (SELECT 1 FROM stats);
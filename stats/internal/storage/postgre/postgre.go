@@ -3,7 +3,10 @@ package postgre
 import (
 	"context"
 	"database/sql"
+	"embed"
 	"fmt"
+	"io/fs"
+	"migrate"
 	"stats/internal/storage"
 	"stats/internal/utils/random"
 	"time"
@@ -17,8 +20,16 @@ type Storage struct {
 
 const ID_LENGTH = 16
 
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationsConfig keeps applied schema versions apart from wallet service sharing the database
+var migrationsConfig = migrate.Config{
+	Table:   "stats_schema_migrations",
+	LockKey: 0x7374617473, // "stats"
+}
+
 func New(dbhost string, dbport int) (*Storage, error) {
-	const fn = "storage.postgre.New"
 	const (
 		user     = "wallets_admin"
 		password = "admin"
@@ -42,72 +53,6 @@ func New(dbhost string, dbport int) (*Storage, error) {
 		return nil, fmt.Errorf("failed to ping db: %w", err)
 	}
 
-	stmt, err := db.Prepare(`
-		CREATE TABLE IF NOT EXISTS stats(
-			id TEXT PRIMARY KEY,
-			total INTEGER NOT NULL,
-			active INTEGER NOT NULL,
-			inactive INTEGER NOT NULL,
-			deposited FLOAT NOT NULL,
-			withdrawn FLOAT NOT NULL,
-			transfered FLOAT NOT NULL,
-			operation VARCHAR(24) NOT NULL,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL);
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("%s:%w", fn, err)
-	}
-
-	defer stmt.Close()
-
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("%s:%w", fn, err)
-	}
-
-	// Проверяем, пустая ли таблица
-	var exists bool
-	err = db.QueryRowContext(ctx, `
-		SELECT EXISTS(SELECT 1 FROM stats LIMIT 1)
-	`).Scan(&exists)
-
-	if err != nil {
-		return nil, fmt.Errorf("check table emptiness error: %w", err)
-	}
-
-	// Если таблица пустая - вставляем дефолтную запись
-	if !exists {
-		statsID := random.NewRandomString(ID_LENGTH)
-		query := `
-			INSERT INTO stats (
-				id,
-            	total, 
-            	active, 
-            	inactive, 
-            	deposited, 
-            	withdrawn, 
-            	transfered,
-				operation
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
-		`
-		_, err := db.ExecContext(
-			ctx,
-			query,
-			statsID,
-			0,
-			0,
-			0,
-			0.0,
-			0.0,
-			0.0,
-			"STATS BEGIN",
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("initial insert error: %w", err)
-		}
-	}
-
 	return &Storage{db: db}, nil
 }
 
@@ -115,6 +60,16 @@ func (s *Storage) Close() error {
 	return s.db.Close()
 }
 
+// Migrator applies versioned schema migrations of postgres dialect
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	scripts, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(s.db, migrate.Postgres, scripts, migrationsConfig)
+}
+
 func (s *Storage) BeginTx(ctx context.Context) (storage.Transaction, error) {
 	const fn = "postgre.BeginTx"
 
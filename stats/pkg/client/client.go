@@ -0,0 +1,182 @@
+// Package client is a Go client of the stats service HTTP API.
+//
+// Every method takes a context, which limits the call including retries.
+// Failed calls return *Error, which matches kind errors such as ErrInternal with errors.Is.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const userAgent = "stats-go-client"
+
+// RetryPolicy configures retries of failed calls. Calls are retried on network errors and 429, 502, 503, 504 responses.
+type RetryPolicy struct {
+	MaxAttempts int           //1 disables retries
+	MinBackoff  time.Duration //delay before the first retry, doubled for each next one
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, MinBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+// Client calls the stats service. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retry      RetryPolicy
+	userAgent  string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets HTTP client used for calls, http.DefaultClient by default
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithUserAgent sets User-Agent header of requests
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New returns client of the service at baseURL, e.g. http://localhost:8082
+func New(baseURL string, opts ...Option) (*Client, error) {
+	const fn = "client.New"
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%s: base URL must be absolute: %q", fn, baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+		userAgent:  userAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+
+	return c, nil
+}
+
+// get sends GET request with retries and decodes JSON response into out
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	var lastErr error
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, path)
+		retryAfter := time.Duration(0)
+
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = err
+		case resp.StatusCode < 300:
+			defer drain(resp.Body)
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("decode GET %s response: %w", path, err)
+			}
+			return nil
+		default:
+			apiErr := readError(resp)
+			if !apiErr.temporary() {
+				return apiErr
+			}
+			lastErr = apiErr
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		}
+
+		if attempt >= c.retry.MaxAttempts {
+			return lastErr
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL.String()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	return c.httpClient.Do(req)
+}
+
+// backoff returns exponential delay, randomized by half to spread retries of concurrent clients
+func (c *Client) backoff(attempt int) time.Duration {
+	if c.retry.MinBackoff <= 0 {
+		return 0
+	}
+
+	delay := c.retry.MinBackoff << (attempt - 1)
+	if delay <= 0 || (c.retry.MaxBackoff > 0 && delay > c.retry.MaxBackoff) {
+		delay = c.retry.MaxBackoff
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// drain lets connection be reused
+func drain(body io.ReadCloser) {
+	io.Copy(io.Discard, body)
+	body.Close()
+}
@@ -0,0 +1,146 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"stats/internal/http/openapi"
+	chirouter "stats/internal/router/chi"
+	"stats/internal/service"
+	"stats/internal/storage"
+	"stats/pkg/client"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// fakeStorage returns fixed stats or fails
+type fakeStorage struct {
+	stats *storage.Stats
+	err   error
+}
+
+func (s *fakeStorage) Close() error { return nil }
+
+func (s *fakeStorage) BeginTx(ctx context.Context) (storage.Transaction, error) {
+	return &fakeTx{s}, nil
+}
+
+type fakeTx struct {
+	s *fakeStorage
+}
+
+func (tx *fakeTx) Commit() error   { return nil }
+func (tx *fakeTx) Rollback() error { return nil }
+
+func (tx *fakeTx) UpdateStats(ctx context.Context, operation string, amount ...float64) error {
+	return nil
+}
+
+func (tx *fakeTx) GetStats(ctx context.Context) (*storage.Stats, error) {
+	return tx.s.stats, tx.s.err
+}
+
+// newClient serves the router of app.Run, counting requests
+func newClient(t *testing.T, st storage.Storage, requests *atomic.Int32) *client.Client {
+	t.Helper()
+
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("openapi.Load: %v", err)
+	}
+	validator, err := openapi.Validator(spec)
+	if err != nil {
+		t.Fatalf("openapi.Validator: %v", err)
+	}
+
+	router := chi.NewRouter()
+	chirouter.InitMiddleware(router, validator)
+	chirouter.InitWallet(router, service.New(st))
+	chirouter.InitOpenAPI(router, spec)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("client.New: %v", err)
+	}
+
+	return c
+}
+
+func TestWalletStats(t *testing.T) {
+	want := storage.Stats{Total: 3, Active: 2, Inactive: 1, Deposited: 100, Withdrawn: 30, Transfered: 20}
+
+	var requests atomic.Int32
+	c := newClient(t, &fakeStorage{stats: &want}, &requests)
+
+	got, err := c.WalletStats(context.Background())
+	if err != nil {
+		t.Fatalf("WalletStats: %v", err)
+	}
+	if got.Total != want.Total || got.Active != want.Active || got.Inactive != want.Inactive ||
+		got.Deposited != want.Deposited || got.Withdrawn != want.Withdrawn || got.Transfered != want.Transfered {
+		t.Errorf("WalletStats = %+v, want %+v", got, want)
+	}
+
+	doc, err := c.OpenAPI(context.Background())
+	if err != nil {
+		t.Fatalf("OpenAPI: %v", err)
+	}
+	if doc["openapi"] == nil {
+		t.Errorf("OpenAPI document has no version: %v", doc)
+	}
+}
+
+func TestInternalError(t *testing.T) {
+	var requests atomic.Int32
+	c := newClient(t, &fakeStorage{err: errors.New("connection refused")}, &requests)
+
+	_, err := c.WalletStats(context.Background())
+	if !errors.Is(err, client.ErrInternal) {
+		t.Fatalf("WalletStats error = %v, want ErrInternal", err)
+	}
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "internal_error" || apiErr.RequestID == "" {
+		t.Errorf("WalletStats error = %+v", apiErr)
+	}
+	//internal errors are not retried, only unavailability is
+	if n := requests.Load(); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+}
+
+func TestRetryUnavailable(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"total":1,"active":1,"inactive":0,"deposited":0,"withdrawn":0,"transfered":0}`))
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("client.New: %v", err)
+	}
+
+	got, err := c.WalletStats(context.Background())
+	if err != nil {
+		t.Fatalf("WalletStats: %v", err)
+	}
+	if got.Total != 1 || requests.Load() != 3 {
+		t.Errorf("WalletStats = %+v after %d requests, want total 1 after 3", got, requests.Load())
+	}
+}
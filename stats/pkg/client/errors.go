@@ -0,0 +1,107 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Error kinds, *Error matches one of them with errors.Is
+var (
+	ErrInvalidArgument = &kindError{"invalid argument"}
+	ErrNotFound        = &kindError{"not found"}
+	ErrInternal        = &kindError{"internal error"}
+)
+
+type kindError struct {
+	msg string
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+// FieldError describes invalid query parameter
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error response of the service, RFC 7807 problem
+type Error struct {
+	StatusCode int          `json:"status"`
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail,omitempty"`
+	Instance   string       `json:"instance,omitempty"`
+	Code       string       `json:"code"`
+	RequestID  string       `json:"request_id,omitempty"`
+	Fields     []FieldError `json:"errors,omitempty"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("stats: %d %s", e.StatusCode, e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// Is matches error kind, e.g. errors.Is(err, client.ErrInternal)
+func (e *Error) Is(target error) bool {
+	return e.Kind() == target
+}
+
+// Kind returns one of the error kinds
+func (e *Error) Kind() error {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode >= 500:
+		return ErrInternal
+	case e.StatusCode >= 400:
+		return ErrInvalidArgument
+	default:
+		return nil
+	}
+}
+
+// temporary reports whether the call may succeed when retried
+func (e *Error) temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// maxErrorBody limits error body read from responses which are not problems, e.g. of proxies
+const maxErrorBody = 4 << 10
+
+// readError decodes problem response and closes its body
+func readError(resp *http.Response) *Error {
+	defer drain(resp.Body)
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/problem+json" || mediaType == "application/json" {
+		if err := json.Unmarshal(body, apiErr); err == nil && apiErr.Code != "" {
+			apiErr.StatusCode = resp.StatusCode
+			return apiErr
+		}
+	}
+
+	apiErr.Code = "http_" + strings.ReplaceAll(strings.ToLower(http.StatusText(resp.StatusCode)), " ", "_")
+	apiErr.Title = http.StatusText(resp.StatusCode)
+	apiErr.Detail = strings.TrimSpace(string(body))
+
+	return apiErr
+}
@@ -0,0 +1,33 @@
+package client
+
+import "context"
+
+// Stats are wallet counters and operation totals aggregated from wallet events
+type Stats struct {
+	Total      int     `json:"total"`
+	Active     int     `json:"active"`
+	Inactive   int     `json:"inactive"`
+	Deposited  float64 `json:"deposited"`
+	Withdrawn  float64 `json:"withdrawn"`
+	Transfered float64 `json:"transfered"`
+}
+
+// WalletStats returns current wallet statistics
+func (c *Client) WalletStats(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	if err := c.get(ctx, "/stats/wallets", &stats); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// OpenAPI returns OpenAPI document of the service
+func (c *Client) OpenAPI(ctx context.Context) (map[string]any, error) {
+	var doc map[string]any
+	if err := c.get(ctx, "/openapi.json", &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
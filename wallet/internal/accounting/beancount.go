@@ -0,0 +1,46 @@
+package accounting
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type beancountJournal struct {
+	w        *bufio.Writer
+	currency string
+}
+
+func newBeancountJournal(w io.Writer, currency string) *beancountJournal {
+	return &beancountJournal{w: bufio.NewWriter(w), currency: currency}
+}
+
+func (b *beancountJournal) Open(account string, date time.Time) error {
+	_, err := fmt.Fprintf(b.w, "%s open %s %s\n", date.UTC().Format(time.DateOnly), account, b.currency)
+	return err
+}
+
+func (b *beancountJournal) Transaction(tx Transaction) error {
+	fmt.Fprintf(b.w, "\n%s * %s\n", tx.Date.UTC().Format(time.DateOnly), quote(tx.Narration))
+	if tx.Code != "" {
+		fmt.Fprintf(b.w, "  operation: %s\n", quote(tx.Code))
+	}
+
+	for _, posting := range tx.Postings {
+		if _, err := fmt.Fprintf(b.w, "  %-50s %12s %s\n", posting.Account, formatAmount(posting.Amount), b.currency); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *beancountJournal) Flush() error {
+	return b.w.Flush()
+}
+
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,216 @@
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+	"wallet/internal/storage"
+)
+
+// Accounts maps wallets and external money flows to journal accounts
+type Accounts struct {
+	WalletPrefix string            //wallet account is WalletPrefix:<Wallet-Name>
+	Wallets      map[string]string //explicit wallet ID to account mapping
+	Deposits     string            //source of external deposits
+	Withdrawals  string            //destination of external withdrawals
+	Opening      string            //source of opening balances
+}
+
+// Exporter converts wallet operation journal into plain text double-entry journal
+type Exporter struct {
+	storage  storage.Storage
+	accounts Accounts
+	currency string
+}
+
+func NewExporter(storage storage.Storage, accounts Accounts, currency string) *Exporter {
+	return &Exporter{
+		storage:  storage,
+		accounts: accounts,
+		currency: currency,
+	}
+}
+
+const pageSize = 500
+
+// Export writes journal with operations made after from up to and including to.
+// When from is set, wallet balances at from are written as opening balance transactions.
+func (e *Exporter) Export(ctx context.Context, format string, from, to time.Time, w io.Writer) error {
+	const fn = "accounting.Export"
+
+	journal, err := NewJournal(format, w, e.currency)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	wallets, err := e.walletAccounts(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	//external accounts are opened before any wallet
+	epoch := time.Unix(0, 0)
+	for _, account := range []string{e.accounts.Opening, e.accounts.Deposits, e.accounts.Withdrawals} {
+		if err := journal.Open(account, epoch); err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+	for _, wallet := range wallets {
+		if err := journal.Open(wallet.account, wallet.createdAt); err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	if !from.IsZero() {
+		balances, err := e.storage.BalancesAt(ctx, from)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+
+		for _, balance := range balances {
+			if balance.Balance == 0 {
+				continue
+			}
+
+			err := journal.Transaction(Transaction{
+				Date:      from,
+				Narration: "Opening balance of " + balance.Name,
+				Postings: []Posting{
+					{Account: wallets[balance.WalletID].account, Amount: balance.Balance},
+					{Account: e.accounts.Opening, Amount: -balance.Balance},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", fn, err)
+			}
+		}
+	}
+
+	account := func(walletID string) string {
+		if wallet, ok := wallets[walletID]; ok {
+			return wallet.account
+		}
+		return e.accounts.WalletPrefix + ":" + sanitize(walletID)
+	}
+
+	err = e.storage.WalkOperations(ctx, storage.OperationFilter{From: from, To: to}, func(op storage.Operation) error {
+		tx := Transaction{Date: op.CreatedAt, Code: op.ID}
+		name := wallets[op.WalletID].name
+
+		switch op.Type {
+		case storage.OpDeposit:
+			tx.Narration = "Deposit to " + name
+			tx.Postings = []Posting{
+				{Account: account(op.WalletID), Amount: op.Amount},
+				{Account: e.accounts.Deposits, Amount: -op.Amount},
+			}
+		case storage.OpOpeningBalance:
+			tx.Narration = "Opening balance of " + name
+			tx.Postings = []Posting{
+				{Account: account(op.WalletID), Amount: op.Amount},
+				{Account: e.accounts.Opening, Amount: -op.Amount},
+			}
+		case storage.OpWithdraw:
+			tx.Narration = "Withdrawal from " + name
+			tx.Postings = []Posting{
+				{Account: account(op.WalletID), Amount: -op.Amount},
+				{Account: e.accounts.Withdrawals, Amount: op.Amount},
+			}
+		case storage.OpTransferOut:
+			tx.Narration = fmt.Sprintf("Transfer from %s to %s", name, wallets[op.Counterparty].name)
+			tx.Postings = []Posting{
+				{Account: account(op.WalletID), Amount: -op.Amount},
+				{Account: account(op.Counterparty), Amount: op.Amount},
+			}
+		default:
+			//incoming leg of transfer is written together with outgoing one
+			return nil
+		}
+
+		return journal.Transaction(tx)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := journal.Flush(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+type walletAccount struct {
+	name      string
+	account   string
+	createdAt time.Time
+}
+
+// walletAccounts loads all wallets and maps them to unique account names
+func (e *Exporter) walletAccounts(ctx context.Context) (map[string]walletAccount, error) {
+	var wallets []storage.Wallet
+
+	query := storage.WalletQuery{SortBy: storage.SortByCreatedAt, Limit: pageSize}
+	for {
+		page, err := e.storage.ListWallets(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		wallets = append(wallets, page.Wallets...)
+
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	used := make(map[string]int, len(wallets))
+	for _, wallet := range wallets {
+		used[e.walletAccount(wallet)]++
+	}
+
+	accounts := make(map[string]walletAccount, len(wallets))
+	for _, wallet := range wallets {
+		account := e.walletAccount(wallet)
+		//different names can be sanitized to the same account
+		if used[account] > 1 {
+			account += "-" + sanitize(wallet.ID)
+		}
+
+		accounts[wallet.ID] = walletAccount{name: wallet.Name, account: account, createdAt: wallet.CreatedAt}
+	}
+
+	return accounts, nil
+}
+
+func (e *Exporter) walletAccount(wallet storage.Wallet) string {
+	if account, ok := e.accounts.Wallets[wallet.ID]; ok {
+		return account
+	}
+
+	return e.accounts.WalletPrefix + ":" + sanitize(wallet.Name)
+}
+
+// sanitize converts value into account name component: capitalized words of letters and digits joined with dashes
+func sanitize(value string) string {
+	words := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+
+	component := strings.Join(words, "-")
+	if component == "" || !unicode.IsUpper([]rune(component)[0]) && !unicode.IsDigit([]rune(component)[0]) {
+		component = "X" + component
+	}
+
+	return component
+}
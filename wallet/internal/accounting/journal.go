@@ -0,0 +1,53 @@
+package accounting
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"wallet/internal/domain"
+)
+
+const (
+	FormatBeancount = "beancount"
+	FormatLedger    = "ledger"
+)
+
+var ErrUnknownFormat = domain.New(domain.ErrInvalidArgument, "unknown_format", "unknown journal format")
+
+type Posting struct {
+	Account string
+	Amount  float64
+}
+
+// Transaction is a balanced journal entry, sum of posting amounts is zero
+type Transaction struct {
+	Date      time.Time
+	Code      string
+	Narration string
+	Postings  []Posting
+}
+
+// Journal writes plain text double-entry journal
+type Journal interface {
+	// Open declares account before its first use
+	Open(account string, date time.Time) error
+	Transaction(tx Transaction) error
+	Flush() error
+}
+
+// NewJournal creates journal writer for format, amounts are denominated in currency
+func NewJournal(format string, w io.Writer, currency string) (Journal, error) {
+	switch format {
+	case FormatBeancount:
+		return newBeancountJournal(w, currency), nil
+	case FormatLedger:
+		return newLedgerJournal(w, currency), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
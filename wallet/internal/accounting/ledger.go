@@ -0,0 +1,43 @@
+package accounting
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+type ledgerJournal struct {
+	w        *bufio.Writer
+	currency string
+}
+
+func newLedgerJournal(w io.Writer, currency string) *ledgerJournal {
+	return &ledgerJournal{w: bufio.NewWriter(w), currency: currency}
+}
+
+// Open writes account directive, ledger-cli doesn't require it but it enables --strict checks
+func (l *ledgerJournal) Open(account string, _ time.Time) error {
+	_, err := fmt.Fprintf(l.w, "account %s\n", account)
+	return err
+}
+
+func (l *ledgerJournal) Transaction(tx Transaction) error {
+	fmt.Fprintf(l.w, "\n%s *", tx.Date.UTC().Format("2006/01/02"))
+	if tx.Code != "" {
+		fmt.Fprintf(l.w, " (%s)", tx.Code)
+	}
+	fmt.Fprintf(l.w, " %s\n", tx.Narration)
+
+	for _, posting := range tx.Postings {
+		if _, err := fmt.Fprintf(l.w, "    %-50s %12s %s\n", posting.Account, formatAmount(posting.Amount), l.currency); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *ledgerJournal) Flush() error {
+	return l.w.Flush()
+}
@@ -1,17 +1,29 @@
 package app
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"wallet/internal/accounting"
 	"wallet/internal/config"
+	"wallet/internal/grpcserver"
+	"wallet/internal/http/idempotency"
+	"wallet/internal/http/openapi"
+	"wallet/internal/importer"
 	"wallet/internal/kafka"
 	logger "wallet/internal/logger/slog"
+	"wallet/internal/notify"
+	"wallet/internal/reconcile"
 	chirouter "wallet/internal/router/chi"
 	"wallet/internal/service"
-	"wallet/internal/storage/postgre"
-	//"wallet/internal/storage/sqlite"
+	"wallet/internal/storage"
+	"wallet/internal/webhook"
+
 	"github.com/go-chi/chi"
+	"google.golang.org/grpc"
 )
 
 func Run() error {
@@ -23,26 +35,86 @@ func Run() error {
 	log.Info("Logger inited!")
 
 	//Init storage
-	storage, err := postgre.New(config.DBServer.Host, config.DBServer.Port)
+	storage, err := newStorage(config)
 	if err != nil {
 		log.Error("Can't init storage: ", logger.Err(err))
 		os.Exit(1)
 	}
 	defer storage.Close()
 
-	//Init kafka producer
-	kafkaProducer, err := kafka.NewProducer(config.Brokers, config.Topic)
+	//Apply schema migrations
+	if config.DBServer.AutoMigrate {
+		if err := migrateUp(context.Background(), storage, log); err != nil {
+			log.Error("Can't migrate storage: ", logger.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	//Init event publisher
+	eventPublisher, err := newPublisher(config, log)
 	if err != nil {
-		log.Error("Can't init kafka producer: ", logger.Err(err))
+		log.Error("Can't init event publisher: ", logger.Err(err))
 		os.Exit(1)
 	}
 
+	//Init webhooks dispatcher, it receives every published event
+	dispatcher := newDispatcher(config, storage)
+	events := eventPublisher
+	if config.Webhooks.Enabled {
+		events = dispatcher.Forward(eventPublisher)
+	}
+
+	//Init wallet changes hub
+	hub := notify.NewHub(config.Streams.History)
+
 	//Init service
-	walletService := service.New(storage, kafkaProducer)
+	walletService := service.New(storage, events, hub)
+
+	//Init accounting exporter
+	exporter := newExporter(config, storage)
+
+	//Init wallets importer
+	walletsImporter := importer.New(storage, events, hub, config.Import.BatchSize)
+
+	//Init reconciliation job
+	reconciler := reconcile.New(storage, events, config.Reconcile.Epsilon)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if config.Reconcile.Enabled {
+		go reconciler.Run(ctx, config.Reconcile.Interval)
+	}
+
+	if config.Webhooks.Enabled {
+		go dispatcher.Run(ctx)
+	}
+
+	//Load API specification
+	spec, err := openapi.Load()
+	if err != nil {
+		log.Error("Can't load API specification: ", logger.Err(err))
+		os.Exit(1)
+	}
+	validator, err := openapi.Validator(spec)
+	if err != nil {
+		log.Error("Can't init request validator: ", logger.Err(err))
+		os.Exit(1)
+	}
 
 	//Init router
 	router := chi.NewRouter()
-	chirouter.InitWallet(router, walletService)
+	chirouter.InitMiddleware(router, validator, idempotency.New(config.Idempotency.TTL, config.Idempotency.MaxItems))
+	chirouter.InitWallet(router, walletService, config)
+	chirouter.InitEvents(router, walletService, hub, config)
+	chirouter.InitExport(router, exporter, config)
+	chirouter.InitImport(router, walletsImporter)
+	chirouter.InitReconcile(router, reconciler)
+	if config.Webhooks.Enabled {
+		//webhooks created without the dispatcher would never receive events
+		chirouter.InitWebhooks(router, dispatcher)
+	}
+	chirouter.InitOpenAPI(router, spec)
 
 	srv := &http.Server{
 		Addr:         config.Address,
@@ -52,13 +124,81 @@ func Run() error {
 		IdleTimeout:  config.IdleTimeout,
 	}
 
-	log.Info("Staring server", slog.String("adress: ", config.Address))
+	//Run gRPC server
+	var grpcServer *grpc.Server
+	if config.GRPC.Enabled {
+		grpcServer = grpcserver.New(walletService, hub, config.GRPC.Timeout, config.GRPC.Buffer, log)
+
+		go func() {
+			log.Info("Staring gRPC server", slog.String("adress: ", config.GRPC.Address))
+			if err := grpcserver.Serve(grpcServer, config.GRPC.Address); err != nil {
+				log.Error("Can't run gRPC server: ", logger.Err(err))
+			}
+		}()
+	}
 
-	if err := srv.ListenAndServe(); err != nil {
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info("Staring server", slog.String("adress: ", config.Address))
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	//Serve until interrupted
+	select {
+	case <-ctx.Done():
+		log.Info("Stopping wallet server")
+	case err := <-serveErr:
 		log.Error("Can't run server: ", logger.Err(err))
 	}
+	cancel()
+
+	//streams end with the hub, otherwise they would hold shutdown
+	hub.Close()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("Can't stop server gracefully: ", logger.Err(err))
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
+	//Flush events of served requests
+	if err := events.Close(); err != nil {
+		log.Error("Can't flush event publisher: ", logger.Err(err))
+	}
+	if kafkaProducer, ok := eventPublisher.(*kafka.Producer); ok {
+		stats := kafkaProducer.Stats()
+		log.Info("Kafka producer closed", slog.Int64("sent", stats.Sent), slog.Int64("failed", stats.Failed), slog.Int64("pending", stats.Pending))
+	}
 
 	log.Info("Wallet server stopped.")
 
 	return nil
 }
+
+func newExporter(config *config.Config, storage storage.Storage) *accounting.Exporter {
+	return accounting.NewExporter(storage, accounting.Accounts{
+		WalletPrefix: config.Accounting.WalletPrefix,
+		Wallets:      config.Accounting.WalletAccounts,
+		Deposits:     config.Accounting.Deposits,
+		Withdrawals:  config.Accounting.Withdrawals,
+		Opening:      config.Accounting.Opening,
+	}, config.Currency)
+}
+
+func newDispatcher(config *config.Config, storage storage.Storage) *webhook.Dispatcher {
+	return webhook.New(storage, webhook.NewClient(), webhook.Config{
+		MaxAttempts:  config.Webhooks.MaxAttempts,
+		MinBackoff:   config.Webhooks.MinBackoff,
+		MaxBackoff:   config.Webhooks.MaxBackoff,
+		Timeout:      config.Webhooks.Timeout,
+		DisableAfter: config.Webhooks.DisableAfter,
+		PollInterval: config.Webhooks.PollInterval,
+		Workers:      config.Webhooks.Workers,
+		Lease:        config.Webhooks.Lease,
+		CacheTTL:     config.Webhooks.CacheTTL,
+	})
+}
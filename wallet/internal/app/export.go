@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"wallet/internal/accounting"
+	"wallet/internal/config"
+)
+
+// Export writes accounting journal of the wallet service into file or stdout.
+//
+//	app export [-format beancount|ledger] [-from RFC3339] [-to RFC3339] [-o file]
+func Export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", accounting.FormatBeancount, "journal format: beancount or ledger")
+	fromRaw := flags.String("from", "", "export operations after this RFC3339 time, balances at it become opening balances")
+	toRaw := flags.String("to", "", "export operations up to this RFC3339 time")
+	output := flags.String("o", "", "output file, stdout by default")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var from, to time.Time
+	var err error
+
+	if *fromRaw != "" {
+		if from, err = time.Parse(time.RFC3339, *fromRaw); err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+	}
+	if *toRaw != "" {
+		if to, err = time.Parse(time.RFC3339, *toRaw); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+
+	//Load config
+	config := config.MustLoad()
+
+	//Init storage
+	storage, err := newStorage(config)
+	if err != nil {
+		return fmt.Errorf("can't init storage: %w", err)
+	}
+	defer storage.Close()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("can't create output file: %w", err)
+		}
+		defer file.Close()
+
+		out = file
+	}
+
+	return newExporter(config, storage).Export(context.Background(), *format, from, to, out)
+}
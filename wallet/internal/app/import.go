@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"wallet/internal/config"
+	"wallet/internal/importer"
+)
+
+// Import creates wallets from CSV or JSON Lines file and prints the import report.
+//
+//	app import -file wallets.csv [-format csv|jsonl] [-dry-run]
+func Import(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := flags.String("file", "", "CSV or JSON Lines file with wallets, stdin when \"-\"")
+	format := flags.String("format", "", "file format: csv or jsonl, taken from file extension by default")
+	dryRun := flags.Bool("dry-run", false, "validate the file without creating wallets")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*file)) {
+		case ".jsonl", ".ndjson", ".json":
+			*format = importer.FormatJSONL
+		default:
+			*format = importer.FormatCSV
+		}
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("can't open file: %w", err)
+		}
+		defer f.Close()
+
+		in = f
+	}
+
+	//Load config
+	config := config.MustLoad()
+
+	//Init storage
+	storage, err := newStorage(config)
+	if err != nil {
+		return fmt.Errorf("can't init storage: %w", err)
+	}
+	defer storage.Close()
+
+	//Init event publisher, kafka producer is sync since the import report counts events which failed to send
+	eventPublisher, err := newSyncPublisher(config)
+	if err != nil {
+		return fmt.Errorf("can't init event publisher: %w", err)
+	}
+	defer eventPublisher.Close()
+
+	//webhook deliveries are queued in storage, the dispatcher of running service sends them
+	events := eventPublisher
+	if config.Webhooks.Enabled {
+		events = newDispatcher(config, storage).Forward(eventPublisher)
+	}
+
+	//changes hub of running service is not reachable, so nobody is notified
+	walletsImporter := importer.New(storage, events, nil, config.Import.BatchSize)
+
+	report, importErr := walletsImporter.Import(context.Background(), *format, in, *dryRun)
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	}
+	if importErr != nil {
+		return importErr
+	}
+
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("import rejected: %d invalid rows", len(report.Errors))
+	}
+
+	if report.Failed != nil {
+		return fmt.Errorf("import stopped at lines %d-%d, %d wallets of the previous lines are created",
+			report.Failed.FirstLine, report.Failed.LastLine, report.Created)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"migrate"
+	"os"
+	"wallet/internal/config"
+	"wallet/internal/storage"
+)
+
+// Migrate applies or reverts schema migrations.
+//
+//	app migrate [up|down|status] [-steps n]
+func Migrate(args []string) error {
+	command, err := migrate.ParseCommand(args)
+	if err != nil {
+		return err
+	}
+
+	//Load config
+	config := config.MustLoad()
+
+	//Init storage
+	storage, err := newStorage(config)
+	if err != nil {
+		return fmt.Errorf("can't init storage: %w", err)
+	}
+	defer storage.Close()
+
+	migratable, ok := storage.(migratable)
+	if !ok {
+		return fmt.Errorf("storage driver %q has no migrations", config.Storage.Driver)
+	}
+
+	migrator, err := migratable.Migrator()
+	if err != nil {
+		return err
+	}
+
+	return command.Run(context.Background(), migrator, os.Stdout)
+}
+
+type migratable interface {
+	Migrator() (*migrate.Migrator, error)
+}
+
+// migrateUp applies pending migrations at startup. Storages without schema are skipped.
+func migrateUp(ctx context.Context, storage storage.Storage, log *slog.Logger) error {
+	migratable, ok := storage.(migratable)
+	if !ok {
+		return nil
+	}
+
+	migrator, err := migratable.Migrator()
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Info("Migration applied", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+	}
+
+	return err
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"events"
+	"fmt"
+	"log/slog"
+	"wallet/internal/config"
+	"wallet/internal/kafka"
+	logger "wallet/internal/logger/slog"
+	"wallet/internal/publisher"
+)
+
+// newPublisher opens event publisher selected by publisher.kind
+func newPublisher(cfg *config.Config, log *slog.Logger) (publisher.Publisher, error) {
+	switch cfg.Publisher.Kind {
+	case config.PublisherKafka:
+		p, err := newProducer(cfg, log)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case config.PublisherMemory:
+		return publisher.NewMemory(), nil
+	case config.PublisherStdout:
+		return publisher.Stdout(), nil
+	case config.PublisherFile:
+		p, err := publisher.OpenFile(cfg.Publisher.File)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case config.PublisherNone:
+		return publisher.Nop{}, nil
+	default:
+		return nil, fmt.Errorf("unknown publisher %q", cfg.Publisher.Kind)
+	}
+}
+
+// newSyncPublisher opens publisher like newPublisher, but kafka producer is always sync
+func newSyncPublisher(cfg *config.Config) (publisher.Publisher, error) {
+	syncCfg := *cfg
+	syncCfg.Kafka.Producer = config.ProducerSync
+
+	return newPublisher(&syncCfg, slog.Default())
+}
+
+// newProducer opens kafka producer selected by kafka.producer. Failures of async producer are logged
+// with the event and the number of events failed so far, so lost events can be found and alerted on.
+func newProducer(cfg *config.Config, log *slog.Logger) (*kafka.Producer, error) {
+	producerConfig, err := newProducerConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var producer *kafka.Producer //set before the first event is sent
+	if producerConfig.Async {
+		producerConfig.OnDelivery = func(d kafka.Delivery) {
+			if d.Err != nil {
+				log.Error("Can't send event to kafka: ",
+					slog.String("id", d.Event.ID),
+					slog.String("type", d.Event.Type),
+					slog.String("key", d.Event.PartitionKey),
+					slog.Int64("sequence", d.Event.Sequence),
+					slog.Int64("failed", producer.Stats().Failed),
+					logger.Err(d.Err),
+				)
+			}
+		}
+	}
+
+	producer, err = kafka.NewProducer(cfg.Brokers, cfg.Topic, producerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return producer, nil
+}
+
+func newProducerConfig(cfg *config.Config) (kafka.ProducerConfig, error) {
+	producerConfig := kafka.ProducerConfig{
+		Compression:   cfg.Kafka.Compression,
+		BatchMessages: cfg.Kafka.BatchMessages,
+		BatchBytes:    cfg.Kafka.BatchBytes,
+		Linger:        cfg.Kafka.Linger,
+		FlushTimeout:  cfg.Kafka.FlushTimeout,
+	}
+
+	codec, err := events.CodecByName(cfg.Kafka.Encoding)
+	if err != nil {
+		return producerConfig, err
+	}
+	producerConfig.Codec = codec
+
+	switch cfg.Kafka.Producer {
+	case config.ProducerSync:
+	case config.ProducerAsync:
+		producerConfig.Async = true
+	default:
+		return producerConfig, fmt.Errorf("unknown kafka producer %q", cfg.Kafka.Producer)
+	}
+
+	return producerConfig, nil
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"fmt"
+	"wallet/internal/config"
+	"wallet/internal/storage"
+	"wallet/internal/storage/memory"
+	"wallet/internal/storage/postgre"
+	"wallet/internal/storage/sqlite"
+)
+
+// newStorage opens storage selected by storage.driver
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.Storage.Driver {
+	case config.DriverPostgres:
+		dsn := cfg.Storage.PostgresDSN
+		if dsn == "" {
+			dsn = cfg.DBServer.DSN()
+		}
+
+		s, err := postgre.New(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case config.DriverSQLite:
+		s, err := sqlite.New(cfg.Storage.SQLiteDSN)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case config.DriverMemory:
+		return memory.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -9,10 +10,69 @@ import (
 )
 
 type Config struct {
-	Env        string `yaml:"env" env-required:"true"`
-	DBServer   `yaml:"db_server" env-required:"true"`
-	HTTPServer `yaml:"http_server"`
-	Kafka      `yaml:"kafka"`
+	Env         string `yaml:"env" env-required:"true"`
+	Storage     `yaml:"storage"`
+	DBServer    `yaml:"db_server"`
+	HTTPServer  `yaml:"http_server"`
+	GRPC        GRPCServer `yaml:"grpc_server"`
+	Kafka       `yaml:"kafka"`
+	Publisher   `yaml:"publisher"`
+	Currency    string `yaml:"currency" env-default:"USD"`
+	Accounting  `yaml:"accounting"`
+	Import      `yaml:"import"`
+	Reconcile   `yaml:"reconcile"`
+	Idempotency `yaml:"idempotency"`
+	Webhooks    Webhooks `yaml:"webhooks"`
+	Streams     Streams  `yaml:"streams"`
+}
+
+// Streams configures pushing of wallet changes over SSE and WebSocket
+type Streams struct {
+	Buffer       int           `yaml:"buffer" env-default:"64"`     //pending changes per connection, slower clients are disconnected
+	History      int           `yaml:"history" env-default:"10000"` //latest changes kept to resume streams
+	Heartbeat    time.Duration `yaml:"heartbeat" env-default:"15s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env-default:"10s"` //of one event, 0 means no limit
+}
+
+// Webhooks configures delivery of events to partner endpoints
+type Webhooks struct {
+	Enabled      bool          `yaml:"enabled" env-default:"true"`    //webhook management routes are registered only when enabled
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"8"`  //attempts of one delivery
+	MinBackoff   time.Duration `yaml:"min_backoff" env-default:"10s"` //delay before the first retry, doubled for each next one
+	MaxBackoff   time.Duration `yaml:"max_backoff" env-default:"1h"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`      //of one attempt
+	DisableAfter int           `yaml:"disable_after" env-default:"20"` //consecutive failed attempts which disable webhook
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"5s"` //how often due retries are checked
+	Workers      int           `yaml:"workers" env-default:"4"`
+	Lease        time.Duration `yaml:"lease" env-default:"1m"`      //how long claimed deliveries are hidden from other instances
+	CacheTTL     time.Duration `yaml:"cache_ttl" env-default:"10s"` //how long webhooks changed by other instances may be stale
+}
+
+// Idempotency configures replay of mutating requests sent with Idempotency-Key header
+type Idempotency struct {
+	TTL      time.Duration `yaml:"ttl" env-default:"24h"`          //how long responses are kept
+	MaxItems int           `yaml:"max_items" env-default:"100000"` //responses kept by an instance, the oldest are dropped
+}
+
+// Import configures bulk import of wallets
+type Import struct {
+	BatchSize int `yaml:"batch_size" env-default:"500"` //wallets per transaction
+}
+
+// Accounting configures export into double-entry journal
+type Accounting struct {
+	WalletPrefix   string            `yaml:"wallet_prefix" env-default:"Assets:Wallets"`
+	WalletAccounts map[string]string `yaml:"wallet_accounts"` //wallet ID -> account
+	Deposits       string            `yaml:"deposits_account" env-default:"Equity:External:Deposits"`
+	Withdrawals    string            `yaml:"withdrawals_account" env-default:"Equity:External:Withdrawals"`
+	Opening        string            `yaml:"opening_account" env-default:"Equity:Opening-Balances"`
+}
+
+// Reconcile configures the ledger reconciliation job
+type Reconcile struct {
+	Enabled  bool          `yaml:"enabled" env-default:"true"`
+	Interval time.Duration `yaml:"interval" env-default:"1h"`
+	Epsilon  float64       `yaml:"epsilon" env-default:"1e-9"` //relative tolerance of balance comparison
 }
 
 type HTTPServer struct {
@@ -21,14 +81,80 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 }
 
+// GRPCServer configures gRPC API served alongside HTTP one
+type GRPCServer struct {
+	Enabled bool          `yaml:"enabled" env-default:"true"`
+	Address string        `yaml:"address" env-default:"localhost:9091"`
+	Timeout time.Duration `yaml:"timeout" env-default:"4s"`      //applied to calls without client deadline
+	Buffer  int           `yaml:"watch_buffer" env-default:"64"` //pending changes per WatchWallets stream
+}
+
 type Kafka struct {
 	Brokers []string `yaml:"brokers"`
 	Topic   string   `yaml:"topic"`
+	//Producer is sync or async, async one doesn't wait for brokers in requests
+	Producer      string        `yaml:"producer" env:"KAFKA_PRODUCER" env-default:"sync"`
+	Compression   string        `yaml:"compression" env-default:"none"`  //none, gzip, snappy, lz4, zstd
+	BatchMessages int           `yaml:"batch_messages" env-default:"0"`  //messages which trigger a batch
+	BatchBytes    int           `yaml:"batch_bytes" env-default:"0"`     //bytes which trigger a batch
+	Linger        time.Duration `yaml:"linger" env-default:"0s"`         //how long a batch is collected
+	FlushTimeout  time.Duration `yaml:"flush_timeout" env-default:"10s"` //how long pending events are flushed on shutdown
+
+	//Encoding of events is json or protobuf, consumers tell it by content-type header
+	Encoding string `yaml:"encoding" env:"KAFKA_ENCODING" env-default:"json"`
 }
 
+// Kafka producer modes
+const (
+	ProducerSync  = "sync"
+	ProducerAsync = "async"
+)
+
+// Publisher selects where events are published: kafka, memory, stdout, file or none
+type Publisher struct {
+	Kind string `yaml:"kind" env:"PUBLISHER" env-default:"kafka"`
+	File string `yaml:"file" env:"PUBLISHER_FILE" env-default:"events.jsonl"` //JSON Lines file of file publisher, appended
+}
+
+// Event publishers
+const (
+	PublisherKafka  = "kafka"
+	PublisherMemory = "memory" //keeps every event, for tests
+	PublisherStdout = "stdout"
+	PublisherFile   = "file"
+	PublisherNone   = "none"
+)
+
+// Storage selects storage driver: postgres, sqlite or memory
+type Storage struct {
+	Driver      string `yaml:"driver" env:"STORAGE_DRIVER" env-default:"postgres"`
+	PostgresDSN string `yaml:"postgres_dsn" env:"POSTGRES_DSN"` //db_server is used when empty
+	SQLiteDSN   string `yaml:"sqlite_dsn" env:"SQLITE_DSN" env-default:"storage/wallet.db"`
+}
+
+// Storage drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
 type DBServer struct {
-	Host string `yaml:"host" env-required:"true"`
-	Port int    `yaml:"port" env-required:"true"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user" env-default:"wallets_admin"`
+	Password string `yaml:"password" env:"DB_PASSWORD" env-default:"admin"`
+	Name     string `yaml:"name" env-default:"wallets_db"`
+	//AutoMigrate applies pending migrations at startup, otherwise run "migrate" command
+	AutoMigrate bool `yaml:"auto_migrate" env-default:"true"`
+}
+
+// DSN returns postgres connection string of db_server
+func (d DBServer) DSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		d.Host, d.Port, d.User, d.Password, d.Name,
+	)
 }
 
 const configPath = "internal/config/local.yaml"
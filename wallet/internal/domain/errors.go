@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds. Every domain error is of one of these kinds, handlers choose response status by kind.
+// Kind message is a human readable title of all errors of the kind.
+var (
+	ErrInvalidArgument   = &Error{code: "invalid_argument", msg: "Invalid argument"}
+	ErrNotFound          = &Error{code: "not_found", msg: "Not found"}
+	ErrExists            = &Error{code: "already_exists", msg: "Already exists"}
+	ErrConflict          = &Error{code: "conflict", msg: "Conflict"}
+	ErrInvalidAmount     = &Error{code: "invalid_amount", msg: "Invalid amount"}
+	ErrInsufficientFunds = &Error{code: "insufficient_funds", msg: "Insufficient funds"}
+	ErrInactive          = &Error{code: "inactive", msg: "Inactive"}
+)
+
+// Error is an error with a stable code and a message safe to show to clients.
+// Internal details are attached with Wrap and are only visible in logs.
+type Error struct {
+	kind *Error
+	code string
+	msg  string
+}
+
+// New returns a domain error of the given kind. Code must not change once released, clients rely on it.
+func New(kind *Error, code, msg string) *Error {
+	return &Error{kind: kind.Kind(), code: code, msg: msg}
+}
+
+// Errorf returns a domain error of the given kind with formatted message
+func Errorf(kind *Error, code, format string, args ...any) *Error {
+	return New(kind, code, fmt.Sprintf(format, args...))
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+func (e *Error) Unwrap() error {
+	if e.kind == nil {
+		return nil
+	}
+	return e.kind
+}
+
+// Kind returns one of the error kinds
+func (e *Error) Kind() *Error {
+	if e.kind == nil {
+		return e
+	}
+	return e.kind
+}
+
+// Code returns machine readable error code, e.g. wallet_not_found
+func (e *Error) Code() string {
+	return e.code
+}
+
+// Title returns human readable summary of the error kind
+func (e *Error) Title() string {
+	return e.Kind().msg
+}
+
+// Wrap attaches cause to the domain error, both are matched by errors.Is
+func Wrap(err *Error, cause error) error {
+	return fmt.Errorf("%w: %w", err, cause)
+}
+
+// As returns the outermost domain error of the chain
+func As(err error) (*Error, bool) {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,219 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+	"wallet/internal/notify"
+	"wallet/internal/service"
+	"wallet/internal/storage"
+	"wallet/pkg/walletpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements walletpb.WalletServiceServer on top of the wallet service
+type Server struct {
+	walletpb.UnimplementedWalletServiceServer
+
+	wallets *service.WalletService
+	hub     *notify.Hub
+	buffer  int //pending changes per WatchWallets stream
+}
+
+// New returns gRPC server with registered WalletService.
+// Calls without deadline are limited by timeout, deadlines set by clients are propagated as is.
+// WatchWallets streams which fall behind by more than buffer changes are ended.
+func New(wallets *service.WalletService, hub *notify.Hub, timeout time.Duration, buffer int, log *slog.Logger) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			recoverUnary(log),
+			timeoutUnary(timeout),
+			correlationUnary(),
+		),
+		grpc.ChainStreamInterceptor(
+			recoverStream(log),
+		),
+	)
+
+	walletpb.RegisterWalletServiceServer(srv, &Server{wallets: wallets, hub: hub, buffer: buffer})
+
+	return srv
+}
+
+// Serve accepts connections on address until server is stopped
+func Serve(srv *grpc.Server, address string) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(lis)
+}
+
+func (s *Server) CreateWallet(ctx context.Context, req *walletpb.CreateWalletRequest) (*walletpb.CreateWalletResponse, error) {
+	wallet, err := s.wallets.CreateWallet(ctx, req.GetName())
+	if err != nil {
+		return nil, Status(ctx, err)
+	}
+
+	return &walletpb.CreateWalletResponse{Wallet: toWallet(wallet)}, nil
+}
+
+func (s *Server) GetWallet(ctx context.Context, req *walletpb.GetWalletRequest) (*walletpb.GetWalletResponse, error) {
+	wallet, err := s.wallets.GetWallet(ctx, req.GetId())
+	if err != nil {
+		return nil, Status(ctx, err)
+	}
+
+	return &walletpb.GetWalletResponse{Wallet: toWallet(wallet)}, nil
+}
+
+func (s *Server) ListWallets(ctx context.Context, req *walletpb.ListWalletsRequest) (*walletpb.ListWalletsResponse, error) {
+	query := storage.WalletQuery{
+		WalletFilter: storage.WalletFilter{
+			Tags:       req.GetTags(),
+			Metadata:   req.GetMetadata(),
+			Status:     req.GetStatus(),
+			MinBalance: req.MinBalance,
+			MaxBalance: req.MaxBalance,
+			NamePrefix: req.GetNamePrefix(),
+		},
+		SortBy:    req.GetSort(),
+		Desc:      req.GetDesc(),
+		Limit:     int(req.GetLimit()),
+		Cursor:    req.GetCursor(),
+		WithTotal: req.GetWithTotal(),
+	}
+
+	page, err := s.wallets.ListWallets(ctx, query)
+	if err != nil {
+		return nil, Status(ctx, err)
+	}
+
+	resp := &walletpb.ListWalletsResponse{
+		Wallets:    make([]*walletpb.Wallet, 0, len(page.Wallets)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Wallets {
+		resp.Wallets = append(resp.Wallets, toWallet(&page.Wallets[i]))
+	}
+	if page.Total != nil {
+		total := int64(*page.Total)
+		resp.Total = &total
+	}
+
+	return resp, nil
+}
+
+func (s *Server) Deposit(ctx context.Context, req *walletpb.DepositRequest) (*walletpb.DepositResponse, error) {
+	if _, err := s.wallets.Deposit(ctx, req.GetWalletId(), req.GetAmount()); err != nil {
+		return nil, Status(ctx, err)
+	}
+
+	return &walletpb.DepositResponse{WalletId: req.GetWalletId(), Amount: req.GetAmount()}, nil
+}
+
+func (s *Server) Withdraw(ctx context.Context, req *walletpb.WithdrawRequest) (*walletpb.WithdrawResponse, error) {
+	if _, err := s.wallets.Withdraw(ctx, req.GetWalletId(), req.GetAmount()); err != nil {
+		return nil, Status(ctx, err)
+	}
+
+	return &walletpb.WithdrawResponse{WalletId: req.GetWalletId(), Amount: req.GetAmount()}, nil
+}
+
+func (s *Server) Transfer(ctx context.Context, req *walletpb.TransferRequest) (*walletpb.TransferResponse, error) {
+	if _, _, err := s.wallets.Transfer(ctx, req.GetWalletId(), req.GetAmount(), req.GetTransferTo()); err != nil {
+		return nil, Status(ctx, err)
+	}
+
+	return &walletpb.TransferResponse{
+		WalletId:   req.GetWalletId(),
+		TransferTo: req.GetTransferTo(),
+		Amount:     req.GetAmount(),
+	}, nil
+}
+
+func (s *Server) RenameWallet(ctx context.Context, req *walletpb.RenameWalletRequest) (*walletpb.RenameWalletResponse, error) {
+	if _, err := s.wallets.UpdateName(ctx, req.GetId(), req.GetName()); err != nil {
+		return nil, Status(ctx, err)
+	}
+
+	wallet, err := s.wallets.GetWallet(ctx, req.GetId())
+	if err != nil {
+		return nil, Status(ctx, err)
+	}
+
+	return &walletpb.RenameWalletResponse{Wallet: toWallet(wallet)}, nil
+}
+
+func (s *Server) DeactivateWallet(ctx context.Context, req *walletpb.DeactivateWalletRequest) (*walletpb.DeactivateWalletResponse, error) {
+	if _, err := s.wallets.DeactivateWallet(ctx, req.GetId()); err != nil {
+		return nil, Status(ctx, err)
+	}
+
+	return &walletpb.DeactivateWalletResponse{}, nil
+}
+
+func (s *Server) WatchWallets(req *walletpb.WatchWalletsRequest, stream grpc.ServerStreamingServer[walletpb.WatchWalletsResponse]) error {
+	ctx := stream.Context()
+
+	sub := s.hub.Subscribe(s.buffer, req.GetWalletIds()...)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return Status(ctx, ctx.Err())
+		case change, ok := <-sub.Changes():
+			if !ok {
+				if sub.Err() == notify.ErrSlowSubscriber {
+					return status.Error(codes.ResourceExhausted, "too many pending changes, watch again")
+				}
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if err := stream.Send(&walletpb.WatchWalletsResponse{Change: toChange(change)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toWallet(wallet *storage.Wallet) *walletpb.Wallet {
+	pb := &walletpb.Wallet{
+		Id:      wallet.ID,
+		Name:    wallet.Name,
+		Balance: wallet.Balance,
+		Status:  wallet.Status,
+		Tags:    wallet.Tags,
+	}
+	if len(wallet.Metadata) > 0 {
+		//metadata is decoded from JSON, so it is always convertible
+		pb.Metadata, _ = structpb.NewStruct(wallet.Metadata)
+	}
+	if !wallet.CreatedAt.IsZero() {
+		pb.CreatedAt = timestamppb.New(wallet.CreatedAt)
+	}
+	if !wallet.UpdatedAt.IsZero() {
+		pb.UpdatedAt = timestamppb.New(wallet.UpdatedAt)
+	}
+	return pb
+}
+
+func toChange(change notify.Change) *walletpb.WalletChange {
+	return &walletpb.WalletChange{
+		Seq:          change.Seq,
+		WalletId:     change.WalletID,
+		Type:         change.Type,
+		Name:         change.Name,
+		Status:       change.Status,
+		Balance:      change.Balance,
+		Amount:       change.Amount,
+		Counterparty: change.Counterparty,
+		At:           timestamppb.New(change.At),
+	}
+}
@@ -0,0 +1,86 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"testing"
+	"time"
+	"wallet/internal/publisher"
+	"wallet/internal/service"
+	"wallet/internal/storage/memory"
+	"wallet/pkg/walletpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T) walletpb.WalletServiceClient {
+	t.Helper()
+
+	wallets := service.New(memory.New(), publisher.NewMemory(), nil)
+	srv := New(wallets, nil, time.Second, 10, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return walletpb.NewWalletServiceClient(conn)
+}
+
+// TestNonFiniteAmount checks that doubles which JSON can't carry are rejected before they reach the balance
+func TestNonFiniteAmount(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+
+	created := make([]string, 0, 2)
+	for _, name := range []string{"alice", "bob"} {
+		resp, err := client.CreateWallet(ctx, &walletpb.CreateWalletRequest{Name: name})
+		if err != nil {
+			t.Fatalf("CreateWallet: %v", err)
+		}
+		created = append(created, resp.GetWallet().GetId())
+	}
+	alice, bob := created[0], created[1]
+	if _, err := client.Deposit(ctx, &walletpb.DepositRequest{WalletId: alice, Amount: 10}); err != nil {
+		t.Fatalf("Deposit: %v", err)
+	}
+
+	for _, amount := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		_, err := client.Deposit(ctx, &walletpb.DepositRequest{WalletId: alice, Amount: amount})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Deposit %v: %v, want %s", amount, err, codes.InvalidArgument)
+		}
+		_, err = client.Withdraw(ctx, &walletpb.WithdrawRequest{WalletId: alice, Amount: amount})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Withdraw %v: %v, want %s", amount, err, codes.InvalidArgument)
+		}
+		_, err = client.Transfer(ctx, &walletpb.TransferRequest{WalletId: alice, TransferTo: bob, Amount: amount})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Transfer %v: %v, want %s", amount, err, codes.InvalidArgument)
+		}
+	}
+
+	for id, want := range map[string]float64{alice: 10, bob: 0} {
+		resp, err := client.GetWallet(ctx, &walletpb.GetWalletRequest{Id: id})
+		if err != nil {
+			t.Fatalf("GetWallet: %v", err)
+		}
+		if got := resp.GetWallet().GetBalance(); got != want {
+			t.Errorf("balance of %s = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"wallet/internal/domain"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is ErrorInfo domain of wallet errors
+const errorDomain = "wallet"
+
+// Status converts error to gRPC status. Domain errors keep their message and code as ErrorInfo reason,
+// other errors are logged and reported as internal, so their details never reach clients.
+func Status(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	}
+
+	domainErr, ok := domain.As(err)
+	code := codes.Internal
+	if ok {
+		code = kindCode(domainErr.Kind())
+	}
+	if code == codes.Internal {
+		slog.ErrorContext(ctx, "Call failed", slog.String("error", err.Error()))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	st := status.New(code, domainErr.Error())
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: domainErr.Code(), Domain: errorDomain}); err == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
+
+func kindCode(kind *domain.Error) codes.Code {
+	switch kind {
+	case domain.ErrInvalidArgument, domain.ErrInvalidAmount:
+		return codes.InvalidArgument
+	case domain.ErrNotFound:
+		return codes.NotFound
+	case domain.ErrExists:
+		return codes.AlreadyExists
+	case domain.ErrConflict:
+		return codes.Aborted
+	case domain.ErrInsufficientFunds, domain.ErrInactive:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"wallet/internal/notify"
+	"wallet/internal/storage"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+)
+
+// Event types of wallet change streams
+const (
+	StreamSnapshot = "snapshot" //current wallet state, sent first unless the stream is resumed
+	StreamChange   = "change"
+	StreamError    = "error" //the stream is ended, reconnect with the last event ID
+)
+
+// codeSlowSubscriber tells clients they were disconnected because they did not keep up with changes
+const codeSlowSubscriber = "slow_subscriber"
+
+// StreamConfig configures wallet change streams
+type StreamConfig struct {
+	Buffer       int           //pending changes per connection, slower clients are disconnected
+	Heartbeat    time.Duration //interval of SSE comments and WebSocket pings
+	WriteTimeout time.Duration //of one event, 0 means no limit
+}
+
+type ChangeSubscriber interface {
+	Resume(lastEventID string, buffer int, walletIDs ...string) (*notify.Subscription, bool)
+	EventID(change notify.Change) string
+}
+
+// StreamEvent is a WebSocket message
+type StreamEvent struct {
+	Type   string          `json:"type"`
+	ID     string          `json:"id,omitempty"`
+	Wallet *storage.Wallet `json:"wallet,omitempty"`
+	Change *notify.Change  `json:"change,omitempty"`
+	Code   string          `json:"code,omitempty"`
+}
+
+// openStream subscribes to wallet changes. Snapshot is returned when changes after lastEventID can't be resumed.
+func openStream(ctx context.Context, recipient WalletRecipient, changes ChangeSubscriber, cfg StreamConfig, walletID, lastEventID string) (*notify.Subscription, *StreamEvent, error) {
+	if walletID == "" {
+		return nil, nil, invalidRequest("Invalid request")
+	}
+
+	//subscribe before loading wallet, so no change is lost in between
+	sub, complete := changes.Resume(lastEventID, cfg.Buffer, walletID)
+
+	wallet, err := recipient.GetWallet(ctx, walletID)
+	if err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	if complete {
+		return sub, nil, nil
+	}
+
+	return sub, &StreamEvent{Type: StreamSnapshot, ID: sub.StartEventID(), Wallet: wallet}, nil
+}
+
+// WalletEventsHandler streams changes of wallet as server-sent events.
+// The stream is resumed after Last-Event-ID header or last_event_id query parameter,
+// otherwise or when the changes are not kept anymore it starts with snapshot event.
+func WalletEventsHandler(recipient WalletRecipient, changes ChangeSubscriber, cfg StreamConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("last_event_id")
+		}
+
+		sub, snapshot, err := openStream(r.Context(), recipient, changes, cfg, chi.URLParam(r, "id"), lastEventID)
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+		defer sub.Close()
+
+		stream := &sseWriter{w: w, rc: http.NewResponseController(w), timeout: cfg.WriteTimeout}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no") //disable proxy buffering
+		w.WriteHeader(http.StatusOK)
+
+		if snapshot != nil {
+			if err := stream.event(snapshot.ID, StreamSnapshot, snapshot.Wallet); err != nil {
+				return
+			}
+		} else if err := stream.comment("resumed"); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(cfg.Heartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				if err := stream.comment("heartbeat"); err != nil {
+					return
+				}
+			case change, ok := <-sub.Changes():
+				if !ok {
+					if errors.Is(sub.Err(), notify.ErrSlowSubscriber) {
+						stream.event("", StreamError, StreamEvent{Type: StreamError, Code: codeSlowSubscriber})
+					}
+					return
+				}
+				if err := stream.event(changes.EventID(change), StreamChange, change); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// sseWriter writes events with write deadline, so a client which stopped reading is disconnected
+type sseWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (s *sseWriter) event(id, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if id != "" {
+		return s.write("id: %s\nevent: %s\ndata: %s\n\n", id, event, payload)
+	}
+	return s.write("event: %s\ndata: %s\n\n", event, payload)
+}
+
+func (s *sseWriter) comment(text string) error {
+	return s.write(": %s\n\n", text)
+}
+
+func (s *sseWriter) write(format string, args ...any) error {
+	//the server write timeout limits whole responses, streams are limited per event instead
+	if err := s.rc.SetWriteDeadline(writeDeadline(s.timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// writeDeadline returns deadline of a write started now, zero time when timeout is not limited
+func writeDeadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// WalletEventsWebSocketHandler streams changes of wallet as StreamEvent messages over WebSocket.
+// The stream is resumed after last_event_id query parameter, like WalletEventsHandler.
+// Messages sent by client are ignored, the connection is checked with pings every heartbeat.
+func WalletEventsWebSocketHandler(recipient WalletRecipient, changes ChangeSubscriber, cfg StreamConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		sub, snapshot, err := openStream(r.Context(), recipient, changes, cfg, chi.URLParam(r, "id"), r.URL.Query().Get("last_event_id"))
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+		defer sub.Close()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return //upgrader has responded
+		}
+		defer conn.Close()
+
+		//hijacked connection keeps deadlines of the server, they are replaced by heartbeat ones
+		conn.SetReadDeadline(time.Now().Add(2 * cfg.Heartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * cfg.Heartbeat))
+		})
+
+		//reading is required to process pongs and close frames
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		send := func(event StreamEvent) error {
+			conn.SetWriteDeadline(writeDeadline(cfg.WriteTimeout))
+			return conn.WriteJSON(event)
+		}
+
+		if snapshot != nil {
+			if err := send(*snapshot); err != nil {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(cfg.Heartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case <-heartbeat.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, writeDeadline(cfg.WriteTimeout)); err != nil {
+					return
+				}
+			case change, ok := <-sub.Changes():
+				if !ok {
+					code, reason := websocket.CloseGoingAway, "server is shutting down"
+					if errors.Is(sub.Err(), notify.ErrSlowSubscriber) {
+						send(StreamEvent{Type: StreamError, Code: codeSlowSubscriber})
+						code, reason = websocket.CloseTryAgainLater, codeSlowSubscriber
+					}
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), writeDeadline(cfg.WriteTimeout))
+					return
+				}
+				if err := send(StreamEvent{Type: StreamChange, ID: changes.EventID(change), Change: &change}); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wallet/internal/notify"
+	"wallet/internal/storage"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+)
+
+// stubRecipient returns wallet. When loading is set, GetWallet signals it and waits for a signal back,
+// so changes can be published between subscription and the snapshot.
+type stubRecipient struct {
+	wallet  storage.Wallet
+	loading chan struct{}
+}
+
+func (s *stubRecipient) GetWallet(ctx context.Context, walletID string) (*storage.Wallet, error) {
+	if s.loading != nil {
+		s.loading <- struct{}{}
+		<-s.loading
+	}
+	if walletID != s.wallet.ID {
+		return nil, storage.ErrWalletNotFound
+	}
+	wallet := s.wallet
+	return &wallet, nil
+}
+
+func newStreamServer(t *testing.T, recipient WalletRecipient, hub *notify.Hub, cfg StreamConfig) *httptest.Server {
+	t.Helper()
+
+	if cfg.Heartbeat == 0 {
+		cfg.Heartbeat = time.Hour
+	}
+
+	router := chi.NewRouter()
+	router.Get("/wallets/{id}/events", WalletEventsHandler(recipient, hub, cfg))
+	router.Get("/wallets/{id}/ws", WalletEventsWebSocketHandler(recipient, hub, cfg))
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// sseEvent is one block of SSE stream
+type sseEvent struct {
+	id, event, data, comment string
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+
+	var event sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return event
+		}
+
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			event.id = value
+		case "event":
+			event.event = value
+		case "data":
+			event.data = value
+		case "":
+			event.comment = value
+		default:
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
+
+func TestWalletEventsSSE(t *testing.T) {
+	hub := notify.NewHub(10)
+	//zero write timeout leaves events without deadline
+	srv := newStreamServer(t, &stubRecipient{wallet: storage.Wallet{ID: "alice", Name: "Alice", Balance: 10}}, hub, StreamConfig{Buffer: 10})
+
+	resp, err := http.Get(srv.URL + "/wallets/alice/events")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("response %d %s, want 200 text/event-stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	r := bufio.NewReader(resp.Body)
+
+	snapshot := readEvent(t, r)
+	var wallet storage.Wallet
+	if snapshot.event != StreamSnapshot || snapshot.id != hub.EventID(notify.Change{}) || json.Unmarshal([]byte(snapshot.data), &wallet) != nil || wallet.Name != "Alice" {
+		t.Fatalf("first event = %+v, want snapshot of alice", snapshot)
+	}
+
+	hub.Publish(notify.Change{WalletID: "bob", Type: notify.ChangeDeposit, Balance: 1})
+	hub.Publish(notify.Change{WalletID: "alice", Type: notify.ChangeDeposit, Balance: 15, Amount: 5})
+
+	change := readEvent(t, r)
+	var got notify.Change
+	if change.event != StreamChange || change.id != hub.EventID(notify.Change{Seq: 2}) || json.Unmarshal([]byte(change.data), &got) != nil {
+		t.Fatalf("second event = %+v, want change 2 of alice", change)
+	}
+	if got.WalletID != "alice" || got.Balance != 15 || got.Amount != 5 {
+		t.Errorf("change = %+v", got)
+	}
+}
+
+func TestWalletEventsSSEResume(t *testing.T) {
+	hub := notify.NewHub(10)
+	srv := newStreamServer(t, &stubRecipient{wallet: storage.Wallet{ID: "alice"}}, hub, StreamConfig{Buffer: 10, WriteTimeout: time.Second})
+
+	hub.Publish(notify.Change{WalletID: "alice", Type: notify.ChangeDeposit})
+	hub.Publish(notify.Change{WalletID: "alice", Type: notify.ChangeWithdraw})
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/wallets/alice/events", nil)
+	req.Header.Set("Last-Event-ID", hub.EventID(notify.Change{Seq: 1}))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+
+	if event := readEvent(t, r); event.comment != "resumed" {
+		t.Fatalf("first event = %+v, want resumed comment", event)
+	}
+	if event := readEvent(t, r); event.event != StreamChange || event.id != hub.EventID(notify.Change{Seq: 2}) {
+		t.Errorf("second event = %+v, want missed change 2", event)
+	}
+}
+
+func TestWalletEventsSSESlowSubscriber(t *testing.T) {
+	hub := notify.NewHub(10)
+	recipient := &stubRecipient{wallet: storage.Wallet{ID: "alice"}, loading: make(chan struct{})}
+	srv := newStreamServer(t, recipient, hub, StreamConfig{Buffer: 1})
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(srv.URL + "/wallets/alice/events")
+		if err != nil {
+			t.Errorf("Get: %v", err)
+		}
+		responses <- resp
+	}()
+
+	//the handler has subscribed, two changes overflow its buffer before the snapshot is sent
+	<-recipient.loading
+	hub.Publish(notify.Change{WalletID: "alice", Type: notify.ChangeDeposit})
+	hub.Publish(notify.Change{WalletID: "alice", Type: notify.ChangeDeposit})
+	recipient.loading <- struct{}{}
+
+	resp := <-responses
+	if resp == nil {
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+
+	for _, want := range []string{StreamSnapshot, StreamChange, StreamError} {
+		if event := readEvent(t, r); event.event != want {
+			t.Fatalf("event = %+v, want %s", event, want)
+		}
+	}
+	if _, err := r.ReadByte(); err == nil {
+		t.Errorf("stream is not closed after error event")
+	}
+}
+
+func TestWalletEventsNotFound(t *testing.T) {
+	srv := newStreamServer(t, &stubRecipient{wallet: storage.Wallet{ID: "alice"}}, notify.NewHub(10), StreamConfig{})
+
+	for _, path := range []string{"/wallets/bob/events", "/wallets/bob/ws"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s responded %d, want 404", path, resp.StatusCode)
+		}
+	}
+}
+
+func dial(t *testing.T, srv *httptest.Server, path string) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+path, nil)
+	if err != nil {
+		t.Errorf("Dial: %v", err)
+		return nil
+	}
+	return conn
+}
+
+func readStreamEvent(t *testing.T, conn *websocket.Conn) StreamEvent {
+	t.Helper()
+
+	var event StreamEvent
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	return event
+}
+
+func assertCloseCode(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != code {
+		t.Errorf("read after the stream end: %v, want close code %d", err, code)
+	}
+}
+
+func TestWalletEventsWebSocket(t *testing.T) {
+	hub := notify.NewHub(10)
+	srv := newStreamServer(t, &stubRecipient{wallet: storage.Wallet{ID: "alice", Name: "Alice"}}, hub, StreamConfig{Buffer: 10})
+
+	conn := dial(t, srv, "/wallets/alice/ws")
+	if conn == nil {
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	if event := readStreamEvent(t, conn); event.Type != StreamSnapshot || event.Wallet == nil || event.Wallet.Name != "Alice" {
+		t.Fatalf("first message = %+v, want snapshot of alice", event)
+	}
+
+	hub.Publish(notify.Change{WalletID: "alice", Type: notify.ChangeRenamed, Name: "Main"})
+	event := readStreamEvent(t, conn)
+	if event.Type != StreamChange || event.ID != hub.EventID(notify.Change{Seq: 1}) || event.Change == nil || event.Change.Name != "Main" {
+		t.Fatalf("second message = %+v, want change 1", event)
+	}
+
+	//subscribers are disconnected on shutdown
+	hub.Close()
+	assertCloseCode(t, conn, websocket.CloseGoingAway)
+}
+
+func TestWalletEventsWebSocketSlowSubscriber(t *testing.T) {
+	hub := notify.NewHub(10)
+	recipient := &stubRecipient{wallet: storage.Wallet{ID: "alice"}, loading: make(chan struct{})}
+	srv := newStreamServer(t, recipient, hub, StreamConfig{Buffer: 1, WriteTimeout: time.Second})
+
+	conns := make(chan *websocket.Conn, 1)
+	go func() {
+		conns <- dial(t, srv, "/wallets/alice/ws")
+	}()
+
+	<-recipient.loading
+	hub.Publish(notify.Change{WalletID: "alice", Type: notify.ChangeDeposit})
+	hub.Publish(notify.Change{WalletID: "alice", Type: notify.ChangeDeposit})
+	recipient.loading <- struct{}{}
+
+	conn := <-conns
+	if conn == nil {
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	for _, want := range []string{StreamSnapshot, StreamChange} {
+		if event := readStreamEvent(t, conn); event.Type != want {
+			t.Fatalf("message = %+v, want %s", event, want)
+		}
+	}
+	if event := readStreamEvent(t, conn); event.Type != StreamError || event.Code != codeSlowSubscriber {
+		t.Fatalf("message = %+v, want %s error", event, codeSlowSubscriber)
+	}
+	assertCloseCode(t, conn, websocket.CloseTryAgainLater)
+}
@@ -2,13 +2,24 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"time"
+	"wallet/internal/accounting"
+	"wallet/internal/domain"
+	"wallet/internal/statement"
+	"wallet/internal/storage"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator"
 )
 
+var errInvalidAmount = domain.New(domain.ErrInvalidAmount, "invalid_amount", "Amount must be more than 0")
+
 type WalletReplenisher interface {
 	Deposit(ctx context.Context, walletID string, amount float64) (int64, error)
 }
@@ -18,7 +29,7 @@ func WalletDepositHandler(replenisher WalletReplenisher) http.HandlerFunc {
 
 		walletID := chi.URLParam(r, "id")
 		if walletID == "" {
-			render.JSON(w, r, Error("Invalid request"))
+			RespondError(w, r, invalidRequest("Invalid request"))
 			return
 		}
 
@@ -26,24 +37,23 @@ func WalletDepositHandler(replenisher WalletReplenisher) http.HandlerFunc {
 
 		//разбираем запрос
 		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			render.JSON(w, r, Error("Can't decode request"))
+			RespondError(w, r, errMalformedBody)
 			return
 		}
 		if req.Amount <= 0 {
-			render.JSON(w, r, Error("Deposit amount must be more than 0"))
+			RespondError(w, r, errInvalidAmount)
 			return
 		}
 
 		//валидируем запрос
-		if err := validator.New().Struct(req); err != nil {
-			validateErr := err.(validator.ValidationErrors)
-			render.JSON(w, r, ValidationError(validateErr))
+		if err := validate.Struct(req); err != nil {
+			RespondError(w, r, err)
 			return
 		}
 
 		_, err := replenisher.Deposit(r.Context(), walletID, req.Amount)
 		if err != nil {
-			render.JSON(w, r, Error(err.Error()))
+			RespondError(w, r, err)
 			return
 		}
 
@@ -63,7 +73,7 @@ func WalletWithdrawHandler(withdrawer WalletWithdrawer) http.HandlerFunc {
 
 		walletID := chi.URLParam(r, "id")
 		if walletID == "" {
-			render.JSON(w, r, Error("Invalid request"))
+			RespondError(w, r, invalidRequest("Invalid request"))
 			return
 		}
 
@@ -71,24 +81,23 @@ func WalletWithdrawHandler(withdrawer WalletWithdrawer) http.HandlerFunc {
 
 		//разбираем запрос
 		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			render.JSON(w, r, Error("Can't decode request"))
+			RespondError(w, r, errMalformedBody)
 			return
 		}
 		if req.Amount <= 0 {
-			render.JSON(w, r, Error("Deposit amount must be more than 0"))
+			RespondError(w, r, errInvalidAmount)
 			return
 		}
 
 		//валидируем запрос
-		if err := validator.New().Struct(req); err != nil {
-			validateErr := err.(validator.ValidationErrors)
-			render.JSON(w, r, ValidationError(validateErr))
+		if err := validate.Struct(req); err != nil {
+			RespondError(w, r, err)
 			return
 		}
 
 		_, err := withdrawer.Withdraw(r.Context(), walletID, req.Amount)
 		if err != nil {
-			render.JSON(w, r, Error(err.Error()))
+			RespondError(w, r, err)
 			return
 		}
 
@@ -108,7 +117,7 @@ func WalletTransferHandler(transferer WalletTransferer) http.HandlerFunc {
 
 		walletID := chi.URLParam(r, "id")
 		if walletID == "" {
-			render.JSON(w, r, Error("Invalid request"))
+			RespondError(w, r, invalidRequest("Invalid request"))
 			return
 		}
 
@@ -116,28 +125,27 @@ func WalletTransferHandler(transferer WalletTransferer) http.HandlerFunc {
 
 		//разбираем запрос
 		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			render.JSON(w, r, Error("Can't decode request"))
+			RespondError(w, r, errMalformedBody)
 			return
 		}
 		if req.Amount <= 0 {
-			render.JSON(w, r, Error("Deposit amount must be more than 0"))
+			RespondError(w, r, errInvalidAmount)
 			return
 		}
 		if req.TransferTo == "" {
-			render.JSON(w, r, Error("Empty reciever wallet ID"))
+			RespondError(w, r, invalidField("transfer_to", "required", "Empty reciever wallet ID"))
 			return
 		}
 
 		//валидируем запрос
-		if err := validator.New().Struct(req); err != nil {
-			validateErr := err.(validator.ValidationErrors)
-			render.JSON(w, r, ValidationError(validateErr))
+		if err := validate.Struct(req); err != nil {
+			RespondError(w, r, err)
 			return
 		}
 
 		_, _, err := transferer.Transfer(r.Context(), walletID, req.Amount, req.TransferTo)
 		if err != nil {
-			render.JSON(w, r, Error(err.Error()))
+			RespondError(w, r, err)
 			return
 		}
 
@@ -147,3 +155,226 @@ func WalletTransferHandler(transferer WalletTransferer) http.HandlerFunc {
 		})
 	}
 }
+
+type WalletBalanceRecipient interface {
+	BalanceAt(ctx context.Context, walletID string, at time.Time) (*storage.WalletBalance, error)
+}
+
+// WalletBalanceHandler returns wallet balance at the moment given by RFC3339 "at" parameter (now by default)
+func WalletBalanceHandler(recipient WalletBalanceRecipient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		walletID := chi.URLParam(r, "id")
+		if walletID == "" {
+			RespondError(w, r, invalidRequest("Invalid request"))
+			return
+		}
+
+		at, err := parseTimeParam(r.URL.Query(), "at")
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		balance, err := recipient.BalanceAt(r.Context(), walletID, at)
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, balance)
+	}
+}
+
+type WalletsBalanceRecipient interface {
+	BalancesAt(ctx context.Context, at time.Time) ([]storage.WalletBalance, error)
+}
+
+// WalletsBalanceHandler returns balances of all wallets at the moment given by "at" parameter
+func WalletsBalanceHandler(recipient WalletsBalanceRecipient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		at, err := parseTimeParam(r.URL.Query(), "at")
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		balances, err := recipient.BalancesAt(r.Context(), at)
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, balances)
+	}
+}
+
+func parseTimeParam(query url.Values, name string) (time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, invalidParameter(name, fmt.Sprintf("Invalid %s parameter, RFC3339 time expected", name))
+	}
+
+	return value, nil
+}
+
+type WalletStatementWriter interface {
+	WriteStatement(ctx context.Context, walletID string, from, to time.Time, out statement.Writer) error
+}
+
+// WalletStatementHandler streams wallet statement for period given by RFC3339 "from" and "to" parameters.
+// Format is chosen with "format" parameter: csv, ofx or json (default).
+// Every chunk is written within writeTimeout, so long statements are not cut by the server write timeout.
+func WalletStatementHandler(writer WalletStatementWriter, currency string, writeTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		walletID := chi.URLParam(r, "id")
+		if walletID == "" {
+			RespondError(w, r, invalidRequest("Invalid request"))
+			return
+		}
+
+		query := r.URL.Query()
+
+		from, err := parseTimeParam(query, "from")
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+		to, err := parseTimeParam(query, "to")
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		format := query.Get("format")
+		if format == "" {
+			format = statement.FormatJSON
+		}
+
+		out := &streamWriter{
+			w:           w,
+			rc:          http.NewResponseController(w),
+			timeout:     writeTimeout,
+			contentType: statement.ContentType(format),
+			filename:    fmt.Sprintf("statement-%s.%s", walletID, format),
+		}
+
+		statementWriter, err := statement.NewWriter(format, out, currency)
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		err = writer.WriteStatement(r.Context(), walletID, from, to, statementWriter)
+		if err != nil && !out.started {
+			RespondError(w, r, err)
+			return
+		}
+		if err != nil {
+			//headers are already sent, statement is truncated
+			slog.Error("Statement streaming failed", slog.String("wallet_id", walletID), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// streamWriter sends response headers on the first write, so errors occurred before can be rendered as usual.
+// The write deadline is extended for every chunk, like events of wallet change streams.
+type streamWriter struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	timeout     time.Duration
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if err := s.rc.SetWriteDeadline(writeDeadline(s.timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return 0, err
+	}
+
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", s.contentType)
+		s.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.filename))
+		s.w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := s.w.Write(p)
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return n, err
+}
+
+type JournalExporter interface {
+	Export(ctx context.Context, format string, from, to time.Time, w io.Writer) error
+}
+
+// ExportJournalHandler streams double-entry journal in format given by "format" parameter: beancount (default) or ledger.
+// Optional RFC3339 "from" and "to" parameters limit exported period. Every chunk is written within writeTimeout.
+func ExportJournalHandler(exporter JournalExporter, writeTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		query := r.URL.Query()
+
+		from, err := parseTimeParam(query, "from")
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+		to, err := parseTimeParam(query, "to")
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		format := query.Get("format")
+		if format == "" {
+			format = accounting.FormatBeancount
+		}
+
+		out := &streamWriter{
+			w:           w,
+			rc:          http.NewResponseController(w),
+			timeout:     writeTimeout,
+			contentType: "text/plain; charset=utf-8",
+			filename:    "journal." + format,
+		}
+
+		err = exporter.Export(r.Context(), format, from, to, out)
+		if err != nil && !out.started {
+			RespondError(w, r, err)
+			return
+		}
+		if err != nil {
+			slog.Error("Journal streaming failed", slog.String("error", err.Error()))
+		}
+	}
+}
+
+type ReconciliationRecipient interface {
+	Latest(ctx context.Context) (*storage.Reconciliation, error)
+}
+
+// LatestReconciliationHandler returns the last report of the ledger reconciliation job
+func LatestReconciliationHandler(recipient ReconciliationRecipient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		report, err := recipient.Latest(r.Context())
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, report)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wallet/internal/statement"
+	"wallet/internal/storage"
+
+	"github.com/go-chi/chi"
+)
+
+// slowStatement writes lines operations, pausing after every batch of them
+type slowStatement struct {
+	lines, batch int
+	pause        time.Duration
+}
+
+func (s slowStatement) WriteStatement(ctx context.Context, walletID string, from, to time.Time, out statement.Writer) error {
+	if err := out.Begin(statement.Header{WalletID: walletID, Name: "alice"}); err != nil {
+		return err
+	}
+
+	for i := 0; i < s.lines; i++ {
+		if i > 0 && i%s.batch == 0 {
+			time.Sleep(s.pause)
+		}
+		line := statement.Line{
+			Operation: storage.Operation{ID: fmt.Sprintf("operation-%05d", i), Type: storage.OpDeposit, Amount: 1, CreatedAt: time.Now().UTC()},
+			Balance:   float64(i + 1),
+		}
+		if err := out.Line(line); err != nil {
+			return err
+		}
+	}
+
+	return out.End(float64(s.lines))
+}
+
+// TestWalletStatementWriteTimeout checks that a statement streamed longer than the server write timeout is not cut
+func TestWalletStatementWriteTimeout(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+
+	router := chi.NewRouter()
+	writer := slowStatement{lines: 400, batch: 40, pause: timeout / 4}
+	router.Get("/wallets/{id}/statement", WalletStatementHandler(writer, "USD", timeout))
+
+	srv := httptest.NewUnstartedServer(router)
+	srv.Config.WriteTimeout = timeout
+	srv.Start()
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL + "/wallets/alice/statement")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var got struct {
+		Operations     []json.RawMessage `json:"operations"`
+		ClosingBalance float64           `json:"closing_balance"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("statement is cut after %v: %v", time.Since(start), err)
+	}
+	if len(got.Operations) != writer.lines || got.ClosingBalance != float64(writer.lines) {
+		t.Errorf("statement has %d operations and closing balance %v, want %d", len(got.Operations), got.ClosingBalance, writer.lines)
+	}
+	if elapsed := time.Since(start); elapsed < timeout {
+		t.Errorf("statement took %v, it must outlast the write timeout %v", elapsed, timeout)
+	}
+}
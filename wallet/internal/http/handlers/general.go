@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"wallet/internal/importer"
 	"wallet/internal/storage"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator"
 )
 
 type WalletCreator interface {
@@ -23,27 +27,20 @@ func CreateWalletHandler(creator WalletCreator) http.HandlerFunc {
 
 		//разбираем запрос
 		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			render.JSON(w, r, Error("Can't decode request"))
+			RespondError(w, r, errMalformedBody)
 			return
 		}
 
 		//валидируем запрос
-		if err := validator.New().Struct(req); err != nil {
-			validateErr := err.(validator.ValidationErrors)
-
-			render.JSON(w, r, ValidationError(validateErr))
-
+		if err := validate.Struct(req); err != nil {
+			RespondError(w, r, err)
 			return
 		}
 
 		//создаем кашелек
 		createdWallet, err := creator.CreateWallet(r.Context(), req.Name)
-		if errors.Is(err, storage.ErrWalletExists) {
-			render.JSON(w, r, Error("Can't create wallet. Wallet already exists"))
-			return
-		}
 		if err != nil {
-			render.JSON(w, r, Error("Can't create wallet: "+err.Error()))
+			RespondError(w, r, err)
 			return
 		}
 
@@ -64,17 +61,13 @@ func GetWalletHandler(recipient WalletRecipient) http.HandlerFunc {
 
 		walletID := chi.URLParam(r, "id")
 		if walletID == "" {
-			render.JSON(w, r, Error("Invalid request"))
+			RespondError(w, r, invalidRequest("Invalid request"))
 			return
 		}
 
 		wallet, err := recipient.GetWallet(r.Context(), walletID)
-		if errors.Is(err, storage.ErrWalletNotExist) {
-			render.JSON(w, r, Error("Wallet not exists"))
-			return
-		}
 		if err != nil {
-			render.JSON(w, r, Error(err.Error()))
+			RespondError(w, r, err)
 			return
 		}
 
@@ -82,20 +75,30 @@ func GetWalletHandler(recipient WalletRecipient) http.HandlerFunc {
 	}
 }
 
-type WalletsRecipient interface {
-	GetWallets(ctx context.Context) ([]storage.Wallet, error)
+type WalletsLister interface {
+	ListWallets(ctx context.Context, query storage.WalletQuery) (*storage.WalletPage, error)
 }
 
-func GetWalletsHandler(recipient WalletsRecipient) http.HandlerFunc {
+// ListWalletsHandler returns a page of wallets. Query parameters:
+// filters - tag (repeatable), meta.<key>=<value>, status (active by default, inactive, all),
+// min_balance, max_balance, name_prefix;
+// paging - sort (name, balance, created_at), order (asc, desc), limit, cursor, total=true
+func ListWalletsHandler(lister WalletsLister) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		wallets, err := recipient.GetWallets(r.Context())
+		query, err := parseWalletQuery(r.URL.Query())
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		page, err := lister.ListWallets(r.Context(), query)
 		if err != nil {
-			render.JSON(w, r, Error("Can't get list of wallets"))
+			RespondError(w, r, err)
 			return
 		}
 
-		render.JSON(w, r, wallets)
+		render.JSON(w, r, page)
 	}
 }
 
@@ -108,25 +111,25 @@ func PutWalletsNameHandler(renamer WalletRenamer) http.HandlerFunc {
 
 		walletID := chi.URLParam(r, "id")
 		if walletID == "" {
-			render.JSON(w, r, Response{ID: walletID, Success: false, ErrCode: "Invalid request"})
+			RespondError(w, r, invalidRequest("Invalid request"))
 			return
 		}
 
 		var req Request
 
 		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			render.JSON(w, r, Response{ID: walletID, Success: false, ErrCode: err.Error()})
+			RespondError(w, r, errMalformedBody)
 			return
 		}
 
 		if req.Name == "" || len(req.Name) <= 1 {
-			render.JSON(w, r, Response{ID: walletID, Success: false, ErrCode: "The name length must be more than 1 character"})
+			RespondError(w, r, invalidField("name", "invalid", "The name length must be more than 1 character"))
 			return
 		}
 
 		_, err := renamer.UpdateName(r.Context(), walletID, req.Name)
 		if err != nil {
-			render.JSON(w, r, Response{ID: walletID, Success: false, ErrCode: err.Error()})
+			RespondError(w, r, err)
 			return
 		}
 
@@ -143,16 +146,215 @@ func RemoveWalletHandler(remover WalletRemover) http.HandlerFunc {
 
 		walletID := chi.URLParam(r, "id")
 		if walletID == "" {
-			render.JSON(w, r, Error("Invalid request"))
+			RespondError(w, r, invalidRequest("Invalid request"))
 			return
 		}
 
 		_, err := remover.DeactivateWallet(r.Context(), walletID)
 		if err != nil {
-			render.JSON(w, r, Response{ID: walletID, Success: false, ErrCode: err.Error()})
+			RespondError(w, r, err)
 			return
 		}
 
 		render.JSON(w, r, Response{ID: walletID, Success: true})
 	}
 }
+
+type WalletMetadataPatcher interface {
+	PatchMetadata(ctx context.Context, walletID string, patch map[string]any) (*storage.Wallet, error)
+}
+
+// PatchWalletMetadataHandler applies JSON merge patch to wallet metadata
+func PatchWalletMetadataHandler(patcher WalletMetadataPatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		walletID := chi.URLParam(r, "id")
+		if walletID == "" {
+			RespondError(w, r, invalidRequest("Invalid request"))
+			return
+		}
+
+		var patch map[string]any
+
+		if err := render.DecodeJSON(r.Body, &patch); err != nil {
+			RespondError(w, r, errMalformedBody)
+			return
+		}
+
+		wallet, err := patcher.PatchMetadata(r.Context(), walletID, patch)
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, wallet)
+	}
+}
+
+type WalletTagsPatcher interface {
+	PatchTags(ctx context.Context, walletID string, add, remove []string) (*storage.Wallet, error)
+}
+
+func PatchWalletTagsHandler(patcher WalletTagsPatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		walletID := chi.URLParam(r, "id")
+		if walletID == "" {
+			RespondError(w, r, invalidRequest("Invalid request"))
+			return
+		}
+
+		var req TagsRequest
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			RespondError(w, r, errMalformedBody)
+			return
+		}
+
+		wallet, err := patcher.PatchTags(r.Context(), walletID, req.Add, req.Remove)
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, wallet)
+	}
+}
+
+const metadataParamPrefix = "meta."
+
+func parseWalletFilter(query url.Values) (storage.WalletFilter, error) {
+	filter := storage.WalletFilter{
+		Tags:       query["tag"],
+		Status:     query.Get("status"),
+		NamePrefix: query.Get("name_prefix"),
+	}
+
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, metadataParamPrefix)
+		if !ok || key == "" || len(values) == 0 {
+			continue
+		}
+		if filter.Metadata == nil {
+			filter.Metadata = make(map[string]string)
+		}
+		filter.Metadata[key] = values[0]
+	}
+
+	var err error
+
+	if filter.MinBalance, err = parseFloatParam(query, "min_balance"); err != nil {
+		return filter, err
+	}
+	if filter.MaxBalance, err = parseFloatParam(query, "max_balance"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseWalletQuery(params url.Values) (storage.WalletQuery, error) {
+	filter, err := parseWalletFilter(params)
+	if err != nil {
+		return storage.WalletQuery{}, err
+	}
+
+	query := storage.WalletQuery{
+		WalletFilter: filter,
+		SortBy:       params.Get("sort"),
+		Cursor:       params.Get("cursor"),
+	}
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, invalidParameter("order", "Invalid order parameter")
+	}
+
+	if raw := params.Get("limit"); raw != "" {
+		if query.Limit, err = strconv.Atoi(raw); err != nil || query.Limit <= 0 {
+			return query, invalidParameter("limit", "Invalid limit parameter")
+		}
+	}
+
+	if raw := params.Get("total"); raw != "" {
+		if query.WithTotal, err = strconv.ParseBool(raw); err != nil {
+			return query, invalidParameter("total", "Invalid total parameter")
+		}
+	}
+
+	return query, nil
+}
+
+func parseFloatParam(query url.Values, name string) (*float64, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, invalidParameter(name, fmt.Sprintf("Invalid %s parameter", name))
+	}
+
+	return &value, nil
+}
+
+type WalletsImporter interface {
+	Import(ctx context.Context, format string, r io.Reader, dryRun bool) (*importer.Report, error)
+}
+
+// maxImportSize limits the size of imported file
+const maxImportSize = 64 << 20
+
+// ImportWalletsHandler creates wallets from CSV or JSON Lines request body. Query parameters:
+//
+//	format   csv or jsonl, taken from Content-Type when omitted
+//	dry_run  validate only, nothing is created
+//
+// The report lists created wallets or row errors, wallets are created only when all rows are valid.
+// When a batch fails to load, the report names it along with wallets created by the earlier batches.
+func ImportWalletsHandler(walletsImporter WalletsImporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		query := r.URL.Query()
+
+		format := query.Get("format")
+		if format == "" {
+			format = importFormat(r.Header.Get("Content-Type"))
+		}
+
+		var dryRun bool
+		if raw := query.Get("dry_run"); raw != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(raw); err != nil {
+				RespondError(w, r, invalidParameter("dry_run", "Invalid dry_run parameter"))
+				return
+			}
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxImportSize)
+
+		report, err := walletsImporter.Import(r.Context(), format, body, dryRun)
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, report)
+	}
+}
+
+func importFormat(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return importer.FormatCSV
+	case strings.HasPrefix(contentType, "application/jsonl"),
+		strings.HasPrefix(contentType, "application/x-ndjson"):
+		return importer.FormatJSONL
+	default:
+		return importer.FormatCSV
+	}
+}
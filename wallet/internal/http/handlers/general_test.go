@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+type stubRenamer struct {
+	renamed int
+}
+
+func (s *stubRenamer) UpdateName(ctx context.Context, walletID, name string) (int64, error) {
+	s.renamed++
+	return 1, nil
+}
+
+func TestPutWalletsNameMalformedBody(t *testing.T) {
+	renamer := &stubRenamer{}
+	router := chi.NewRouter()
+	router.Put("/wallets/{id}/name", PutWalletsNameHandler(renamer))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/wallets/alice/name", strings.NewReader(`{"name":`)))
+
+	var problem struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || problem.Code != "malformed_body" {
+		t.Errorf("response %d %s, want 400 malformed_body", rec.Code, problem.Code)
+	}
+	if renamer.renamed != 0 {
+		t.Errorf("wallet is renamed by malformed request")
+	}
+}
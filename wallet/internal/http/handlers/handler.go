@@ -1,12 +1,5 @@
 package handlers
 
-import (
-	"fmt"
-	"strings"
-
-	"github.com/go-playground/validator"
-)
-
 type Response struct {
 	ID      string  `json:"id,omitempty"`
 	Name    string  `json:"name,omitempty"`
@@ -14,7 +7,6 @@ type Response struct {
 	Status  string  `json:"status,omitempty"`
 	Amount  float64 `json:"amount,omitempty"`
 	Success bool    `json:"success,omitempty"`
-	ErrCode string  `json:"err_code,omitempty"`
 }
 
 type Request struct {
@@ -23,27 +15,7 @@ type Request struct {
 	TransferTo string  `json:"transfer_to,omitempty"`
 }
 
-func Error(msg string) Response {
-	return Response{
-		ErrCode: msg,
-	}
-}
-
-// Обработчик ошибок валидатора. Опционально, реализовано для повышения читаемости ошибок
-// TODO: Добавить больше кейсов
-func ValidationError(errs validator.ValidationErrors) Response {
-	var errMsgs []string
-
-	for _, err := range errs {
-		switch err.ActualTag() {
-		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
-		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
-		}
-	}
-
-	return Response{
-		ErrCode: strings.Join(errMsgs, ", "),
-	}
+type TagsRequest struct {
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
 }
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"wallet/internal/domain"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-playground/validator"
+)
+
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix makes problem type URI from error code
+const problemTypePrefix = "urn:wallet:problem:"
+
+// Problem is an error response body, RFC 7807.
+// Code is stable and identifies the problem, Detail is a human readable explanation of this occurrence.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes invalid field of request body or query parameter
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// requestError is an invalid request along with its invalid fields
+type requestError struct {
+	err    *domain.Error
+	fields []FieldError
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// invalidRequest is an error of request parsing
+func invalidRequest(msg string) error {
+	return domain.New(domain.ErrInvalidArgument, "invalid_request", msg)
+}
+
+var errMalformedBody = domain.New(domain.ErrInvalidArgument, "malformed_body", "Can't decode request")
+
+// invalidParameter is an error of query parameter parsing
+func invalidParameter(name, msg string) error {
+	return &requestError{
+		err:    domain.New(domain.ErrInvalidArgument, "invalid_parameter", msg),
+		fields: []FieldError{{Field: name, Code: "invalid", Message: msg}},
+	}
+}
+
+// invalidField is an error of request body field
+func invalidField(name, code, msg string) error {
+	return InvalidFields(msg, []FieldError{{Field: name, Code: code, Message: msg}})
+}
+
+// InvalidFields is an error of request validation, it is rendered with the list of invalid fields
+func InvalidFields(msg string, fields []FieldError) error {
+	return &requestError{
+		err:    domain.New(domain.ErrInvalidArgument, "validation_failed", msg),
+		fields: fields,
+	}
+}
+
+// validate checks request structures, field errors are named by json tags
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// Обработчик ошибок валидатора. Опционально, реализовано для повышения читаемости ошибок
+// TODO: Добавить больше кейсов
+func ValidationError(errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+
+	for _, err := range errs {
+		switch err.ActualTag() {
+		case "required":
+			fields = append(fields, FieldError{Field: err.Field(), Code: "required", Message: fmt.Sprintf("field %s is a required field", err.Field())})
+		default:
+			fields = append(fields, FieldError{Field: err.Field(), Code: "invalid", Message: fmt.Sprintf("field %s is not valid", err.Field())})
+		}
+	}
+
+	return fields
+}
+
+// RespondError renders error as problem with HTTP status of its domain kind.
+// Errors of unknown kind are logged and reported as internal, so their details never reach clients.
+func RespondError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := NewProblem(err)
+	if problem.Status == http.StatusInternalServerError {
+		slog.Error("Request failed",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	WriteProblem(w, r, problem)
+}
+
+// WriteProblem renders problem as application/problem+json, request ID and path are filled from request
+func WriteProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	problem.Instance = r.URL.Path
+	problem.RequestID = middleware.GetReqID(r.Context())
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// NewProblem describes error for clients
+func NewProblem(err error) Problem {
+	var validateErr validator.ValidationErrors
+	if errors.As(err, &validateErr) {
+		return Problem{
+			Type:   problemTypePrefix + "validation_failed",
+			Title:  domain.ErrInvalidArgument.Title(),
+			Status: http.StatusBadRequest,
+			Detail: "Request is not valid",
+			Code:   "validation_failed",
+			Errors: ValidationError(validateErr),
+		}
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return Problem{
+			Type:   problemTypePrefix + "request_too_large",
+			Title:  "Request too large",
+			Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("Request body is larger than %d bytes", maxBytesErr.Limit),
+			Code:   "request_too_large",
+		}
+	}
+
+	domainErr, ok := domain.As(err)
+	if !ok {
+		return internalProblem()
+	}
+
+	status := kindStatus(domainErr.Kind())
+	if status == http.StatusInternalServerError {
+		return internalProblem()
+	}
+
+	problem := Problem{
+		Type:   problemTypePrefix + domainErr.Code(),
+		Title:  domainErr.Title(),
+		Status: status,
+		Detail: domainErr.Error(),
+		Code:   domainErr.Code(),
+	}
+
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		problem.Errors = reqErr.fields
+	}
+
+	return problem
+}
+
+func kindStatus(kind *domain.Error) int {
+	switch kind {
+	case domain.ErrInvalidArgument:
+		return http.StatusBadRequest
+	case domain.ErrNotFound:
+		return http.StatusNotFound
+	case domain.ErrExists, domain.ErrConflict:
+		return http.StatusConflict
+	case domain.ErrInvalidAmount, domain.ErrInsufficientFunds, domain.ErrInactive:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func internalProblem() Problem {
+	return Problem{
+		Type:   problemTypePrefix + "internal_error",
+		Title:  "Internal error",
+		Status: http.StatusInternalServerError,
+		Code:   "internal_error",
+	}
+}
+
+// NotFoundHandler reports unknown routes as problem
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, Problem{
+		Type:   problemTypePrefix + "route_not_found",
+		Title:  domain.ErrNotFound.Title(),
+		Status: http.StatusNotFound,
+		Detail: fmt.Sprintf("No route for %s %s", r.Method, r.URL.Path),
+		Code:   "route_not_found",
+	})
+}
+
+// MethodNotAllowedHandler reports unsupported methods of known routes as problem
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, Problem{
+		Type:   problemTypePrefix + "method_not_allowed",
+		Title:  "Method not allowed",
+		Status: http.StatusMethodNotAllowed,
+		Detail: fmt.Sprintf("Method %s is not allowed for %s", r.Method, r.URL.Path),
+		Code:   "method_not_allowed",
+	})
+}
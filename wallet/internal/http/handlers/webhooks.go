@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+	"wallet/internal/storage"
+	"wallet/internal/webhook"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type WebhookRequest struct {
+	URL        string   `json:"url" validate:"required"`
+	EventTypes []string `json:"event_types,omitempty"`
+	WalletIDs  []string `json:"wallet_ids,omitempty"`
+	Secret     string   `json:"secret,omitempty"`
+}
+
+type WebhookPatchRequest struct {
+	URL        *string   `json:"url,omitempty"`
+	EventTypes *[]string `json:"event_types,omitempty"`
+	WalletIDs  *[]string `json:"wallet_ids,omitempty"`
+	Secret     *string   `json:"secret,omitempty"`
+	Enabled    *bool     `json:"enabled,omitempty"`
+}
+
+// WebhookResponse is a webhook, its secret is shown only once on creation
+type WebhookResponse struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	WalletIDs  []string  `json:"wallet_ids"`
+	Secret     string    `json:"secret,omitempty"`
+	Status     string    `json:"status"`
+	Failures   int       `json:"failures"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func newWebhookResponse(webhook *storage.Webhook, withSecret bool) WebhookResponse {
+	resp := WebhookResponse{
+		ID:         webhook.ID,
+		URL:        webhook.URL,
+		EventTypes: webhook.EventTypes,
+		WalletIDs:  webhook.WalletIDs,
+		Status:     webhook.Status,
+		Failures:   webhook.Failures,
+		CreatedAt:  webhook.CreatedAt,
+		UpdatedAt:  webhook.UpdatedAt,
+	}
+	if withSecret {
+		resp.Secret = webhook.Secret
+	}
+	return resp
+}
+
+type WebhookCreator interface {
+	Create(ctx context.Context, sub webhook.Subscription) (*storage.Webhook, error)
+}
+
+func CreateWebhookHandler(creator WebhookCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var req WebhookRequest
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			RespondError(w, r, errMalformedBody)
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		created, err := creator.Create(r.Context(), webhook.Subscription{
+			URL:        req.URL,
+			EventTypes: req.EventTypes,
+			WalletIDs:  req.WalletIDs,
+			Secret:     req.Secret,
+		})
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, newWebhookResponse(created, true))
+	}
+}
+
+type WebhooksLister interface {
+	List(ctx context.Context) ([]storage.Webhook, error)
+}
+
+func ListWebhooksHandler(lister WebhooksLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		webhooks, err := lister.List(r.Context())
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		resp := make([]WebhookResponse, 0, len(webhooks))
+		for i := range webhooks {
+			resp = append(resp, newWebhookResponse(&webhooks[i], false))
+		}
+
+		render.JSON(w, r, map[string]any{"webhooks": resp})
+	}
+}
+
+type WebhookRecipient interface {
+	Get(ctx context.Context, webhookID string) (*storage.Webhook, error)
+}
+
+func GetWebhookHandler(recipient WebhookRecipient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		found, err := recipient.Get(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, newWebhookResponse(found, false))
+	}
+}
+
+type WebhookUpdater interface {
+	Update(ctx context.Context, webhookID string, patch webhook.Patch) (*storage.Webhook, error)
+}
+
+func PatchWebhookHandler(updater WebhookUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var req WebhookPatchRequest
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			RespondError(w, r, errMalformedBody)
+			return
+		}
+
+		updated, err := updater.Update(r.Context(), chi.URLParam(r, "id"), webhook.Patch{
+			URL:        req.URL,
+			EventTypes: req.EventTypes,
+			WalletIDs:  req.WalletIDs,
+			Secret:     req.Secret,
+			Enabled:    req.Enabled,
+		})
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, newWebhookResponse(updated, false))
+	}
+}
+
+type WebhookRemover interface {
+	Delete(ctx context.Context, webhookID string) error
+}
+
+func DeleteWebhookHandler(remover WebhookRemover) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		webhookID := chi.URLParam(r, "id")
+
+		if err := remover.Delete(r.Context(), webhookID); err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, Response{ID: webhookID, Success: true})
+	}
+}
+
+type DeliveriesLister interface {
+	Deliveries(ctx context.Context, webhookID string, limit int) ([]storage.Delivery, error)
+}
+
+// WebhookDeliveriesHandler returns the delivery log of webhook, newest first
+func WebhookDeliveriesHandler(lister DeliveriesLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		limit := 0
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			var err error
+			if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+				RespondError(w, r, invalidParameter("limit", "Invalid limit parameter"))
+				return
+			}
+		}
+
+		deliveries, err := lister.Deliveries(r.Context(), chi.URLParam(r, "id"), limit)
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, map[string]any{"deliveries": deliveries})
+	}
+}
+
+type DeliveryReplayer interface {
+	Replay(ctx context.Context, webhookID, deliveryID string) (*storage.Delivery, error)
+}
+
+// ReplayDeliveryHandler queues the event of delivery to be sent once again
+func ReplayDeliveryHandler(replayer DeliveryReplayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		delivery, err := replayer.Replay(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "delivery_id"))
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, delivery)
+	}
+}
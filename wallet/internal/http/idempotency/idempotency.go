@@ -0,0 +1,238 @@
+package idempotency
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"wallet/internal/domain"
+	"wallet/internal/http/handlers"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// Header carries client generated key of a mutating request
+const Header = "Idempotency-Key"
+
+// ReplayedHeader marks responses replayed from the store
+const ReplayedHeader = "Idempotent-Replayed"
+
+const maxKeyLength = 255
+
+var (
+	ErrKeyInUse  = domain.New(domain.ErrConflict, "idempotency_key_in_use", "request with this idempotency key is in progress, retry later")
+	ErrKeyReused = domain.New(domain.ErrInvalidArgument, "idempotency_key_reused", "idempotency key was used for another request")
+	ErrKeyLength = domain.Errorf(domain.ErrInvalidArgument, "invalid_idempotency_key", "idempotency key must be 1 to %d characters", maxKeyLength)
+)
+
+// Store keeps responses of mutating requests sent with Idempotency-Key, so retries are not applied again.
+// Responses are kept in memory of the instance for ttl, at most maxEntries of them: the oldest ones are dropped
+// when it is full. A retry is applied again when it reaches another instance or its response is dropped.
+type Store struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	order   *list.List //IDs of entries, oldest first, so they expire in order
+}
+
+type entry struct {
+	elem        *list.Element
+	fingerprint [sha256.Size]byte
+	done        bool
+	expires     time.Time
+
+	status int
+	header http.Header
+	body   []byte
+}
+
+// New returns store which keeps responses for ttl, maxEntries <= 0 means no limit
+func New(ttl time.Duration, maxEntries int) *Store {
+	return &Store{ttl: ttl, maxEntries: maxEntries, entries: make(map[string]*entry), order: list.New()}
+}
+
+// Middleware replays stored response when request is repeated with the same key, method, path and body.
+// Responses of aborted or failed requests (409, 429 and 5xx) are not stored, their retries are executed again.
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" || !mutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			handlers.RespondError(w, r, ErrKeyLength)
+			return
+		}
+
+		id := r.Method + " " + r.URL.Path + " " + key
+		now := time.Now()
+
+		s.mu.Lock()
+		s.sweep(now)
+		stored, ok := s.entries[id]
+		if ok && stored.done && now.After(stored.expires) {
+			ok = false
+		}
+		if !ok {
+			stored = s.add(id, now)
+		}
+		s.mu.Unlock()
+
+		if ok {
+			s.replay(w, r, stored)
+			return
+		}
+
+		//body is hashed while handler reads it
+		hash := sha256.New()
+		r.Body = readCloser{Reader: io.TeeReader(r.Body, hash), Closer: r.Body}
+
+		var body bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&body)
+
+		defer func() {
+			//panics are responded by Recoverer afterwards, nothing to store
+			if p := recover(); p != nil {
+				s.forget(id)
+				panic(p)
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if !storable(status) {
+				s.forget(id)
+				return
+			}
+
+			//body may be read partially by handler, the rest is hashed by the tee before the store is locked
+			io.Copy(io.Discard, r.Body)
+			var fingerprint [sha256.Size]byte
+			hash.Sum(fingerprint[:0])
+			header := w.Header().Clone()
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			stored.fingerprint = fingerprint
+			stored.status = status
+			stored.header = header
+			stored.body = body.Bytes()
+			stored.done = true
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+func (s *Store) replay(w http.ResponseWriter, r *http.Request, stored *entry) {
+	var fingerprint [sha256.Size]byte
+	hash := sha256.New()
+	io.Copy(hash, r.Body)
+	hash.Sum(fingerprint[:0])
+
+	s.mu.Lock()
+	done := stored.done
+	s.mu.Unlock()
+
+	if !done {
+		handlers.RespondError(w, r, ErrKeyInUse)
+		return
+	}
+	if fingerprint != stored.fingerprint {
+		handlers.RespondError(w, r, ErrKeyReused)
+		return
+	}
+
+	for name, values := range stored.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(stored.status)
+	w.Write(stored.body)
+}
+
+// add stores entry of request in progress, the oldest completed entry is dropped when the store is full.
+// It must be called with s.mu held.
+func (s *Store) add(id string, now time.Time) *entry {
+	s.remove(id)
+
+	if s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+		//entries in progress are kept, their requests would be executed twice otherwise
+		for elem := s.order.Front(); elem != nil; elem = elem.Next() {
+			if oldest := elem.Value.(string); s.entries[oldest].done {
+				s.remove(oldest)
+				break
+			}
+		}
+	}
+
+	stored := &entry{expires: now.Add(s.ttl)}
+	stored.elem = s.order.PushBack(id)
+	s.entries[id] = stored
+
+	return stored
+}
+
+// remove must be called with s.mu held
+func (s *Store) remove(id string) {
+	if stored, ok := s.entries[id]; ok {
+		s.order.Remove(stored.elem)
+		delete(s.entries, id)
+	}
+}
+
+// forget removes the entry of request which response is not stored
+func (s *Store) forget(id string) {
+	s.mu.Lock()
+	s.remove(id)
+	s.mu.Unlock()
+}
+
+// sweep removes expired entries, it must be called with s.mu held
+func (s *Store) sweep(now time.Time) {
+	for elem := s.order.Front(); elem != nil; {
+		id := elem.Value.(string)
+		stored := s.entries[id]
+		if now.Before(stored.expires) {
+			return
+		}
+
+		elem = elem.Next()
+		if stored.done {
+			s.remove(id)
+		}
+	}
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// storable reports whether request with response status was applied or rejected for good.
+// Server errors are not, a retry may succeed after the server or its storage has recovered.
+func storable(status int) bool {
+	switch {
+	case status == http.StatusConflict, status == http.StatusTooManyRequests:
+		return false
+	default:
+		return status < http.StatusInternalServerError
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,113 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// counter responds with status and counts applied requests
+type counter struct {
+	status  int
+	applied int
+}
+
+func (c *counter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.applied++
+	w.WriteHeader(c.status)
+	w.Write([]byte("applied"))
+}
+
+func send(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/wallets", strings.NewReader(body))
+	req.Header.Set(Header, key)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReplay(t *testing.T) {
+	c := &counter{status: http.StatusCreated}
+	h := New(time.Hour, 0).Middleware(c)
+
+	first := send(h, "key-1", `{"name":"alice"}`)
+	retry := send(h, "key-1", `{"name":"alice"}`)
+
+	if c.applied != 1 {
+		t.Errorf("applied %d times, want once", c.applied)
+	}
+	if retry.Code != first.Code || retry.Body.String() != "applied" || retry.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("retry responded %d %q, replayed %q", retry.Code, retry.Body.String(), retry.Header().Get(ReplayedHeader))
+	}
+
+	if reused := send(h, "key-1", `{"name":"bob"}`); reused.Code != http.StatusBadRequest {
+		t.Errorf("key reused for another body responded %d, want 400", reused.Code)
+	}
+}
+
+func TestStorable(t *testing.T) {
+	tests := []struct {
+		status  int
+		applied int //after the request and its retry
+	}{
+		{http.StatusOK, 1},
+		{http.StatusUnprocessableEntity, 1},
+		{http.StatusConflict, 2},
+		{http.StatusTooManyRequests, 2},
+		{http.StatusInternalServerError, 2},
+		{http.StatusServiceUnavailable, 2},
+	}
+
+	for _, tt := range tests {
+		c := &counter{status: tt.status}
+		h := New(time.Hour, 0).Middleware(c)
+
+		send(h, "key", "{}")
+		send(h, "key", "{}")
+
+		if c.applied != tt.applied {
+			t.Errorf("status %d: applied %d times, want %d", tt.status, c.applied, tt.applied)
+		}
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	c := &counter{status: http.StatusOK}
+	s := New(time.Hour, 2)
+	h := s.Middleware(c)
+
+	for _, key := range []string{"key-1", "key-2", "key-3"} {
+		send(h, key, "{}")
+	}
+	if len(s.entries) != 2 || s.order.Len() != 2 {
+		t.Fatalf("store keeps %d entries, want 2", len(s.entries))
+	}
+
+	//the oldest response is dropped, its retry is applied again
+	send(h, "key-3", "{}")
+	send(h, "key-1", "{}")
+	if c.applied != 4 {
+		t.Errorf("applied %d times, want 4", c.applied)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	c := &counter{status: http.StatusOK}
+	s := New(time.Hour, 0)
+	h := s.Middleware(c)
+
+	send(h, "key-1", "{}")
+	send(h, "key-2", "{}")
+
+	s.mu.Lock()
+	s.sweep(time.Now().Add(2 * time.Hour))
+	left := len(s.entries)
+	s.mu.Unlock()
+
+	if left != 0 || s.order.Len() != 0 {
+		t.Errorf("%d entries left after ttl", left)
+	}
+}
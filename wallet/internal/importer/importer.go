@@ -0,0 +1,427 @@
+package importer
+
+import (
+	"context"
+	"events"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+	"wallet/internal/domain"
+	"wallet/internal/kafka"
+	"wallet/internal/notify"
+	"wallet/internal/service"
+	"wallet/internal/storage"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+var ErrUnknownFormat = domain.New(domain.ErrInvalidArgument, "unknown_format", "unknown import format")
+
+// Transaction is a historical deposit or withdrawal of imported wallet
+type Transaction struct {
+	Type   string    `json:"type"`
+	Amount float64   `json:"amount"`
+	At     time.Time `json:"at"`
+}
+
+// Record is an imported wallet
+type Record struct {
+	Line           int            `json:"-"`
+	Name           string         `json:"name"`
+	OpeningBalance float64        `json:"opening_balance"`
+	OpenedAt       time.Time      `json:"opened_at"`
+	Tags           []string       `json:"tags"`
+	Metadata       map[string]any `json:"metadata"`
+	Transactions   []Transaction  `json:"transactions"`
+}
+
+type RowError struct {
+	Line  int    `json:"line"`
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error"`
+}
+
+type ImportedWallet struct {
+	Line    int     `json:"line"`
+	ID      string  `json:"id,omitempty"`
+	Name    string  `json:"name"`
+	Balance float64 `json:"balance"`
+
+	events []events.Event //created event followed by events of opening balance and transactions
+}
+
+// FailedBatch is a batch of lines which was not loaded, import stops on it
+type FailedBatch struct {
+	FirstLine int    `json:"first_line"`
+	LastLine  int    `json:"last_line"`
+	Error     string `json:"error"`
+}
+
+// Report describes import result. Nothing is loaded when Errors is not empty.
+// When Failed is set, wallets of the earlier batches stay created and are listed in Wallets,
+// the failed and later lines are not loaded, so they can be imported again without the listed lines.
+type Report struct {
+	DryRun       bool             `json:"dry_run"`
+	Total        int              `json:"total"`
+	Created      int              `json:"created"`
+	EventsFailed int              `json:"events_failed,omitempty"`
+	Wallets      []ImportedWallet `json:"wallets,omitempty"`
+	Errors       []RowError       `json:"errors,omitempty"`
+	Failed       *FailedBatch     `json:"failed,omitempty"`
+}
+
+type EventSender interface {
+	SendEvent(event events.Event) error
+}
+
+// Importer loads wallets with opening balances and history from files of the legacy system
+type Importer struct {
+	storage   storage.Storage
+	events    EventSender
+	notifier  service.Notifier
+	batchSize int
+}
+
+// New returns importer, notifier may be nil
+func New(storage storage.Storage, events EventSender, notifier service.Notifier, batchSize int) *Importer {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	return &Importer{
+		storage:   storage,
+		events:    events,
+		notifier:  notifier,
+		batchSize: batchSize,
+	}
+}
+
+// Import parses and validates all records first. Records are loaded only when every one of them is valid,
+// in transactions of batchSize wallets. Every created wallet sends Wallet_Created event, then Wallet_Deposited
+// for its opening balance and Wallet_Deposited or Wallet_Withdrawn for every historical transaction.
+// Subscribers of wallet changes are notified about created wallets with their resulting balances.
+func (i *Importer) Import(ctx context.Context, format string, r io.Reader, dryRun bool) (*Report, error) {
+	const fn = "importer.Import"
+
+	var (
+		records []Record
+		errs    []RowError
+	)
+
+	switch format {
+	case FormatCSV:
+		records, errs = ParseCSV(r)
+	case FormatJSONL:
+		records, errs = ParseJSONL(r)
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", fn, ErrUnknownFormat, format)
+	}
+
+	report := &Report{DryRun: dryRun, Total: len(records)}
+
+	now := time.Now().UTC()
+	for idx := range records {
+		errs = append(errs, validate(&records[idx], now)...)
+	}
+
+	errs = append(errs, checkDuplicates(records)...)
+
+	existing, err := i.checkExisting(ctx, records)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	errs = append(errs, existing...)
+
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(a, b int) bool { return errs[a].Line < errs[b].Line })
+		report.Errors = errs
+		return report, nil
+	}
+
+	if dryRun {
+		for _, record := range records {
+			report.Wallets = append(report.Wallets, ImportedWallet{
+				Line:    record.Line,
+				Name:    record.Name,
+				Balance: record.balance(),
+			})
+		}
+		return report, nil
+	}
+
+	for start := 0; start < len(records); start += i.batchSize {
+		end := min(start+i.batchSize, len(records))
+
+		created, err := i.load(ctx, records[start:end])
+		if err != nil {
+			report.Failed = &FailedBatch{FirstLine: records[start].Line, LastLine: records[end-1].Line, Error: "internal error"}
+			//domain errors such as a name taken since validation are safe to report
+			if domainErr, ok := domain.As(err); ok {
+				report.Failed.Error = domainErr.Error()
+			}
+			slog.Error("Can't load imported batch", slog.Int("first_line", report.Failed.FirstLine),
+				slog.Int("last_line", report.Failed.LastLine), slog.String("error", err.Error()))
+			return report, nil
+		}
+
+		report.Created += len(created)
+		report.Wallets = append(report.Wallets, created...)
+
+		for _, wallet := range created {
+			if i.notifier != nil {
+				i.notifier.Publish(notify.Change{
+					WalletID: wallet.ID,
+					Type:     notify.ChangeCreated,
+					Name:     wallet.Name,
+					Status:   storage.StatusActive,
+					Balance:  wallet.Balance,
+					At:       time.Now().UTC(),
+				})
+			}
+
+			for _, event := range wallet.events {
+				if err := i.events.SendEvent(event); err != nil {
+					report.EventsFailed++
+					slog.Error("Can't send event for imported wallet", slog.String("wallet_id", wallet.ID),
+						slog.String("type", event.Type), slog.String("error", err.Error()))
+				}
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// load creates wallets of one batch in a single transaction
+func (i *Importer) load(ctx context.Context, records []Record) ([]ImportedWallet, error) {
+	tx, err := i.storage.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created := make([]ImportedWallet, 0, len(records))
+
+	for _, record := range records {
+		wallet := &storage.Wallet{
+			Name:      record.Name,
+			Balance:   record.balance(),
+			Metadata:  record.Metadata,
+			Tags:      record.Tags,
+			CreatedAt: record.OpenedAt,
+		}
+
+		if err := tx.CreateWallet(ctx, wallet); err != nil {
+			return nil, fmt.Errorf("line %d: %w", record.Line, err)
+		}
+
+		imported := ImportedWallet{
+			Line:    record.Line,
+			ID:      wallet.ID,
+			Name:    wallet.Name,
+			Balance: wallet.Balance,
+		}
+
+		//events are numbered in the order of operations, they are sent after commit
+		addEvent := func(eventType string, payload any) error {
+			seq, err := tx.NextEventSeq(ctx, wallet.ID)
+			if err != nil {
+				return err
+			}
+
+			event := kafka.NewEvent(ctx, eventType, wallet.ID, payload)
+			event.Sequence = seq
+			imported.events = append(imported.events, event)
+			return nil
+		}
+
+		if err := addEvent(events.EventWalletCreated, events.WalletCreatedPayload{ID: wallet.ID, Name: wallet.Name}); err != nil {
+			return nil, fmt.Errorf("line %d: %w", record.Line, err)
+		}
+
+		operations := make([]storage.Operation, 0, len(record.Transactions)+1)
+		if record.OpeningBalance > 0 {
+			operations = append(operations, storage.Operation{Type: storage.OpOpeningBalance, Amount: record.OpeningBalance, CreatedAt: record.OpenedAt})
+		}
+		for _, transaction := range record.Transactions {
+			operations = append(operations, storage.Operation{Type: transaction.Type, Amount: transaction.Amount, CreatedAt: transaction.At})
+		}
+
+		for _, operation := range operations {
+			operation.WalletID = wallet.ID
+			if err := tx.AddOperation(ctx, &operation); err != nil {
+				return nil, fmt.Errorf("line %d: %w", record.Line, err)
+			}
+
+			var err error
+			if operation.Type == storage.OpWithdraw {
+				err = addEvent(events.EventWalletWithdrawn, events.WalletWithdrawnPayload{ID: wallet.ID, Name: wallet.Name, Amount: operation.Amount})
+			} else {
+				err = addEvent(events.EventWalletDeposited, events.WalletDepositedPayload{ID: wallet.ID, Name: wallet.Name, Amount: operation.Amount})
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", record.Line, err)
+			}
+		}
+
+		created = append(created, imported)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// balance is a wallet balance after all historical transactions
+func (r Record) balance() float64 {
+	balance := r.OpeningBalance
+	for _, transaction := range r.Transactions {
+		balance += storage.Operation{Type: transaction.Type, Amount: transaction.Amount}.Delta()
+	}
+
+	return balance
+}
+
+// validate checks record and normalizes its tags, opening time and transaction order
+func validate(record *Record, now time.Time) []RowError {
+	var errs []RowError
+
+	fail := func(format string, args ...any) {
+		errs = append(errs, RowError{Line: record.Line, Name: record.Name, Error: fmt.Sprintf(format, args...)})
+	}
+
+	if len(record.Name) <= 1 {
+		fail("The name length must be more than 1 character")
+	}
+
+	switch {
+	case math.IsNaN(record.OpeningBalance) || math.IsInf(record.OpeningBalance, 0):
+		fail("opening_balance must be a finite number")
+	case record.OpeningBalance < 0:
+		fail("opening_balance must not be negative")
+	}
+
+	if record.OpenedAt.IsZero() {
+		record.OpenedAt = now
+	}
+	record.OpenedAt = record.OpenedAt.UTC()
+	if record.OpenedAt.After(now) {
+		fail("opened_at must not be in the future")
+	}
+
+	tags, err := service.NormalizeTags(record.Tags)
+	if err != nil {
+		fail("%s", err)
+	}
+	record.Tags = tags
+
+	for idx, transaction := range record.Transactions {
+		switch transaction.Type {
+		case storage.OpDeposit, storage.OpWithdraw:
+		default:
+			fail("transaction %d: type must be %s or %s", idx+1, storage.OpDeposit, storage.OpWithdraw)
+		}
+		switch {
+		case math.IsNaN(transaction.Amount) || math.IsInf(transaction.Amount, 0):
+			fail("transaction %d: amount must be a finite number", idx+1)
+		case transaction.Amount <= 0:
+			fail("transaction %d: amount must be positive", idx+1)
+		}
+		if transaction.At.IsZero() {
+			fail("transaction %d: time is required", idx+1)
+			continue
+		}
+
+		record.Transactions[idx].At = transaction.At.UTC()
+		if transaction.At.Before(record.OpenedAt) {
+			fail("transaction %d: time is before opened_at", idx+1)
+		}
+		if transaction.At.After(now) {
+			fail("transaction %d: time must not be in the future", idx+1)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	sort.SliceStable(record.Transactions, func(a, b int) bool {
+		return record.Transactions[a].At.Before(record.Transactions[b].At)
+	})
+
+	balance := record.OpeningBalance
+	for _, transaction := range record.Transactions {
+		balance += storage.Operation{Type: transaction.Type, Amount: transaction.Amount}.Delta()
+		if balance < 0 {
+			fail("withdrawal of %.2f at %s exceeds balance", transaction.Amount, transaction.At.Format(time.RFC3339))
+			break
+		}
+	}
+
+	return errs
+}
+
+func checkDuplicates(records []Record) []RowError {
+	var errs []RowError
+
+	lines := make(map[string]int, len(records))
+	for _, record := range records {
+		if line, ok := lines[record.Name]; ok {
+			errs = append(errs, RowError{
+				Line:  record.Line,
+				Name:  record.Name,
+				Error: fmt.Sprintf("duplicate wallet name, first defined on line %d", line),
+			})
+			continue
+		}
+		lines[record.Name] = record.Line
+	}
+
+	return errs
+}
+
+const existingChunk = 500
+
+// checkExisting reports records whose wallet names are already taken
+func (i *Importer) checkExisting(ctx context.Context, records []Record) ([]RowError, error) {
+	var errs []RowError
+
+	for start := 0; start < len(records); start += existingChunk {
+		chunk := records[start:min(start+existingChunk, len(records))]
+
+		names := make([]string, len(chunk))
+		for idx, record := range chunk {
+			names[idx] = record.Name
+		}
+
+		page, err := i.storage.ListWallets(ctx, storage.WalletQuery{
+			WalletFilter: storage.WalletFilter{Names: names},
+			SortBy:       storage.SortByName,
+			Limit:        len(names),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		taken := make(map[string]bool, len(page.Wallets))
+		for _, wallet := range page.Wallets {
+			taken[wallet.Name] = true
+		}
+
+		for _, record := range chunk {
+			if taken[record.Name] {
+				errs = append(errs, RowError{Line: record.Line, Name: record.Name, Error: storage.ErrWalletExists.Error()})
+			}
+		}
+	}
+
+	return errs, nil
+}
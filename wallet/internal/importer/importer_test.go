@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"context"
+	"events"
+	"strings"
+	"testing"
+	"wallet/internal/notify"
+	"wallet/internal/publisher"
+	"wallet/internal/storage"
+	"wallet/internal/storage/memory"
+)
+
+// TestImportAmounts checks that amounts which are parsed by strconv but are not numbers are rejected
+func TestImportAmounts(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want string //error of line 2, empty when the file is valid
+	}{
+		{"valid", "name,opening_balance,opened_at,tx_type,tx_amount,tx_at\nalice,10,2024-01-01T00:00:00Z,deposit,5,2024-01-02T00:00:00Z\n", ""},
+		{"NaN amount", "name,opened_at,tx_type,tx_amount,tx_at\nalice,2024-01-01T00:00:00Z,deposit,NaN,2024-01-02T00:00:00Z\n", "transaction 1: amount must be a finite number"},
+		{"infinite amount", "name,opened_at,tx_type,tx_amount,tx_at\nalice,2024-01-01T00:00:00Z,withdraw,+Inf,2024-01-02T00:00:00Z\n", "transaction 1: amount must be a finite number"},
+		{"negative amount", "name,opened_at,tx_type,tx_amount,tx_at\nalice,2024-01-01T00:00:00Z,deposit,-1,2024-01-02T00:00:00Z\n", "transaction 1: amount must be positive"},
+		{"NaN opening balance", "name,opening_balance\nalice,nan\n", "opening_balance must be a finite number"},
+		{"infinite opening balance", "name,opening_balance\nalice,Inf\n", "opening_balance must be a finite number"},
+		{"negative infinite opening balance", "name,opening_balance\nalice,-Inf\n", "opening_balance must be a finite number"},
+		{"negative opening balance", "name,opening_balance\nalice,-1\n", "opening_balance must not be negative"},
+	}
+
+	for _, tt := range tests {
+		i := New(memory.New(), publisher.NewMemory(), nil, 0)
+
+		report, err := i.Import(context.Background(), FormatCSV, strings.NewReader(tt.csv), true)
+		if err != nil {
+			t.Fatalf("%s: Import: %v", tt.name, err)
+		}
+
+		if tt.want == "" {
+			if len(report.Errors) != 0 || len(report.Wallets) != 1 {
+				t.Errorf("%s: report = %+v, want one valid wallet", tt.name, report)
+			}
+			continue
+		}
+		if len(report.Errors) != 1 || report.Errors[0].Line != 2 || report.Errors[0].Error != tt.want {
+			t.Errorf("%s: errors = %+v, want %q on line 2", tt.name, report.Errors, tt.want)
+		}
+	}
+}
+
+type notifier struct {
+	changes []notify.Change
+}
+
+func (n *notifier) Publish(change notify.Change) {
+	n.changes = append(n.changes, change)
+}
+
+// TestImportEvents checks that imported balances reach consumers of events and wallet changes
+func TestImportEvents(t *testing.T) {
+	const csv = "name,opening_balance,opened_at,tx_type,tx_amount,tx_at\n" +
+		"alice,10,2024-01-01T00:00:00Z,withdraw,3,2024-01-03T00:00:00Z\n" +
+		"alice,,,deposit,5,2024-01-02T00:00:00Z\n" +
+		"bob,0,2024-01-01T00:00:00Z,,,\n"
+
+	pub := publisher.NewMemory()
+	changes := &notifier{}
+	i := New(memory.New(), pub, changes, 0)
+
+	report, err := i.Import(context.Background(), FormatCSV, strings.NewReader(csv), false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(report.Errors) != 0 || report.Created != 2 {
+		t.Fatalf("report = %+v, want two created wallets", report)
+	}
+	alice, bob := report.Wallets[0].ID, report.Wallets[1].ID
+
+	want := []struct {
+		eventType string
+		key       string
+		seq       int64
+		amount    float64
+	}{
+		{events.EventWalletCreated, alice, 1, 0},
+		{events.EventWalletDeposited, alice, 2, 10},
+		{events.EventWalletDeposited, alice, 3, 5},
+		{events.EventWalletWithdrawn, alice, 4, 3},
+		{events.EventWalletCreated, bob, 1, 0},
+	}
+
+	got := pub.Events()
+	if len(got) != len(want) {
+		t.Fatalf("published %d events, want %d", len(got), len(want))
+	}
+	for idx, event := range got {
+		var amount float64
+		switch payload := event.Payload.(type) {
+		case events.WalletDepositedPayload:
+			amount = payload.Amount
+		case events.WalletWithdrawnPayload:
+			amount = payload.Amount
+		}
+		if event.Type != want[idx].eventType || event.PartitionKey != want[idx].key || event.Sequence != want[idx].seq || amount != want[idx].amount {
+			t.Errorf("event %d = %s %s #%d %v, want %s %s #%d %v", idx, event.Type, event.PartitionKey, event.Sequence, amount,
+				want[idx].eventType, want[idx].key, want[idx].seq, want[idx].amount)
+		}
+	}
+
+	if len(changes.changes) != 2 || changes.changes[0].Type != notify.ChangeCreated || changes.changes[0].Balance != 12 {
+		t.Errorf("changes = %+v, want created alice with balance 12 and bob", changes.changes)
+	}
+}
+
+// takenStorage fails to create wallet name in transactions, as if it was taken after validation
+type takenStorage struct {
+	storage.Storage
+	name string
+}
+
+func (s takenStorage) BeginTx(ctx context.Context) (storage.Transaction, error) {
+	tx, err := s.Storage.BeginTx(ctx)
+	return takenTx{Transaction: tx, name: s.name}, err
+}
+
+type takenTx struct {
+	storage.Transaction
+	name string
+}
+
+func (tx takenTx) CreateWallet(ctx context.Context, wallet *storage.Wallet) error {
+	if wallet.Name == tx.name {
+		return storage.ErrWalletExists
+	}
+	return tx.Transaction.CreateWallet(ctx, wallet)
+}
+
+// TestImportFailedBatch checks that the report tells which lines are loaded when a batch fails
+func TestImportFailedBatch(t *testing.T) {
+	const csv = "name\nalice\nbob\ncarol\ndave\neve\n"
+
+	wallets := memory.New()
+	i := New(takenStorage{Storage: wallets, name: "dave"}, publisher.NewMemory(), nil, 2)
+
+	report, err := i.Import(context.Background(), FormatCSV, strings.NewReader(csv), false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	want := FailedBatch{FirstLine: 4, LastLine: 5, Error: storage.ErrWalletExists.Error()}
+	if report.Failed == nil || *report.Failed != want {
+		t.Fatalf("failed batch = %+v, want %+v", report.Failed, want)
+	}
+	if report.Created != 2 || len(report.Wallets) != 2 || report.Wallets[0].Name != "alice" || report.Wallets[1].Name != "bob" {
+		t.Errorf("report = %+v, want alice and bob created", report)
+	}
+
+	for _, name := range []string{"carol", "eve"} {
+		page, err := wallets.ListWallets(context.Background(), storage.WalletQuery{
+			WalletFilter: storage.WalletFilter{Names: []string{name}},
+			SortBy:       storage.SortByName,
+			Limit:        1,
+		})
+		if err != nil {
+			t.Fatalf("ListWallets: %v", err)
+		}
+		if len(page.Wallets) != 0 {
+			t.Errorf("%s is created after the failed batch", name)
+		}
+	}
+}
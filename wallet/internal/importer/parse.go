@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSV columns. Rows with the same name describe one wallet: wallet columns are taken from its first row,
+// every row with tx_type adds a historical transaction.
+const (
+	colName           = "name"
+	colOpeningBalance = "opening_balance"
+	colOpenedAt       = "opened_at"
+	colTags           = "tags"
+	colMetadata       = "metadata"
+	colTxType         = "tx_type"
+	colTxAmount       = "tx_amount"
+	colTxAt           = "tx_at"
+)
+
+// tagSeparator separates tags in a CSV cell
+const tagSeparator = "|"
+
+// ParseCSV reads records from CSV with a header row
+func ParseCSV(r io.Reader) ([]Record, []RowError) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, []RowError{{Line: 1, Error: fmt.Sprintf("can't read header: %s", err)}}
+	}
+
+	columns := make(map[string]int, len(header))
+	for idx, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = idx
+	}
+	if _, ok := columns[colName]; !ok {
+		return nil, []RowError{{Line: 1, Error: "header must contain name column"}}
+	}
+
+	var (
+		records []Record
+		errs    []RowError
+		byName  = make(map[string]int)
+	)
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.Line
+			}
+			errs = append(errs, RowError{Line: line, Error: err.Error()})
+			if parseErr == nil {
+				break
+			}
+			continue
+		}
+
+		cell := func(column string) string {
+			idx, ok := columns[column]
+			if !ok || idx >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[idx])
+		}
+
+		name := cell(colName)
+		fail := func(format string, args ...any) {
+			errs = append(errs, RowError{Line: line, Name: name, Error: fmt.Sprintf(format, args...)})
+		}
+
+		idx, seen := byName[name]
+		if !seen {
+			record := Record{Line: line, Name: name}
+
+			if value := cell(colOpeningBalance); value != "" {
+				if record.OpeningBalance, err = strconv.ParseFloat(value, 64); err != nil {
+					fail("invalid opening_balance %q", value)
+				}
+			}
+			if value := cell(colOpenedAt); value != "" {
+				if record.OpenedAt, err = time.Parse(time.RFC3339, value); err != nil {
+					fail("invalid opened_at %q, RFC 3339 expected", value)
+				}
+			}
+			if value := cell(colTags); value != "" {
+				record.Tags = strings.Split(value, tagSeparator)
+			}
+			if value := cell(colMetadata); value != "" {
+				if err := json.Unmarshal([]byte(value), &record.Metadata); err != nil {
+					fail("invalid metadata, JSON object expected")
+				}
+			}
+
+			idx = len(records)
+			byName[name] = idx
+			records = append(records, record)
+		}
+
+		txType := cell(colTxType)
+		if txType == "" {
+			if seen {
+				fail("wallet is already defined on line %d", records[idx].Line)
+			}
+			continue
+		}
+
+		transaction := Transaction{Type: txType}
+		if value := cell(colTxAmount); value != "" {
+			if transaction.Amount, err = strconv.ParseFloat(value, 64); err != nil {
+				fail("invalid tx_amount %q", value)
+			}
+		}
+		if value := cell(colTxAt); value != "" {
+			if transaction.At, err = time.Parse(time.RFC3339, value); err != nil {
+				fail("invalid tx_at %q, RFC 3339 expected", value)
+			}
+		}
+
+		records[idx].Transactions = append(records[idx].Transactions, transaction)
+	}
+
+	return records, errs
+}
+
+// maxJSONLine limits the size of one JSON Lines record
+const maxJSONLine = 4 << 20
+
+// ParseJSONL reads records from JSON Lines, one wallet per line
+func ParseJSONL(r io.Reader) ([]Record, []RowError) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLine)
+
+	var (
+		records []Record
+		errs    []RowError
+		line    int
+	)
+
+	for scanner.Scan() {
+		line++
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var record Record
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			errs = append(errs, RowError{Line: line, Error: fmt.Sprintf("invalid JSON: %s", err)})
+			continue
+		}
+
+		record.Line = line
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, RowError{Line: line + 1, Error: err.Error()})
+	}
+
+	return records, errs
+}
@@ -1,42 +1,24 @@
 package kafka
 
-const (
-	EventWalletCreated     = "Wallet_Created"
-	EventWalletDeleted     = "Wallet_Deleted"
-	EventWalletDeposited   = "Wallet_Deposited"
-	EventWalletWithdrawn   = "Wallet_Withdrawn"
-	EventWalletTransferred = "Wallet_Transfered"
+import (
+	"context"
+	"events"
 )
 
-type Event struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload"`
+// NewEvent returns event with new ID about subject, correlation ID is taken from ctx
+func NewEvent(ctx context.Context, eventType, subject string, payload any) events.Event {
+	return events.New(eventType, subject, CorrelationID(ctx), payload)
 }
 
-type WalletCreatedPayload struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
-
-type WalletDeletedPayload struct {
-	ID string `json:"id"`
-}
-
-type WalletDepositedPayload struct {
-	ID     string  `json:"id"`
-	Name   string  `json:"name"`
-	Amount float64 `json:"amount"`
-}
+type correlationKey struct{}
 
-type WalletWithdrawnPayload struct {
-	ID     string  `json:"id"`
-	Name   string  `json:"name"`
-	Amount float64 `json:"amount"`
+// WithCorrelationID returns ctx whose events are correlated by id, usually the request ID
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
 }
 
-type WalletTransferredPayload struct {
-	ID         string  `json:"id"`
-	Name       string  `json:"name"`
-	TransferTo string  `json:"transfer_to"`
-	Amount     float64 `json:"amount"`
+// CorrelationID returns ID set by WithCorrelationID or empty string
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
 }
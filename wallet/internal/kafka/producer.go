@@ -1,45 +1,235 @@
 package kafka
 
 import (
-	"encoding/json"
+	"errors"
+	"events"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/IBM/sarama"
 )
 
+// ErrProducerClosed is returned by SendEvent after Close
+var ErrProducerClosed = errors.New("kafka producer is closed")
+
+// ProducerConfig selects producer mode. Sync producer waits for brokers in SendEvent,
+// async one returns at once and reports results to OnDelivery.
+// Batching options apply to both modes, sync producer batches concurrent sends.
+type ProducerConfig struct {
+	Async         bool
+	Compression   string        //none, gzip, snappy, lz4 or zstd
+	BatchMessages int           //messages which trigger a batch, 0 - no limit
+	BatchBytes    int           //bytes which trigger a batch, 0 - no limit
+	Linger        time.Duration //how long a batch is collected, 0 - sent at once
+	FlushTimeout  time.Duration //how long Close waits for pending async messages
+	Codec         events.Codec  //encoding of events told by content-type header, JSON by default
+	OnDelivery    func(d Delivery)
+}
+
+// Delivery is a result of sending event. It is reported in both modes.
+type Delivery struct {
+	Event     events.Event
+	Partition int32
+	Offset    int64
+	Err       error
+}
+
+// ProducerStats are counters of sent events
+type ProducerStats struct {
+	Sent    int64 `json:"sent"`
+	Failed  int64 `json:"failed"`
+	Pending int64 `json:"pending"` //accepted by async producer, not acknowledged yet
+}
+
 type Producer struct {
 	producer sarama.SyncProducer
+	async    sarama.AsyncProducer
 	Topic    string
+
+	config ProducerConfig
+	sent   atomic.Int64
+	failed atomic.Int64
+	queued atomic.Int64
+
+	mu      sync.RWMutex //guards closed against sends to closed input
+	closed  bool
+	drained chan struct{} //closed when async results are read
 }
 
-func NewProducer(brokers []string, topic string) (*Producer, error) {
+func NewProducer(brokers []string, topic string, cfg ProducerConfig) (*Producer, error) {
+	config, err := newConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.Async {
+		producer, err := sarama.NewSyncProducer(brokers, config)
+		if err != nil {
+			return nil, err
+		}
+
+		return newSyncProducer(producer, topic, cfg), nil
+	}
+
+	producer, err := sarama.NewAsyncProducer(brokers, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAsyncProducer(producer, topic, cfg), nil
+}
+
+// newConfig returns sarama config of producer
+func newConfig(cfg ProducerConfig) (*sarama.Config, error) {
 	config := sarama.NewConfig()
 	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
 	config.Producer.RequiredAcks = sarama.WaitForAll // Гарантия доставки
 	config.Producer.Retry.Max = 3
+	//retried batch must not overtake a later one, otherwise events of a wallet are reordered
+	config.Producer.Idempotent = true
+	config.Net.MaxOpenRequests = 1
+	config.Producer.Partitioner = sarama.NewHashPartitioner //events of a wallet go to one partition by key
+	config.Producer.Flush.Messages = cfg.BatchMessages
+	config.Producer.Flush.Bytes = cfg.BatchBytes
+	config.Producer.Flush.Frequency = cfg.Linger
 
-	producer, err := sarama.NewSyncProducer(brokers, config)
-	if err != nil {
-		return nil, err
+	if cfg.Compression != "" {
+		if err := config.Producer.Compression.UnmarshalText([]byte(cfg.Compression)); err != nil {
+			return nil, fmt.Errorf("invalid compression %q: %w", cfg.Compression, err)
+		}
 	}
 
-	return &Producer{producer: producer, Topic: topic}, nil
+	return config, nil
+}
+
+func newSyncProducer(producer sarama.SyncProducer, topic string, cfg ProducerConfig) *Producer {
+	return &Producer{producer: producer, Topic: topic, config: cfg}
+}
+
+func newAsyncProducer(producer sarama.AsyncProducer, topic string, cfg ProducerConfig) *Producer {
+	p := &Producer{async: producer, Topic: topic, config: cfg, drained: make(chan struct{})}
+	go p.readResults()
+
+	return p
 }
 
-func (p *Producer) SendEvent(event Event) error {
-	eventJSON, err := json.Marshal(event)
+// SendEvent sends event to the topic. Async producer only queues it, failures are reported to OnDelivery.
+func (p *Producer) SendEvent(event events.Event) error {
+	codec := p.config.Codec
+	if codec == nil {
+		codec = events.JSON
+	}
+
+	value, err := codec.Encode(event)
 	if err != nil {
 		return err
 	}
 
 	msg := &sarama.ProducerMessage{
 		Topic: p.Topic,
-		Value: sarama.ByteEncoder(eventJSON),
+		Value: sarama.ByteEncoder(value),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte(events.ContentTypeHeader), Value: []byte(codec.ContentType())},
+		},
+		Metadata: event,
+	}
+	if event.PartitionKey != "" {
+		msg.Key = sarama.StringEncoder(event.PartitionKey)
+	}
+
+	if p.async == nil {
+		partition, offset, err := p.producer.SendMessage(msg)
+		p.report(Delivery{Event: event, Partition: partition, Offset: offset, Err: err})
+		return err
 	}
 
-	_, _, err = p.producer.SendMessage(msg)
-	return err
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	p.queued.Add(1)
+	p.async.Input() <- msg
+
+	return nil
 }
 
+// readResults reports async deliveries until the producer is closed and flushed
+func (p *Producer) readResults() {
+	defer close(p.drained)
+
+	successes, errs := p.async.Successes(), p.async.Errors()
+	for successes != nil || errs != nil {
+		select {
+		case msg, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			p.queued.Add(-1)
+			p.report(Delivery{Event: msg.Metadata.(events.Event), Partition: msg.Partition, Offset: msg.Offset})
+		case perr, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			p.queued.Add(-1)
+			p.report(Delivery{Event: perr.Msg.Metadata.(events.Event), Err: perr.Err})
+		}
+	}
+}
+
+func (p *Producer) report(d Delivery) {
+	if d.Err != nil {
+		p.failed.Add(1)
+	} else {
+		p.sent.Add(1)
+	}
+
+	if p.config.OnDelivery != nil {
+		p.config.OnDelivery(d)
+	}
+}
+
+// Stats returns counters of events sent since the producer was created
+func (p *Producer) Stats() ProducerStats {
+	return ProducerStats{Sent: p.sent.Load(), Failed: p.failed.Load(), Pending: p.queued.Load()}
+}
+
+// Close stops the producer. Async producer first flushes queued events,
+// waiting at most FlushTimeout, then the number of events which are still pending is returned as error.
 func (p *Producer) Close() error {
-	return p.producer.Close()
+	if p.async == nil {
+		return p.producer.Close()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	//sarama flushes buffered messages before closing result channels
+	p.async.AsyncClose()
+
+	var timeout <-chan time.Time
+	if p.config.FlushTimeout > 0 {
+		timer := time.NewTimer(p.config.FlushTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-p.drained:
+		return nil
+	case <-timeout:
+		return fmt.Errorf("kafka producer is not flushed in %s, %d events pending", p.config.FlushTimeout, p.queued.Load())
+	}
 }
@@ -0,0 +1,151 @@
+package kafka
+
+import (
+	"errors"
+	"events"
+	"events/eventstest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+// TestProducerContract checks that events of every type sent by the producer are decoded by consumers
+func TestProducerContract(t *testing.T) {
+	for _, codec := range []events.Codec{events.JSON, events.Protobuf} {
+		for _, event := range eventstest.Samples() {
+			t.Run(codec.ContentType()+"/"+event.Type, func(t *testing.T) {
+				var sent *sarama.ProducerMessage
+				capture := func(msg *sarama.ProducerMessage) error {
+					sent = msg
+					return nil
+				}
+
+				config := mocks.NewTestConfig()
+				config.Producer.Return.Successes = true
+
+				sync := mocks.NewSyncProducer(t, config)
+				sync.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(capture)
+				producer := newSyncProducer(sync, "wallet-events", ProducerConfig{Codec: codec})
+				if err := producer.SendEvent(event); err != nil {
+					t.Fatalf("SendEvent: %v", err)
+				}
+				if err := producer.Close(); err != nil {
+					t.Fatalf("Close: %v", err)
+				}
+				assertDecoded(t, sent, event)
+
+				async := mocks.NewAsyncProducer(t, config)
+				async.ExpectInputWithMessageCheckerFunctionAndSucceed(capture)
+				producer = newAsyncProducer(async, "wallet-events", ProducerConfig{Codec: codec})
+				if err := producer.SendEvent(event); err != nil {
+					t.Fatalf("SendEvent: %v", err)
+				}
+				if err := producer.Close(); err != nil {
+					t.Fatalf("Close: %v", err)
+				}
+				assertDecoded(t, sent, event)
+
+				if stats := producer.Stats(); stats.Sent != 1 || stats.Pending != 0 {
+					t.Errorf("stats = %+v, want 1 sent", stats)
+				}
+			})
+		}
+	}
+}
+
+func assertDecoded(t *testing.T, sent *sarama.ProducerMessage, event events.Event) {
+	t.Helper()
+
+	var contentType string
+	for _, header := range sent.Headers {
+		if string(header.Key) == events.ContentTypeHeader {
+			contentType = string(header.Value)
+		}
+	}
+
+	var key string
+	if sent.Key != nil {
+		raw, err := sent.Key.Encode()
+		if err != nil {
+			t.Fatalf("Key.Encode: %v", err)
+		}
+		key = string(raw)
+	}
+	if key != event.PartitionKey {
+		t.Errorf("key = %q, want %q", key, event.PartitionKey)
+	}
+
+	value, err := sent.Value.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	msg, err := events.DecodeMessage(contentType, value)
+	if err != nil {
+		t.Fatalf("DecodeMessage(%s): %v", contentType, err)
+	}
+	if msg.ID != event.ID || msg.Type != event.Type || msg.CorrelationID != event.CorrelationID ||
+		msg.PartitionKey != event.PartitionKey || msg.Sequence != event.Sequence {
+		t.Errorf("envelope = %+v, want %+v", msg, event)
+	}
+
+	payload, err := msg.DecodePayload()
+	if err != nil {
+		t.Fatalf("DecodePayload: %v", err)
+	}
+	if got := reflect.ValueOf(payload).Elem().Interface(); !reflect.DeepEqual(got, event.Payload) {
+		t.Errorf("payload = %+v, want %+v", got, event.Payload)
+	}
+}
+
+// TestConfigOrdering checks that sarama accepts producer config which keeps order of retried messages
+func TestConfigOrdering(t *testing.T) {
+	for _, cfg := range []ProducerConfig{{}, {Async: true, Compression: "snappy", BatchMessages: 100, Linger: time.Millisecond}} {
+		config, err := newConfig(cfg)
+		if err != nil {
+			t.Fatalf("newConfig(%+v): %v", cfg, err)
+		}
+		if err := config.Validate(); err != nil {
+			t.Errorf("config of %+v is invalid: %v", cfg, err)
+		}
+		if !config.Producer.Idempotent || config.Net.MaxOpenRequests != 1 {
+			t.Errorf("config of %+v allows reordering on retries", cfg)
+		}
+	}
+}
+
+// TestAsyncFailure checks that failures of async producer are counted and reported to OnDelivery
+func TestAsyncFailure(t *testing.T) {
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+
+	errBroker := errors.New("broker is down")
+	var reported []Delivery
+
+	async := mocks.NewAsyncProducer(t, config)
+	async.ExpectInputAndFail(errBroker)
+	async.ExpectInputAndSucceed()
+	producer := newAsyncProducer(async, "wallet-events", ProducerConfig{
+		OnDelivery: func(d Delivery) { reported = append(reported, d) },
+	})
+
+	samples := eventstest.Samples()
+	for _, event := range samples[:2] {
+		if err := producer.SendEvent(event); err != nil {
+			t.Fatalf("SendEvent: %v", err)
+		}
+	}
+	if err := producer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if stats := producer.Stats(); stats.Sent != 1 || stats.Failed != 1 || stats.Pending != 0 {
+		t.Errorf("stats = %+v, want 1 sent and 1 failed", stats)
+	}
+	if len(reported) != 2 || !errors.Is(reported[0].Err, errBroker) || reported[0].Event.ID != samples[0].ID || reported[1].Err != nil {
+		t.Errorf("reported %+v, want failure of the first event and success of the second", reported)
+	}
+}
@@ -0,0 +1,290 @@
+package notify
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Change types
+const (
+	ChangeCreated     = "created"
+	ChangeDeposit     = "deposit"
+	ChangeWithdraw    = "withdraw"
+	ChangeTransferIn  = "transfer_in"
+	ChangeTransferOut = "transfer_out"
+	ChangeRenamed     = "renamed"
+	ChangeUpdated     = "updated" //metadata or tags
+	ChangeDeactivated = "deactivated"
+)
+
+// Change is a committed change of a wallet state. Balance is the wallet balance after the change.
+type Change struct {
+	Seq          uint64    `json:"-"`
+	WalletID     string    `json:"wallet_id"`
+	Type         string    `json:"type"`
+	Name         string    `json:"name,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	Balance      float64   `json:"balance"`
+	Amount       float64   `json:"amount,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	At           time.Time `json:"at"`
+}
+
+// ErrSlowSubscriber closes subscriptions which don't keep up with changes
+var ErrSlowSubscriber = errors.New("subscriber is too slow")
+
+// ErrClosed closes subscriptions of the closed hub
+var ErrClosed = errors.New("hub is closed")
+
+// Hub fans out wallet changes to subscribers. Publish never blocks:
+// a subscriber with a full buffer is disconnected instead of slowing down the service.
+// The latest changes are kept, so subscribers can resume after reconnect without losing changes.
+type Hub struct {
+	epoch string //distinguishes sequences of hub instances, they restart from 1
+
+	mu       sync.Mutex
+	seq      uint64
+	history  []Change //ring of the latest changes
+	next     int      //ring position of the next change
+	all      map[*Subscription]struct{}
+	byWallet map[string]map[*Subscription]struct{}
+	closed   bool
+}
+
+// NewHub returns hub which keeps history latest changes for resumed subscriptions
+func NewHub(history int) *Hub {
+	return &Hub{
+		epoch:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		history:  make([]Change, 0, max(history, 0)),
+		all:      make(map[*Subscription]struct{}),
+		byWallet: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Publish numbers the change and sends it to interested subscribers
+func (h *Hub) Publish(change Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.seq++
+	change.Seq = h.seq
+	if change.At.IsZero() {
+		change.At = time.Now().UTC()
+	}
+
+	h.remember(change)
+
+	for sub := range h.all {
+		h.send(sub, change)
+	}
+	for sub := range h.byWallet[change.WalletID] {
+		h.send(sub, change)
+	}
+}
+
+// remember must be called with h.mu held
+func (h *Hub) remember(change Change) {
+	if cap(h.history) == 0 {
+		return
+	}
+	if len(h.history) < cap(h.history) {
+		h.history = append(h.history, change)
+		return
+	}
+	h.history[h.next] = change
+	h.next = (h.next + 1) % len(h.history)
+}
+
+// send must be called with h.mu held
+func (h *Hub) send(sub *Subscription, change Change) {
+	select {
+	case sub.changes <- change:
+	default:
+		h.remove(sub, ErrSlowSubscriber)
+	}
+}
+
+// Subscribe returns subscription to changes of the given wallets, or of all wallets when none are given.
+// Buffer is the number of pending changes, the subscription is ended with ErrSlowSubscriber when it is full.
+func (h *Hub) Subscribe(buffer int, walletIDs ...string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.add(nil, buffer, walletIDs)
+}
+
+// Resume subscribes to changes published after the change with lastEventID, they are delivered first.
+// Complete is false when some of those changes are not kept anymore or lastEventID is of another hub instance,
+// then the subscription receives only new changes and subscriber must reload wallet state.
+func (h *Hub) Resume(lastEventID string, buffer int, walletIDs ...string) (sub *Subscription, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	after, ok := h.parseEventID(lastEventID)
+	if !ok || !h.remembers(after) {
+		return h.add(nil, buffer, walletIDs), false
+	}
+
+	var missed []Change
+	for i := range h.history {
+		change := h.history[(h.next+i)%len(h.history)]
+		if change.Seq > after && wants(walletIDs, change.WalletID) {
+			missed = append(missed, change)
+		}
+	}
+
+	return h.add(missed, buffer, walletIDs), true
+}
+
+// remembers reports whether all changes after seq are in history, must be called with h.mu held
+func (h *Hub) remembers(seq uint64) bool {
+	if seq > h.seq {
+		return false
+	}
+	if seq == h.seq {
+		return true
+	}
+	if len(h.history) == 0 {
+		return false
+	}
+	oldest := h.history[h.next%len(h.history)].Seq
+	return seq+1 >= oldest
+}
+
+// add must be called with h.mu held
+func (h *Hub) add(missed []Change, buffer int, walletIDs []string) *Subscription {
+	sub := &Subscription{
+		hub:     h,
+		start:   h.seq,
+		wallets: walletIDs,
+		changes: make(chan Change, max(buffer, 1)+len(missed)),
+	}
+	for _, change := range missed {
+		sub.changes <- change
+	}
+
+	if h.closed {
+		sub.err, sub.done = ErrClosed, true
+		close(sub.changes)
+		return sub
+	}
+
+	if len(walletIDs) == 0 {
+		h.all[sub] = struct{}{}
+	}
+	for _, id := range walletIDs {
+		if h.byWallet[id] == nil {
+			h.byWallet[id] = make(map[*Subscription]struct{})
+		}
+		h.byWallet[id][sub] = struct{}{}
+	}
+
+	return sub
+}
+
+// EventID returns ID of change which is accepted by Resume
+func (h *Hub) EventID(change Change) string {
+	return h.eventID(change.Seq)
+}
+
+func (h *Hub) eventID(seq uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+func (h *Hub) parseEventID(id string) (uint64, bool) {
+	epoch, raw, ok := strings.Cut(id, "-")
+	if !ok || epoch != h.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(raw, 10, 64)
+	return seq, err == nil
+}
+
+// Close disconnects all subscribers
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.all {
+		h.remove(sub, ErrClosed)
+	}
+	for _, subs := range h.byWallet {
+		for sub := range subs {
+			h.remove(sub, ErrClosed)
+		}
+	}
+}
+
+// remove must be called with h.mu held
+func (h *Hub) remove(sub *Subscription, err error) {
+	if sub.done {
+		return
+	}
+	sub.done = true
+
+	delete(h.all, sub)
+	for _, id := range sub.wallets {
+		delete(h.byWallet[id], sub)
+		if len(h.byWallet[id]) == 0 {
+			delete(h.byWallet, id)
+		}
+	}
+
+	sub.err = err
+	close(sub.changes)
+}
+
+func wants(walletIDs []string, walletID string) bool {
+	if len(walletIDs) == 0 {
+		return true
+	}
+	for _, id := range walletIDs {
+		if id == walletID {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription receives changes until it is closed by subscriber or by hub
+type Subscription struct {
+	hub     *Hub
+	start   uint64 //last change published before subscription
+	wallets []string
+	changes chan Change
+	err     error //guarded by hub.mu
+	done    bool  //guarded by hub.mu
+}
+
+// StartEventID returns ID of the last change published before subscription.
+// Wallet state loaded after Subscribe is resumed from it without losing changes.
+func (s *Subscription) StartEventID() string {
+	return s.hub.eventID(s.start)
+}
+
+// Changes is closed when subscription ends, Err tells why
+func (s *Subscription) Changes() <-chan Change {
+	return s.changes
+}
+
+// Err returns ErrSlowSubscriber or ErrClosed after the hub has ended subscription, nil otherwise
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+// Close unsubscribes from the hub
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s, nil)
+}
@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+)
+
+// receive returns sequence numbers of changes pending in sub, closed reports whether sub is ended
+func receive(sub *Subscription) (seqs []uint64, closed bool) {
+	for {
+		select {
+		case change, ok := <-sub.Changes():
+			if !ok {
+				return seqs, true
+			}
+			seqs = append(seqs, change.Seq)
+		default:
+			return seqs, false
+		}
+	}
+}
+
+func publish(h *Hub, walletIDs ...string) {
+	for _, id := range walletIDs {
+		h.Publish(Change{WalletID: id, Type: ChangeDeposit})
+	}
+}
+
+func equal(got, want []uint64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSubscribe(t *testing.T) {
+	h := NewHub(10)
+
+	all := h.Subscribe(10)
+	defer all.Close()
+	alice := h.Subscribe(10, "alice")
+	defer alice.Close()
+
+	publish(h, "alice", "bob", "alice")
+
+	if got, _ := receive(all); !equal(got, []uint64{1, 2, 3}) {
+		t.Errorf("subscriber of all wallets got %v, want [1 2 3]", got)
+	}
+	if got, _ := receive(alice); !equal(got, []uint64{1, 3}) {
+		t.Errorf("subscriber of alice got %v, want [1 3]", got)
+	}
+
+	alice.Close()
+	publish(h, "alice")
+	if got, closed := receive(alice); len(got) != 0 || !closed || alice.Err() != nil {
+		t.Errorf("closed subscription got %v, closed %v, err %v", got, closed, alice.Err())
+	}
+}
+
+func TestResume(t *testing.T) {
+	h := NewHub(10)
+	publish(h, "alice", "bob", "alice", "alice")
+
+	sub, complete := h.Resume(h.EventID(Change{Seq: 1}), 10, "alice")
+	defer sub.Close()
+	if !complete {
+		t.Fatalf("Resume after kept change is not complete")
+	}
+
+	publish(h, "bob", "alice")
+
+	//missed changes of alice come first, then the new ones
+	if got, _ := receive(sub); !equal(got, []uint64{3, 4, 6}) {
+		t.Errorf("resumed subscriber got %v, want [3 4 6]", got)
+	}
+
+	//resuming after the latest change needs no history
+	latest, complete := h.Resume(h.EventID(Change{Seq: 6}), 10)
+	defer latest.Close()
+	if got, _ := receive(latest); !complete || len(got) != 0 {
+		t.Errorf("Resume after the latest change = %v, complete %v, want nothing missed", got, complete)
+	}
+}
+
+func TestResumeRotated(t *testing.T) {
+	h := NewHub(3)
+	publish(h, "alice", "alice", "alice", "alice", "alice")
+
+	//changes 1..5 are published, only 3..5 are kept
+	tests := []struct {
+		name     string
+		id       string
+		complete bool
+		missed   []uint64
+	}{
+		{"oldest kept", h.EventID(Change{Seq: 2}), true, []uint64{3, 4, 5}},
+		{"rotated out", h.EventID(Change{Seq: 1}), false, nil},
+		{"from the future", h.EventID(Change{Seq: 9}), false, nil},
+		{"another hub", "otherepoch-4", false, nil},
+		{"malformed", "garbage", false, nil},
+		{"empty", "", false, nil},
+	}
+
+	for _, tt := range tests {
+		sub, complete := h.Resume(tt.id, 10, "alice")
+		got, _ := receive(sub)
+		sub.Close()
+
+		if complete != tt.complete || !equal(got, tt.missed) {
+			t.Errorf("%s: Resume = %v, complete %v, want %v, complete %v", tt.name, got, complete, tt.missed, tt.complete)
+		}
+	}
+
+	//the snapshot of an incomplete resume is taken after the start event
+	sub, complete := h.Resume("", 10, "alice")
+	defer sub.Close()
+	if complete || sub.StartEventID() != h.EventID(Change{Seq: 5}) {
+		t.Errorf("StartEventID = %s, want %s", sub.StartEventID(), h.EventID(Change{Seq: 5}))
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	h := NewHub(10)
+
+	slow := h.Subscribe(2, "alice")
+	fast := h.Subscribe(10, "alice")
+	defer fast.Close()
+
+	publish(h, "alice", "alice", "alice")
+
+	got, closed := receive(slow)
+	if !equal(got, []uint64{1, 2}) || !closed {
+		t.Errorf("slow subscriber got %v, closed %v, want [1 2] and closed", got, closed)
+	}
+	if !errors.Is(slow.Err(), ErrSlowSubscriber) {
+		t.Errorf("slow subscriber Err = %v, want %v", slow.Err(), ErrSlowSubscriber)
+	}
+
+	if got, closed := receive(fast); !equal(got, []uint64{1, 2, 3}) || closed || fast.Err() != nil {
+		t.Errorf("fast subscriber got %v, closed %v, err %v", got, closed, fast.Err())
+	}
+
+	//missed changes don't count against the buffer of resumed subscription
+	resumed, complete := h.Resume(h.EventID(Change{Seq: 0}), 1, "alice")
+	defer resumed.Close()
+	if got, closed := receive(resumed); !complete || !equal(got, []uint64{1, 2, 3}) || closed {
+		t.Errorf("resumed subscriber got %v, closed %v", got, closed)
+	}
+}
+
+func TestClose(t *testing.T) {
+	h := NewHub(10)
+	sub := h.Subscribe(10)
+	wallet := h.Subscribe(10, "alice")
+
+	h.Close()
+
+	for _, s := range []*Subscription{sub, wallet} {
+		if _, closed := receive(s); !closed || !errors.Is(s.Err(), ErrClosed) {
+			t.Errorf("subscription after Close: closed %v, err %v", closed, s.Err())
+		}
+	}
+
+	late := h.Subscribe(10)
+	if _, closed := receive(late); !closed || !errors.Is(late.Err(), ErrClosed) {
+		t.Errorf("subscription of closed hub: closed %v, err %v", closed, late.Err())
+	}
+
+	publish(h, "alice")
+}
@@ -0,0 +1,90 @@
+package publisher
+
+import (
+	"events"
+	"sync"
+)
+
+// Memory keeps published events for inspection in tests, they are never dropped
+type Memory struct {
+	mu     sync.Mutex
+	events []events.Event
+	err    error
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// SendEvent keeps event, it fails with error set by Fail instead
+func (m *Memory) SendEvent(event events.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Events returns copy of events in the order they were published
+func (m *Memory) Events() []events.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]events.Event(nil), m.events...)
+}
+
+// ByType returns events of eventType in the order they were published
+func (m *Memory) ByType(eventType string) []events.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var found []events.Event
+	for _, event := range m.events {
+		if event.Type == eventType {
+			found = append(found, event)
+		}
+	}
+	return found
+}
+
+// Last returns the last published event, false when nothing was published
+func (m *Memory) Last() (events.Event, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.events) == 0 {
+		return events.Event{}, false
+	}
+	return m.events[len(m.events)-1], true
+}
+
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.events)
+}
+
+// Reset forgets published events
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = nil
+}
+
+// Fail makes SendEvent return err, nil makes it succeed again
+func (m *Memory) Fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
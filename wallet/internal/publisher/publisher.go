@@ -0,0 +1,17 @@
+package publisher
+
+import "events"
+
+// Publisher publishes wallet events. Kafka producer is used in production,
+// other publishers let the service run and be tested without brokers.
+type Publisher interface {
+	SendEvent(event events.Event) error
+	//Close flushes pending events, nothing is sent after it
+	Close() error
+}
+
+// Nop drops every event
+type Nop struct{}
+
+func (Nop) SendEvent(event events.Event) error { return nil }
+func (Nop) Close() error                       { return nil }
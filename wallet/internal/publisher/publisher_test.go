@@ -0,0 +1,115 @@
+package publisher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"events"
+	"events/eventstest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	samples := eventstest.Samples()
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, event := range samples {
+		if err := w.SendEvent(event); err != nil {
+			t.Fatalf("SendEvent: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	assertLines(t, buf.Bytes(), samples)
+}
+
+func TestOpenFile(t *testing.T) {
+	samples := eventstest.Samples()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	//events are appended by every opened publisher
+	for _, part := range [][]events.Event{samples[:2], samples[2:]} {
+		w, err := OpenFile(path)
+		if err != nil {
+			t.Fatalf("OpenFile: %v", err)
+		}
+		for _, event := range part {
+			if err := w.SendEvent(event); err != nil {
+				t.Fatalf("SendEvent: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	assertLines(t, data, samples)
+}
+
+func assertLines(t *testing.T, data []byte, want []events.Event) {
+	t.Helper()
+
+	var got []events.Event
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var event events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %d: %v", len(got)+1, err)
+		}
+		got = append(got, event)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].ID != want[i].ID || got[i].Type != want[i].Type {
+			t.Errorf("line %d = %s %s, want %s %s", i+1, got[i].ID, got[i].Type, want[i].ID, want[i].Type)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	samples := eventstest.Samples()
+	m := NewMemory()
+
+	if _, ok := m.Last(); ok {
+		t.Fatalf("Last of empty publisher is found")
+	}
+
+	for _, event := range samples {
+		if err := m.SendEvent(event); err != nil {
+			t.Fatalf("SendEvent: %v", err)
+		}
+	}
+
+	if m.Len() != len(samples) {
+		t.Errorf("Len = %d, want %d", m.Len(), len(samples))
+	}
+	if last, ok := m.Last(); !ok || last.ID != samples[len(samples)-1].ID {
+		t.Errorf("Last = %+v, want %+v", last, samples[len(samples)-1])
+	}
+	if found := m.ByType(events.EventWalletDeposited); len(found) != 1 || found[0].Type != events.EventWalletDeposited {
+		t.Errorf("ByType = %+v", found)
+	}
+
+	m.Fail(os.ErrClosed)
+	if err := m.SendEvent(samples[0]); err != os.ErrClosed {
+		t.Errorf("SendEvent error = %v, want %v", err, os.ErrClosed)
+	}
+	m.Fail(nil)
+
+	m.Reset()
+	if m.Len() != 0 {
+		t.Errorf("Len after Reset = %d", m.Len())
+	}
+}
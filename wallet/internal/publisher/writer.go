@@ -0,0 +1,67 @@
+package publisher
+
+import (
+	"encoding/json"
+	"events"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer writes events as JSON Lines, one envelope per line
+type Writer struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+// NewWriter returns publisher writing to w, w is not closed by Close
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{encoder: json.NewEncoder(w)}
+}
+
+// Stdout returns publisher writing to standard output
+func Stdout() *Writer {
+	return NewWriter(os.Stdout)
+}
+
+// OpenFile returns publisher appending to file at path, the file is created when it doesn't exist
+func OpenFile(path string) (*Writer, error) {
+	const fn = "publisher.OpenFile"
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	w := NewWriter(f)
+	w.closer = f
+	return w, nil
+}
+
+func (w *Writer) SendEvent(event events.Event) error {
+	const fn = "publisher.Writer.SendEvent"
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.encoder.Encode(event); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+// Close closes the file opened by OpenFile
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closer == nil {
+		return nil
+	}
+
+	err := w.closer.Close()
+	w.closer = nil
+	return err
+}
@@ -0,0 +1,146 @@
+package reconcile
+
+import (
+	"context"
+	"events"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+	"wallet/internal/kafka"
+	"wallet/internal/storage"
+	"wallet/internal/utils/random"
+)
+
+const ID_LENGTH = 16
+
+type EventSender interface {
+	SendEvent(event events.Event) error
+}
+
+// Reconciler recomputes wallet balances from the operation journal and compares them with stored balances
+type Reconciler struct {
+	storage storage.Storage
+	events  EventSender
+	epsilon float64 //allowed float rounding error
+}
+
+func New(storage storage.Storage, events EventSender, epsilon float64) *Reconciler {
+	return &Reconciler{
+		storage: storage,
+		events:  events,
+		epsilon: epsilon,
+	}
+}
+
+// Run reconciles the ledger every interval until ctx is done
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RunOnce(ctx); err != nil {
+			slog.Error("Reconciliation failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce checks that every wallet balance equals the sum of its operations
+// and the sum of all balances equals external deposits minus withdrawals.
+// The report is saved, Ledger_Mismatch event is sent when discrepancies are found.
+func (r *Reconciler) RunOnce(ctx context.Context) (*storage.Reconciliation, error) {
+	const fn = "Reconciler.RunOnce"
+
+	report := &storage.Reconciliation{
+		ID:            random.NewRandomString(ID_LENGTH),
+		StartedAt:     time.Now().UTC(),
+		Discrepancies: []storage.Discrepancy{},
+	}
+
+	ledger, err := r.storage.Ledger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	report.Wallets = len(ledger.Balances)
+
+	for _, balance := range ledger.Balances {
+		report.TotalBalance += balance.Balance
+
+		if !r.equal(balance.Journal, balance.Balance) {
+			report.Discrepancies = append(report.Discrepancies, storage.Discrepancy{
+				Kind:       storage.DiscrepancyBalance,
+				WalletID:   balance.WalletID,
+				Name:       balance.Name,
+				Expected:   balance.Journal,
+				Actual:     balance.Balance,
+				Difference: balance.Balance - balance.Journal,
+			})
+		}
+	}
+
+	report.ExternalInflows = ledger.Totals[storage.OpDeposit] + ledger.Totals[storage.OpOpeningBalance]
+	report.ExternalOutflows = ledger.Totals[storage.OpWithdraw]
+
+	expected := report.ExternalInflows - report.ExternalOutflows
+	if !r.equal(expected, report.TotalBalance) {
+		report.Discrepancies = append(report.Discrepancies, storage.Discrepancy{
+			Kind:       storage.DiscrepancyTotal,
+			Expected:   expected,
+			Actual:     report.TotalBalance,
+			Difference: report.TotalBalance - expected,
+		})
+	}
+
+	transfersIn, transfersOut := ledger.Totals[storage.OpTransferIn], ledger.Totals[storage.OpTransferOut]
+	if !r.equal(transfersOut, transfersIn) {
+		report.Discrepancies = append(report.Discrepancies, storage.Discrepancy{
+			Kind:       storage.DiscrepancyTransfers,
+			Expected:   transfersOut,
+			Actual:     transfersIn,
+			Difference: transfersIn - transfersOut,
+		})
+	}
+
+	report.OK = len(report.Discrepancies) == 0
+	report.FinishedAt = time.Now().UTC()
+
+	if err := r.storage.SaveReconciliation(ctx, report); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if report.OK {
+		return report, nil
+	}
+
+	slog.Warn("Ledger discrepancies found", slog.String("report_id", report.ID), slog.Int("discrepancies", len(report.Discrepancies)))
+
+	event := kafka.NewEvent(ctx, events.EventLedgerMismatch, "", events.LedgerMismatchPayload{
+		ReportID:      report.ID,
+		Discrepancies: len(report.Discrepancies),
+		TotalBalance:  report.TotalBalance,
+		Expected:      expected,
+	})
+
+	if err := r.events.SendEvent(event); err != nil {
+		return report, fmt.Errorf("Producer.SendEvent error for reconciliation: %w", err)
+	}
+
+	return report, nil
+}
+
+// Latest returns the last saved report
+func (r *Reconciler) Latest(ctx context.Context) (*storage.Reconciliation, error) {
+	return r.storage.LatestReconciliation(ctx)
+}
+
+// equal compares amounts with relative tolerance, sums of many float operations are not exact
+func (r *Reconciler) equal(a, b float64) bool {
+	return math.Abs(a-b) <= r.epsilon*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
@@ -1,18 +1,47 @@
 package chirouter
 
 import (
+	"net/http"
+	"wallet/internal/accounting"
+	"wallet/internal/config"
 	"wallet/internal/http/handlers"
+	"wallet/internal/http/idempotency"
+	"wallet/internal/http/openapi"
+	"wallet/internal/importer"
+	"wallet/internal/kafka"
+	"wallet/internal/notify"
+	"wallet/internal/reconcile"
 	"wallet/internal/service"
+	"wallet/internal/webhook"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 )
 
-func InitWallet(r *chi.Mux, s *service.WalletService) {
+// InitMiddleware must be called before other Init functions, chi does not accept middlewares after routes
+func InitMiddleware(r *chi.Mux, validator func(http.Handler) http.Handler, replays *idempotency.Store) {
 
 	r.Use(middleware.RequestID) //трейсинг запросов
+	r.Use(correlation)          //связь событий с запросом
 	r.Use(middleware.Logger)    //логирование запросов
 	r.Use(middleware.Recoverer) //отлов паник
+	r.Use(validator)            //проверка запросов по спецификации
+	r.Use(replays.Middleware)   //повтор ответов на запросы с Idempotency-Key
+
+	r.NotFound(handlers.NotFoundHandler)
+	r.MethodNotAllowed(handlers.MethodNotAllowedHandler)
+}
+
+// correlation correlates events sent while handling request by its ID
+func correlation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := kafka.WithCorrelationID(r.Context(), middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func InitWallet(r *chi.Mux, s *service.WalletService, cfg *config.Config) {
 
 	r.Route("/wallet", func(r chi.Router) {
 		r.Post("/", handlers.CreateWalletHandler(s))
@@ -20,11 +49,58 @@ func InitWallet(r *chi.Mux, s *service.WalletService) {
 	})
 
 	r.Route("/wallets", func(r chi.Router) {
-		r.Get("/", handlers.GetWalletsHandler(s))
+		r.Get("/", handlers.ListWalletsHandler(s))
+		r.Get("/search", handlers.ListWalletsHandler(s))
+		r.Get("/balances", handlers.WalletsBalanceHandler(s))
 		r.Get("/{id}", handlers.GetWalletHandler(s))
 		r.Put("/{id}", handlers.PutWalletsNameHandler(s))
+		r.Patch("/{id}/metadata", handlers.PatchWalletMetadataHandler(s))
+		r.Patch("/{id}/tags", handlers.PatchWalletTagsHandler(s))
 		r.Post("/{id}/deposit", handlers.WalletDepositHandler(s))
 		r.Post("/{id}/withdraw", handlers.WalletWithdrawHandler(s))
 		r.Post("/{id}/transfer", handlers.WalletTransferHandler(s))
+		r.Get("/{id}/balance", handlers.WalletBalanceHandler(s))
+		r.Get("/{id}/statement", handlers.WalletStatementHandler(s, cfg.Currency, cfg.Timeout))
+	})
+}
+
+// InitEvents registers streams of wallet changes
+func InitEvents(r *chi.Mux, s *service.WalletService, hub *notify.Hub, cfg *config.Config) {
+	streams := handlers.StreamConfig{
+		Buffer:       cfg.Streams.Buffer,
+		Heartbeat:    cfg.Streams.Heartbeat,
+		WriteTimeout: cfg.Streams.WriteTimeout,
+	}
+
+	r.Get("/wallets/{id}/events", handlers.WalletEventsHandler(s, hub, streams))
+	r.Get("/wallets/{id}/ws", handlers.WalletEventsWebSocketHandler(s, hub, streams))
+}
+
+func InitExport(r *chi.Mux, e *accounting.Exporter, cfg *config.Config) {
+	r.Get("/export/journal", handlers.ExportJournalHandler(e, cfg.Timeout))
+}
+
+func InitImport(r *chi.Mux, i *importer.Importer) {
+	r.Post("/wallets/import", handlers.ImportWalletsHandler(i))
+}
+
+func InitOpenAPI(r *chi.Mux, doc *openapi3.T) {
+	r.Get("/openapi.json", openapi.Handler(doc))
+}
+
+func InitReconcile(r *chi.Mux, rec *reconcile.Reconciler) {
+	r.Get("/reconciliation/latest", handlers.LatestReconciliationHandler(rec))
+}
+
+func InitWebhooks(r *chi.Mux, d *webhook.Dispatcher) {
+
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Post("/", handlers.CreateWebhookHandler(d))
+		r.Get("/", handlers.ListWebhooksHandler(d))
+		r.Get("/{id}", handlers.GetWebhookHandler(d))
+		r.Patch("/{id}", handlers.PatchWebhookHandler(d))
+		r.Delete("/{id}", handlers.DeleteWebhookHandler(d))
+		r.Get("/{id}/deliveries", handlers.WebhookDeliveriesHandler(d))
+		r.Post("/{id}/deliveries/{delivery_id}/replay", handlers.ReplayDeliveryHandler(d))
 	})
 }
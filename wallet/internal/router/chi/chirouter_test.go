@@ -0,0 +1,62 @@
+package chirouter
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"wallet/internal/config"
+	"wallet/internal/http/idempotency"
+	"wallet/internal/http/openapi"
+
+	"github.com/go-chi/chi"
+)
+
+// TestRoutesDescribed fails when a route is registered without being described in the API specification or vice versa
+func TestRoutesDescribed(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	validator, err := openapi.Validator(spec)
+	if err != nil {
+		t.Fatalf("Validator: %v", err)
+	}
+
+	router := chi.NewRouter()
+	InitMiddleware(router, validator, idempotency.New(time.Hour, 0))
+	InitWallet(router, nil, &config.Config{})
+	InitEvents(router, nil, nil, &config.Config{})
+	InitExport(router, nil, &config.Config{})
+	InitImport(router, nil)
+	InitReconcile(router, nil)
+	InitWebhooks(router, nil)
+	InitOpenAPI(router, spec)
+
+	registered := make(map[string]bool)
+
+	err = chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		//routes of subrouters end with slash, "/wallet/" is served as "/wallet" as well
+		if trimmed := strings.TrimSuffix(route, "/"); trimmed != "" {
+			route = trimmed
+		}
+		registered[method+" "+route] = true
+
+		item := spec.Paths.Value(route)
+		if item == nil || item.GetOperation(method) == nil {
+			t.Errorf("%s %s is not described in openapi.yaml", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			if !registered[method+" "+path] {
+				t.Errorf("%s %s is described in openapi.yaml but not registered", method, path)
+			}
+		}
+	}
+}
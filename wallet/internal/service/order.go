@@ -0,0 +1,64 @@
+package service
+
+import "sync"
+
+// walletLocks serialize operations of a wallet from taking the event sequence number till publishing the event,
+// so one instance publishes events of a wallet in sequence order. Instances don't share the locks,
+// consumers detect reordering between them by events.Event.Sequence. Zero value is ready to use.
+type walletLocks struct {
+	mu    sync.Mutex
+	locks map[string]*walletLock
+}
+
+type walletLock struct {
+	sync.Mutex
+	refs int //holders and waiters, the lock is dropped when there are none
+}
+
+// lock locks wallet and returns its unlock
+func (l *walletLocks) lock(walletID string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*walletLock)
+	}
+	lock, ok := l.locks[walletID]
+	if !ok {
+		lock = &walletLock{}
+		l.locks[walletID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, walletID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// lockPair locks both wallets in ID order, so transfers in opposite directions don't deadlock
+func (l *walletLocks) lockPair(walletID, otherID string) func() {
+	pair := sortedPair(walletID, otherID)
+	unlock := l.lock(pair[0])
+	unlockOther := l.lock(pair[1])
+
+	return func() {
+		unlockOther()
+		unlock()
+	}
+}
+
+// sortedPair returns both wallet IDs in ascending order
+func sortedPair(walletID, otherID string) [2]string {
+	if otherID < walletID {
+		return [2]string{otherID, walletID}
+	}
+	return [2]string{walletID, otherID}
+}
@@ -3,39 +3,86 @@ package service
 import (
 	"context"
 	"errors"
+	"events"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"wallet/internal/domain"
 	"wallet/internal/kafka"
+	"wallet/internal/notify"
+	"wallet/internal/publisher"
+	"wallet/internal/statement"
 	"wallet/internal/storage"
 )
 
 type WalletService struct {
-	storage  storage.Storage
-	producer *kafka.Producer
+	storage   storage.Storage
+	publisher publisher.Publisher
+	notifier  Notifier
+	wallets   walletLocks //events of a wallet are published in sequence order
 }
 
-func New(storage storage.Storage, producer *kafka.Producer) *WalletService {
+// Notifier receives changes of wallets after they are committed
+type Notifier interface {
+	Publish(change notify.Change)
+}
+
+var (
+	ErrInvalidAmount     = domain.New(domain.ErrInvalidAmount, "invalid_amount", "amount must be a positive finite number")
+	ErrInsufficientFunds = domain.New(domain.ErrInsufficientFunds, "insufficient_funds", "insufficient funds")
+	ErrWalletInactive    = domain.New(domain.ErrInactive, "wallet_inactive", "wallet is inactive")
+	ErrInvalidName       = domain.New(domain.ErrInvalidArgument, "invalid_name", "the name length must be more than 1 character")
+	ErrSelfTransfer      = domain.New(domain.ErrInvalidArgument, "self_transfer", "can't transfer to the same wallet")
+)
+
+// New returns wallet service, notifier may be nil
+func New(storage storage.Storage, publisher publisher.Publisher, notifier Notifier) *WalletService {
 	return &WalletService{
-		storage:  storage,
-		producer: producer}
+		storage:   storage,
+		publisher: publisher,
+		notifier:  notifier}
+}
+
+func (w *WalletService) notify(change notify.Change) {
+	if w.notifier != nil {
+		w.notifier.Publish(change)
+	}
+}
+
+// walletChange describes the wallet state after change
+func walletChange(changeType string, wallet *storage.Wallet) notify.Change {
+	return notify.Change{
+		WalletID: wallet.ID,
+		Type:     changeType,
+		Name:     wallet.Name,
+		Status:   wallet.Status,
+		Balance:  wallet.Balance,
+		At:       time.Now().UTC(),
+	}
 }
 
 func (w *WalletService) Deposit(ctx context.Context, walletID string, amount float64) (int64, error) {
 	const fn = "WalletService.Deposit"
-	if amount <= 0 {
-		return 0, fmt.Errorf("%s: Amount must be positive", fn)
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("%s: %w", fn, ErrInvalidAmount)
 	}
 
+	defer w.wallets.lock(walletID)()
+
 	tx, err := w.storage.BeginTx(ctx)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
+	defer tx.Rollback()
 
 	wallet, err := tx.GetWallet(ctx, walletID)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
-	if wallet.Status == "inactive" {
-		return 0, storage.ErrWalletNotFound
+	if wallet.Status == storage.StatusInactive {
+		return 0, fmt.Errorf("%s: %w", fn, ErrWalletInactive)
 	}
 
 	wallet.Balance += amount
@@ -45,20 +92,35 @@ func (w *WalletService) Deposit(ctx context.Context, walletID string, amount flo
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err := tx.AddOperation(ctx, &storage.Operation{
+		WalletID: wallet.ID,
+		Type:     storage.OpDeposit,
+		Amount:   amount,
+	}); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	seq, err := tx.NextEventSeq(ctx, wallet.ID)
+	if err != nil {
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	event := kafka.Event{
-		Type: kafka.EventWalletDeposited,
-		Payload: kafka.WalletDepositedPayload{
-			ID:     wallet.ID,
-			Name:   wallet.Name,
-			Amount: amount,
-		},
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if err := w.producer.SendEvent(event); err != nil {
+	change := walletChange(notify.ChangeDeposit, wallet)
+	change.Amount = amount
+	w.notify(change)
+
+	event := kafka.NewEvent(ctx, events.EventWalletDeposited, wallet.ID, events.WalletDepositedPayload{
+		ID:     wallet.ID,
+		Name:   wallet.Name,
+		Amount: amount,
+	})
+	event.Sequence = seq
+
+	if err := w.publisher.SendEvent(event); err != nil {
 		return 0, fmt.Errorf("Producer.SendEvent error for deposit service: %w", err)
 	}
 
@@ -67,24 +129,27 @@ func (w *WalletService) Deposit(ctx context.Context, walletID string, amount flo
 
 func (w *WalletService) Withdraw(ctx context.Context, walletID string, amount float64) (int64, error) {
 	const fn = "WalletService.Withdraw"
-	if amount <= 0 {
-		return 0, fmt.Errorf("%s: Amount must be positive", fn)
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("%s: %w", fn, ErrInvalidAmount)
 	}
 
+	defer w.wallets.lock(walletID)()
+
 	tx, err := w.storage.BeginTx(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
+	defer tx.Rollback()
 
 	wallet, err := tx.GetWallet(ctx, walletID)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
-	if wallet.Status == "inactive" {
-		return 0, storage.ErrWalletNotFound
+	if wallet.Status == storage.StatusInactive {
+		return 0, fmt.Errorf("%s: %w", fn, ErrWalletInactive)
 	}
 	if wallet.Balance < amount {
-		return 0, fmt.Errorf("%s: Insufficient funds", fn)
+		return 0, fmt.Errorf("%s: %w", fn, ErrInsufficientFunds)
 	}
 
 	wallet.Balance -= amount
@@ -94,20 +159,35 @@ func (w *WalletService) Withdraw(ctx context.Context, walletID string, amount fl
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err := tx.AddOperation(ctx, &storage.Operation{
+		WalletID: wallet.ID,
+		Type:     storage.OpWithdraw,
+		Amount:   amount,
+	}); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	seq, err := tx.NextEventSeq(ctx, wallet.ID)
+	if err != nil {
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	event := kafka.Event{
-		Type: kafka.EventWalletWithdrawn,
-		Payload: kafka.WalletWithdrawnPayload{
-			ID:     wallet.ID,
-			Name:   wallet.Name,
-			Amount: amount,
-		},
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if err := w.producer.SendEvent(event); err != nil {
+	change := walletChange(notify.ChangeWithdraw, wallet)
+	change.Amount = amount
+	w.notify(change)
+
+	event := kafka.NewEvent(ctx, events.EventWalletWithdrawn, wallet.ID, events.WalletWithdrawnPayload{
+		ID:     wallet.ID,
+		Name:   wallet.Name,
+		Amount: amount,
+	})
+	event.Sequence = seq
+
+	if err := w.publisher.SendEvent(event); err != nil {
 		return 0, fmt.Errorf("Producer.SendEvent error for withdraw service: %w", err)
 	}
 
@@ -116,33 +196,40 @@ func (w *WalletService) Withdraw(ctx context.Context, walletID string, amount fl
 
 func (w *WalletService) Transfer(ctx context.Context, walletID string, amount float64, transferTo string) (int64, int64, error) {
 	const fn = "WalletService.Transfer"
-	if amount <= 0 {
-		return 0, 0, fmt.Errorf("%s: Amount must be positive", fn)
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, 0, fmt.Errorf("%s: %w", fn, ErrInvalidAmount)
 	}
+	if walletID == transferTo {
+		return 0, 0, fmt.Errorf("%s: %w", fn, ErrSelfTransfer)
+	}
+
+	//transfer events are numbered by both wallets
+	defer w.wallets.lockPair(walletID, transferTo)()
 
 	tx, err := w.storage.BeginTx(ctx)
 	if err != nil {
 		return 0, 0, fmt.Errorf("%s: %w", fn, err)
 	}
+	defer tx.Rollback()
 
 	fromWallet, err := tx.GetWallet(ctx, walletID)
 	if err != nil {
 		return 0, 0, fmt.Errorf("%s: %w", fn, err)
 	}
-	if fromWallet.Status == "inactive" {
-		return 0, 0, storage.ErrWalletNotFound
+	if fromWallet.Status == storage.StatusInactive {
+		return 0, 0, fmt.Errorf("%s: %w", fn, ErrWalletInactive)
 	}
 
 	toWallet, err := tx.GetWallet(ctx, transferTo)
 	if err != nil {
 		return 0, 0, fmt.Errorf("%s: %w", fn, err)
 	}
-	if toWallet.Status == "inactive" {
-		return 0, 0, storage.ErrWalletNotFound
+	if toWallet.Status == storage.StatusInactive {
+		return 0, 0, fmt.Errorf("%s: %w", fn, ErrWalletInactive)
 	}
 
 	if fromWallet.Balance < amount {
-		return 0, 0, fmt.Errorf("%s: Insufficient funds", fn)
+		return 0, 0, fmt.Errorf("%s: %w", fn, ErrInsufficientFunds)
 	}
 
 	fromWallet.Balance -= amount
@@ -158,22 +245,82 @@ func (w *WalletService) Transfer(ctx context.Context, walletID string, amount fl
 		return 0, 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if err := tx.Commit(); err != nil {
+	//both legs of transfer share the same timestamp
+	now := time.Now().UTC()
+
+	if err := tx.AddOperation(ctx, &storage.Operation{
+		WalletID:     fromWallet.ID,
+		Type:         storage.OpTransferOut,
+		Amount:       amount,
+		Counterparty: toWallet.ID,
+		CreatedAt:    now,
+	}); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.AddOperation(ctx, &storage.Operation{
+		WalletID:     toWallet.ID,
+		Type:         storage.OpTransferIn,
+		Amount:       amount,
+		Counterparty: fromWallet.ID,
+		CreatedAt:    now,
+	}); err != nil {
 		return 0, 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	event := kafka.Event{
-		Type: kafka.EventWalletTransferred,
-		Payload: kafka.WalletTransferredPayload{
-			ID:         walletID,
-			Name:       fromWallet.Name,
-			TransferTo: transferTo,
-			Amount:     amount,
-		},
+	//sender and recipient events are keyed and numbered by their wallets, see events.Event.
+	//Sequences are taken in ID order as the wallet locks.
+	seqs := make(map[string]int64, 2)
+	for _, id := range sortedPair(fromWallet.ID, toWallet.ID) {
+		seq, err := tx.NextEventSeq(ctx, id)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s: %w", fn, err)
+		}
+		seqs[id] = seq
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if err := w.producer.SendEvent(event); err != nil {
-		return id, recipientID, fmt.Errorf("Producer.SendEvent error for transfer service: %w", err)
+	for _, leg := range []struct {
+		changeType string
+		wallet     *storage.Wallet
+		other      string
+	}{
+		{notify.ChangeTransferOut, fromWallet, toWallet.ID},
+		{notify.ChangeTransferIn, toWallet, fromWallet.ID},
+	} {
+		change := walletChange(leg.changeType, leg.wallet)
+		change.Amount = amount
+		change.Counterparty = leg.other
+		change.At = now
+		w.notify(change)
+	}
+
+	sent := kafka.NewEvent(ctx, events.EventWalletTransferred, walletID, events.WalletTransferredPayload{
+		ID:         walletID,
+		Name:       fromWallet.Name,
+		TransferTo: transferTo,
+		Amount:     amount,
+	})
+	sent.Sequence = seqs[walletID]
+
+	received := kafka.NewEvent(ctx, events.EventWalletReceived, transferTo, events.WalletReceivedPayload{
+		ID:           transferTo,
+		Name:         toWallet.Name,
+		TransferFrom: walletID,
+		Amount:       amount,
+	})
+	received.Sequence = seqs[transferTo]
+
+	//the recipient event is sent even when the sender one fails, so the recipient's sequence has no gap
+	sendErr := w.publisher.SendEvent(sent)
+	if err := w.publisher.SendEvent(received); err != nil && sendErr == nil {
+		sendErr = err
+	}
+	if sendErr != nil {
+		return id, recipientID, fmt.Errorf("Producer.SendEvent error for transfer service: %w", sendErr)
 	}
 
 	return id, recipientID, nil
@@ -182,43 +329,43 @@ func (w *WalletService) Transfer(ctx context.Context, walletID string, amount fl
 func (w *WalletService) UpdateName(ctx context.Context, walletID, name string) (int64, error) {
 	const fn = "WalletService.UpdateName"
 	if len(name) <= 1 {
-		return 0, fmt.Errorf("%s: The name length must be more than 1 character", fn)
+		return 0, fmt.Errorf("%s: %w", fn, ErrInvalidName)
 	}
 
 	tx, err := w.storage.BeginTx(ctx)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
+	defer tx.Rollback()
 
 	wallet, err := tx.GetWallet(ctx, walletID)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
-	if wallet.Status == "inactive" {
-		return 0, storage.ErrWalletNotFound
+	if wallet.Status == storage.StatusInactive {
+		return 0, fmt.Errorf("%s: %w", fn, ErrWalletInactive)
 	}
 
 	wallet.Name = name
 
 	id, err := tx.UpdateWallet(ctx, wallet)
-	if errors.Is(err, storage.ErrWalletNotExist) {
-		return 0, err
-	}
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
 	if err := tx.Commit(); err != nil {
-		return 0, err
+		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
+	w.notify(walletChange(notify.ChangeRenamed, wallet))
+
 	return id, nil
 }
 
 func (w *WalletService) CreateWallet(ctx context.Context, name string) (*storage.Wallet, error) {
 	const fn = "WalletService.CreateWallet"
 	if len(name) <= 1 {
-		return nil, fmt.Errorf("%s: The name length must be more than 1 character", fn)
+		return nil, fmt.Errorf("%s: %w", fn, ErrInvalidName)
 	}
 
 	walletID, err := w.storage.CreateWallet(ctx, name)
@@ -229,15 +376,22 @@ func (w *WalletService) CreateWallet(ctx context.Context, name string) (*storage
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	event := kafka.Event{
-		Type: kafka.EventWalletCreated,
-		Payload: kafka.WalletCreatedPayload{
-			ID:   walletID,
-			Name: name,
-		},
+	w.notify(walletChange(notify.ChangeCreated, &storage.Wallet{ID: walletID, Name: name, Status: storage.StatusActive}))
+
+	defer w.wallets.lock(walletID)()
+
+	seq, err := w.storage.NextEventSeq(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if err := w.producer.SendEvent(event); err != nil {
+	event := kafka.NewEvent(ctx, events.EventWalletCreated, walletID, events.WalletCreatedPayload{
+		ID:   walletID,
+		Name: name,
+	})
+	event.Sequence = seq
+
+	if err := w.publisher.SendEvent(event); err != nil {
 		return nil, fmt.Errorf("Producer.SendEvent error for wallet create service: %w", err)
 	}
 
@@ -250,33 +404,75 @@ func (w *WalletService) GetWallet(ctx context.Context, walletID string) (*storag
 	var wallet *storage.Wallet
 
 	wallet, err := w.storage.GetWallet(ctx, walletID)
-	if errors.Is(err, storage.ErrWalletNotExist) {
-		return nil, err
-	}
-	if wallet.Status == "inactive" {
-		return nil, storage.ErrWalletNotFound
-	}
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
+	if wallet.Status == storage.StatusInactive {
+		return nil, storage.ErrWalletNotFound
+	}
 
 	return wallet, nil
 }
 
-func (w *WalletService) GetWallets(ctx context.Context) ([]storage.Wallet, error) {
-	const fn = "WalletService.GetWallets"
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	// StatusAll disables status filtering in ListWallets
+	StatusAll = "all"
+)
+
+// ListWallets returns a page of wallets. Inactive wallets are hidden unless status is set explicitly.
+func (w *WalletService) ListWallets(ctx context.Context, query storage.WalletQuery) (*storage.WalletPage, error) {
+	const fn = "WalletService.ListWallets"
+
+	switch query.Status {
+	case "":
+		query.Status = storage.StatusActive
+	case StatusAll:
+		query.Status = ""
+	case storage.StatusActive, storage.StatusInactive:
+	default:
+		return nil, fmt.Errorf("%s: %w", fn, domain.Errorf(domain.ErrInvalidArgument, "invalid_status", "unknown status %q", query.Status))
+	}
+
+	switch query.SortBy {
+	case "":
+		query.SortBy = storage.SortByCreatedAt
+	case storage.SortByName, storage.SortByBalance, storage.SortByCreatedAt:
+	default:
+		return nil, fmt.Errorf("%s: %w", fn, domain.Errorf(domain.ErrInvalidArgument, "invalid_sort", "unknown sort field %q", query.SortBy))
+	}
 
-	wallets, err := w.storage.GetWallets(ctx)
+	if query.Limit <= 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit > maxPageSize {
+		query.Limit = maxPageSize
+	}
+
+	tags, err := NormalizeTags(query.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	query.Tags = tags
+
+	if query.MinBalance != nil && query.MaxBalance != nil && *query.MinBalance > *query.MaxBalance {
+		return nil, fmt.Errorf("%s: %w", fn, domain.New(domain.ErrInvalidArgument, "invalid_balance_range", "min_balance must not be greater than max_balance"))
+	}
+
+	page, err := w.storage.ListWallets(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	return wallets, nil
+	return page, nil
 }
 
 func (w *WalletService) DeactivateWallet(ctx context.Context, walletID string) (int64, error) {
 	const fn = "WalletService.GetWallets"
 
+	defer w.wallets.lock(walletID)()
+
 	id, err := w.storage.DeactivateWallet(ctx, walletID)
 	if errors.Is(err, storage.ErrWalletNotExist) {
 		return 0, err
@@ -285,16 +481,237 @@ func (w *WalletService) DeactivateWallet(ctx context.Context, walletID string) (
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	event := kafka.Event{
-		Type: kafka.EventWalletDeleted,
-		Payload: kafka.WalletDeletedPayload{
-			ID: walletID,
-		},
+	w.notify(walletChange(notify.ChangeDeactivated, &storage.Wallet{ID: walletID, Status: storage.StatusInactive}))
+
+	seq, err := w.storage.NextEventSeq(ctx, walletID)
+	if err != nil {
+		return id, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if err := w.producer.SendEvent(event); err != nil {
+	event := kafka.NewEvent(ctx, events.EventWalletDeleted, walletID, events.WalletDeletedPayload{
+		ID: walletID,
+	})
+	event.Sequence = seq
+
+	if err := w.publisher.SendEvent(event); err != nil {
 		return id, fmt.Errorf("Producer.SendEvent error for wallet delete service: %w", err)
 	}
 
 	return id, nil
 }
+
+// PatchMetadata merges patch into wallet metadata. Keys with null value are removed.
+func (w *WalletService) PatchMetadata(ctx context.Context, walletID string, patch map[string]any) (*storage.Wallet, error) {
+	const fn = "WalletService.PatchMetadata"
+
+	tx, err := w.storage.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := tx.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if wallet.Status == storage.StatusInactive {
+		return nil, fmt.Errorf("%s: %w", fn, ErrWalletInactive)
+	}
+
+	if wallet.Metadata == nil {
+		wallet.Metadata = make(map[string]any, len(patch))
+	}
+	for key, value := range patch {
+		if key == "" {
+			return nil, fmt.Errorf("%s: %w", fn, domain.New(domain.ErrInvalidArgument, "invalid_metadata_key", "metadata key must not be empty"))
+		}
+		if value == nil {
+			delete(wallet.Metadata, key)
+			continue
+		}
+		wallet.Metadata[key] = value
+	}
+
+	if err := tx.UpdateMetadata(ctx, walletID, wallet.Metadata); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	w.notify(walletChange(notify.ChangeUpdated, wallet))
+
+	return wallet, nil
+}
+
+// PatchTags adds and removes wallet tags. Tags are trimmed and lowercased.
+func (w *WalletService) PatchTags(ctx context.Context, walletID string, add, remove []string) (*storage.Wallet, error) {
+	const fn = "WalletService.PatchTags"
+
+	add, err := NormalizeTags(add)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	remove, err = NormalizeTags(remove)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	tx, err := w.storage.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := tx.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if wallet.Status == storage.StatusInactive {
+		return nil, fmt.Errorf("%s: %w", fn, ErrWalletInactive)
+	}
+
+	tags := make(map[string]struct{}, len(wallet.Tags)+len(add))
+	for _, tag := range wallet.Tags {
+		tags[tag] = struct{}{}
+	}
+	for _, tag := range add {
+		tags[tag] = struct{}{}
+	}
+	for _, tag := range remove {
+		delete(tags, tag)
+	}
+
+	wallet.Tags = make([]string, 0, len(tags))
+	for tag := range tags {
+		wallet.Tags = append(wallet.Tags, tag)
+	}
+	sort.Strings(wallet.Tags)
+
+	if err := tx.UpdateTags(ctx, walletID, wallet.Tags); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	w.notify(walletChange(notify.ChangeUpdated, wallet))
+
+	return wallet, nil
+}
+
+const maxTagLength = 64
+
+// NormalizeTags trims and lowercases tags, empty and too long tags are rejected
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, domain.New(domain.ErrInvalidArgument, "invalid_tag", "tag must not be empty")
+		}
+		if len(tag) > maxTagLength {
+			return nil, domain.Errorf(domain.ErrInvalidArgument, "invalid_tag", "tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		normalized = append(normalized, tag)
+	}
+
+	return normalized, nil
+}
+
+// BalanceAt computes wallet balance at the moment at from the operation journal
+func (w *WalletService) BalanceAt(ctx context.Context, walletID string, at time.Time) (*storage.WalletBalance, error) {
+	const fn = "WalletService.BalanceAt"
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+	at = at.UTC()
+
+	balance, err := w.storage.BalanceAt(ctx, walletID, at)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &storage.WalletBalance{WalletID: walletID, Balance: balance, At: at}, nil
+}
+
+// BalancesAt computes balances of all wallets existed at the moment at, used for period end reports
+func (w *WalletService) BalancesAt(ctx context.Context, at time.Time) ([]storage.WalletBalance, error) {
+	const fn = "WalletService.BalancesAt"
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+	at = at.UTC()
+
+	balances, err := w.storage.BalancesAt(ctx, at)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return balances, nil
+}
+
+// WriteStatement streams wallet statement with operations made after from up to and including to.
+// Zero from means wallet creation, zero to means now.
+func (w *WalletService) WriteStatement(ctx context.Context, walletID string, from, to time.Time, out statement.Writer) error {
+	const fn = "WalletService.WriteStatement"
+
+	wallet, err := w.storage.GetWallet(ctx, walletID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from, to = from.UTC(), to.UTC()
+
+	if !from.IsZero() && from.After(to) {
+		return fmt.Errorf("%s: %w", fn, domain.New(domain.ErrInvalidArgument, "invalid_period", "from must not be after to"))
+	}
+
+	filter := storage.OperationFilter{WalletID: walletID, From: from, To: to}
+
+	var opening float64
+
+	if from.Before(wallet.CreatedAt) {
+		//there are no operations before wallet creation, statement starts from the first one
+		filter.From = time.Time{}
+		if from.IsZero() {
+			from = wallet.CreatedAt
+		}
+	} else if opening, err = w.storage.BalanceAt(ctx, walletID, from); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := out.Begin(statement.Header{
+		WalletID:       wallet.ID,
+		Name:           wallet.Name,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+	}); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	balance := opening
+
+	err = w.storage.WalkOperations(ctx, filter, func(op storage.Operation) error {
+		balance += op.Delta()
+		return out.Line(statement.Line{Operation: op, Balance: balance})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := out.End(balance); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"events"
+	"sync"
+	"testing"
+	"wallet/internal/publisher"
+	"wallet/internal/storage/memory"
+)
+
+// TestEvents checks events published by wallet operations without kafka
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	pub := publisher.NewMemory()
+	s := New(memory.New(), pub, nil)
+
+	alice, err := s.CreateWallet(ctx, "alice")
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	bob, err := s.CreateWallet(ctx, "bob")
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	if _, err := s.Deposit(ctx, alice.ID, 100); err != nil {
+		t.Fatalf("Deposit: %v", err)
+	}
+	if _, err := s.Withdraw(ctx, alice.ID, 10); err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+	if _, _, err := s.Transfer(ctx, alice.ID, 30, bob.ID); err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	want := []struct {
+		eventType string
+		key       string
+		seq       int64
+	}{
+		{events.EventWalletCreated, alice.ID, 1},
+		{events.EventWalletCreated, bob.ID, 1},
+		{events.EventWalletDeposited, alice.ID, 2},
+		{events.EventWalletWithdrawn, alice.ID, 3},
+		{events.EventWalletTransferred, alice.ID, 4},
+		{events.EventWalletReceived, bob.ID, 2},
+	}
+
+	got := pub.Events()
+	if len(got) != len(want) {
+		t.Fatalf("published %d events, want %d", len(got), len(want))
+	}
+	for i, event := range got {
+		if event.Type != want[i].eventType || event.PartitionKey != want[i].key || event.Sequence != want[i].seq {
+			t.Errorf("event %d = %s %s #%d, want %s %s #%d", i, event.Type, event.PartitionKey, event.Sequence,
+				want[i].eventType, want[i].key, want[i].seq)
+		}
+	}
+
+	transfers := pub.ByType(events.EventWalletTransferred)
+	if len(transfers) != 1 {
+		t.Fatalf("published %d transfers, want 1", len(transfers))
+	}
+	payload, ok := transfers[0].Payload.(events.WalletTransferredPayload)
+	if !ok || payload.TransferTo != bob.ID || payload.Amount != 30 {
+		t.Errorf("transfer payload = %+v", transfers[0].Payload)
+	}
+
+	received, ok := pub.ByType(events.EventWalletReceived)[0].Payload.(events.WalletReceivedPayload)
+	if !ok || received.ID != bob.ID || received.TransferFrom != alice.ID || received.Amount != 30 {
+		t.Errorf("received payload = %+v", received)
+	}
+}
+
+func TestPublisherError(t *testing.T) {
+	ctx := context.Background()
+	pub := publisher.NewMemory()
+	s := New(memory.New(), pub, nil)
+
+	wallet, err := s.CreateWallet(ctx, "alice")
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	errDown := errors.New("publisher is down")
+	pub.Fail(errDown)
+
+	if _, err := s.Deposit(ctx, wallet.ID, 100); !errors.Is(err, errDown) {
+		t.Fatalf("Deposit error = %v, want %v", err, errDown)
+	}
+	if pub.Len() != 1 {
+		t.Errorf("published %d events, want 1", pub.Len())
+	}
+}
+
+// TestEventOrder checks that concurrent operations of a wallet publish events in sequence order
+func TestEventOrder(t *testing.T) {
+	ctx := context.Background()
+	pub := publisher.NewMemory()
+	s := New(memory.New(), pub, nil)
+
+	wallet, err := s.CreateWallet(ctx, "alice")
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Deposit(ctx, wallet.ID, 1); err != nil {
+				t.Errorf("Deposit: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, event := range pub.Events() {
+		if event.Sequence != int64(i+1) {
+			t.Fatalf("event %d has sequence %d", i, event.Sequence)
+		}
+	}
+}
+
+// TestTransferOrder checks that concurrent transfers in both directions publish events of each wallet in sequence order
+func TestTransferOrder(t *testing.T) {
+	ctx := context.Background()
+	pub := publisher.NewMemory()
+	s := New(memory.New(), pub, nil)
+
+	var ids []string
+	for _, name := range []string{"alice", "bob"} {
+		wallet, err := s.CreateWallet(ctx, name)
+		if err != nil {
+			t.Fatalf("CreateWallet: %v", err)
+		}
+		if _, err := s.Deposit(ctx, wallet.ID, 1000); err != nil {
+			t.Fatalf("Deposit: %v", err)
+		}
+		ids = append(ids, wallet.ID)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		from, to := ids[i%2], ids[1-i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := s.Transfer(ctx, from, 1, to); err != nil {
+				t.Errorf("Transfer: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	last := make(map[string]int64)
+	for i, event := range pub.Events() {
+		if event.Sequence != last[event.PartitionKey]+1 {
+			t.Fatalf("event %d of %s has sequence %d after %d", i, event.PartitionKey, event.Sequence, last[event.PartitionKey])
+		}
+		last[event.PartitionKey] = event.Sequence
+	}
+	for _, id := range ids {
+		if last[id] != 52 {
+			t.Errorf("%s has %d events, want 52", id, last[id])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+	// to is used as a date of the closing balance row
+	to time.Time
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Begin(header Header) error {
+	c.to = header.To
+
+	if err := c.w.Write([]string{"date", "operation_id", "type", "amount", "counterparty", "balance"}); err != nil {
+		return err
+	}
+
+	return c.w.Write([]string{formatDate(header.From), "", "opening_balance", "", "", formatAmount(header.OpeningBalance)})
+}
+
+func (c *csvWriter) Line(line Line) error {
+	return c.w.Write([]string{
+		formatDate(line.CreatedAt),
+		line.ID,
+		line.Type,
+		formatAmount(line.Delta()),
+		line.Counterparty,
+		formatAmount(line.Balance),
+	})
+}
+
+func (c *csvWriter) End(closingBalance float64) error {
+	if err := c.w.Write([]string{formatDate(c.to), "", "closing_balance", "", "", formatAmount(closingBalance)}); err != nil {
+		return err
+	}
+
+	c.w.Flush()
+
+	return c.w.Error()
+}
+
+func formatDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,93 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// jsonWriter writes statement object part by part, so operations are never collected in memory
+type jsonWriter struct {
+	w        *bufio.Writer
+	currency string
+	lines    int
+}
+
+type jsonHeader struct {
+	WalletID       string    `json:"wallet_id"`
+	Name           string    `json:"name"`
+	Currency       string    `json:"currency,omitempty"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	OpeningBalance float64   `json:"opening_balance"`
+}
+
+type jsonLine struct {
+	ID           string    `json:"id"`
+	Date         time.Time `json:"date"`
+	Type         string    `json:"type"`
+	Amount       float64   `json:"amount"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	Balance      float64   `json:"balance"`
+}
+
+func newJSONWriter(w io.Writer, currency string) *jsonWriter {
+	return &jsonWriter{w: bufio.NewWriter(w), currency: currency}
+}
+
+func (j *jsonWriter) Begin(header Header) error {
+	data, err := json.Marshal(jsonHeader{
+		WalletID:       header.WalletID,
+		Name:           header.Name,
+		Currency:       j.currency,
+		From:           header.From,
+		To:             header.To,
+		OpeningBalance: header.OpeningBalance,
+	})
+	if err != nil {
+		return err
+	}
+
+	//open header object and append operations array to it
+	j.w.Write(data[:len(data)-1])
+	_, err = j.w.WriteString(`,"operations":[`)
+
+	return err
+}
+
+func (j *jsonWriter) Line(line Line) error {
+	data, err := json.Marshal(jsonLine{
+		ID:           line.ID,
+		Date:         line.CreatedAt,
+		Type:         line.Type,
+		Amount:       line.Delta(),
+		Counterparty: line.Counterparty,
+		Balance:      line.Balance,
+	})
+	if err != nil {
+		return err
+	}
+
+	if j.lines > 0 {
+		j.w.WriteByte(',')
+	}
+	j.lines++
+
+	_, err = j.w.Write(data)
+
+	return err
+}
+
+func (j *jsonWriter) End(closingBalance float64) error {
+	data, err := json.Marshal(closingBalance)
+	if err != nil {
+		return err
+	}
+
+	j.w.WriteString(`],"closing_balance":`)
+	j.w.Write(data)
+	j.w.WriteString("}\n")
+
+	return j.w.Flush()
+}
@@ -0,0 +1,80 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"wallet/internal/storage"
+)
+
+// ofxWriter writes OFX 2.2 bank statement
+type ofxWriter struct {
+	w        *bufio.Writer
+	currency string
+	header   Header
+}
+
+func newOFXWriter(w io.Writer, currency string) *ofxWriter {
+	return &ofxWriter{w: bufio.NewWriter(w), currency: currency}
+}
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+func (o *ofxWriter) Begin(header Header) error {
+	o.header = header
+
+	o.w.WriteString(ofxHeader)
+	o.w.WriteString("<OFX>\n")
+	fmt.Fprintf(o.w, "<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>"+
+		"<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n", ofxTime(time.Now()))
+	o.w.WriteString("<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	fmt.Fprintf(o.w, "<STMTRS><CURDEF>%s</CURDEF>\n", escape(o.currency))
+	fmt.Fprintf(o.w, "<BANKACCTFROM><BANKID>WALLET</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n", escape(header.WalletID))
+	//OFX has no opening balance element, it is kept as a comment for readers
+	fmt.Fprintf(o.w, "<!-- opening balance %s -->\n", formatAmount(header.OpeningBalance))
+	_, err := fmt.Fprintf(o.w, "<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", ofxTime(header.From), ofxTime(header.To))
+
+	return err
+}
+
+func (o *ofxWriter) Line(line Line) error {
+	trnType := "CREDIT"
+	switch line.Type {
+	case storage.OpWithdraw:
+		trnType = "DEBIT"
+	case storage.OpTransferIn, storage.OpTransferOut:
+		trnType = "XFER"
+	}
+
+	fmt.Fprintf(o.w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID>",
+		trnType, ofxTime(line.CreatedAt), formatAmount(line.Delta()), escape(line.ID))
+	if line.Counterparty != "" {
+		fmt.Fprintf(o.w, "<NAME>%s</NAME>", escape(line.Counterparty))
+	}
+	_, err := fmt.Fprintf(o.w, "<MEMO>%s</MEMO></STMTTRN>\n", escape(line.Type))
+
+	return err
+}
+
+func (o *ofxWriter) End(closingBalance float64) error {
+	o.w.WriteString("</BANKTRANLIST>\n")
+	fmt.Fprintf(o.w, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", formatAmount(closingBalance), ofxTime(o.header.To))
+	o.w.WriteString("</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n</OFX>\n")
+
+	return o.w.Flush()
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+func escape(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"time"
+	"wallet/internal/domain"
+	"wallet/internal/storage"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatOFX  = "ofx"
+	FormatJSON = "json"
+)
+
+var ErrUnknownFormat = domain.New(domain.ErrInvalidArgument, "unknown_format", "unknown statement format")
+
+// Header opens a statement. Statement contains operations made after From up to and including To.
+type Header struct {
+	WalletID       string
+	Name           string
+	From           time.Time
+	To             time.Time
+	OpeningBalance float64
+}
+
+// Line is a statement operation with wallet balance after it
+type Line struct {
+	storage.Operation
+	Balance float64
+}
+
+// Writer streams statement: Begin once, Line for every operation and End with closing balance
+type Writer interface {
+	Begin(header Header) error
+	Line(line Line) error
+	End(closingBalance float64) error
+}
+
+// NewWriter creates statement writer for format. Amounts are denominated in currency.
+func NewWriter(format string, w io.Writer, currency string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatOFX:
+		return newOFXWriter(w, currency), nil
+	case FormatJSON:
+		return newJSONWriter(w, currency), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatOFX:
+		return "application/x-ofx"
+	default:
+		return "application/json"
+	}
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"wallet/internal/domain"
+)
+
+var ErrDeadlock = domain.New(domain.ErrConflict, "concurrent_update", "deadlock detected, retry the request")
+
+// lockTable holds exclusive locks of transactions on keys until commit or rollback.
+// A transaction waiting for a lock whose owner (transitively) waits for this transaction
+// gets ErrDeadlock, as postgres aborts one of deadlocked transactions.
+type lockTable struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	owners  map[string]*Tx
+	waiting map[*Tx]string
+}
+
+func newLockTable() *lockTable {
+	l := &lockTable{
+		owners:  map[string]*Tx{},
+		waiting: map[*Tx]string{},
+	}
+	l.cond = sync.NewCond(&l.mu)
+
+	return l
+}
+
+func (l *lockTable) acquire(ctx context.Context, tx *Tx, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	//wake up on context cancellation
+	stop := context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	})
+	defer stop()
+
+	for {
+		owner, locked := l.owners[key]
+		if !locked {
+			l.owners[key] = tx
+			tx.locks = append(tx.locks, key)
+			return nil
+		}
+		if owner == tx {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		for next := owner; next != nil; {
+			waitKey, ok := l.waiting[next]
+			if !ok {
+				break
+			}
+			if next = l.owners[waitKey]; next == tx {
+				return ErrDeadlock
+			}
+		}
+
+		l.waiting[tx] = key
+		l.cond.Wait()
+		delete(l.waiting, tx)
+	}
+}
+
+// release frees all locks of tx
+func (l *lockTable) release(tx *Tx) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range tx.locks {
+		if l.owners[key] == tx {
+			delete(l.owners, key)
+		}
+	}
+	tx.locks = nil
+
+	l.cond.Broadcast()
+}
@@ -0,0 +1,988 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wallet/internal/storage"
+	"wallet/internal/utils/random"
+)
+
+const ID_LENGTH = 16
+
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// snapshot is an immutable committed state. Commit publishes a new snapshot,
+// so readers never see changes of uncommitted transactions and never wait for writers.
+type snapshot struct {
+	wallets    map[string]*storage.Wallet
+	names      map[string]string //name -> wallet ID
+	operations []storage.Operation
+	reports    []storage.Reconciliation
+	seqs       map[string]int64 //wallet ID -> last event sequence number
+}
+
+// Storage keeps wallets in memory. It is meant for tests and demos, data is lost on Close.
+type Storage struct {
+	state    atomic.Pointer[snapshot]
+	commitMu sync.Mutex
+	locks    *lockTable
+
+	//webhooks are not a part of wallet transactions, so they are kept aside of snapshots
+	webhookMu  sync.Mutex
+	webhooks   map[string]storage.Webhook
+	deliveries map[string]storage.Delivery
+}
+
+func New() *Storage {
+	s := &Storage{
+		locks:      newLockTable(),
+		webhooks:   map[string]storage.Webhook{},
+		deliveries: map[string]storage.Delivery{},
+	}
+	s.state.Store(&snapshot{
+		wallets: map[string]*storage.Wallet{},
+		names:   map[string]string{},
+	})
+
+	return s
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+func (s *Storage) CreateWallet(ctx context.Context, name string) (string, error) {
+	const fn = "memory.CreateWallet"
+
+	wallet := &storage.Wallet{Name: name}
+
+	err := s.inTx(ctx, func(tx *Tx) error {
+		return tx.CreateWallet(ctx, wallet)
+	})
+	if errors.Is(err, storage.ErrWalletExists) {
+		return "", fmt.Errorf("%s: %w", fn, storage.ErrWalletExists)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s failed to create wallet: %w", fn, err)
+	}
+
+	return wallet.ID, nil
+}
+
+func (s *Storage) GetWallet(ctx context.Context, walletID string) (*storage.Wallet, error) {
+	const fn = "memory.GetWallet"
+
+	wallet, ok := s.state.Load().wallets[walletID]
+	if !ok {
+		return nil, fmt.Errorf("%s failed to get wallet: %w", fn, storage.ErrWalletNotExist)
+	}
+
+	return copyWallet(wallet), nil
+}
+
+func (s *Storage) ListWallets(ctx context.Context, query storage.WalletQuery) (*storage.WalletPage, error) {
+	const fn = "memory.ListWallets"
+
+	less, ok := sortFuncs[query.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown sort field %q", fn, query.SortBy)
+	}
+
+	var after *storage.Wallet
+	if query.Cursor != "" {
+		cursor, err := storage.DecodeCursor(query)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		after = &storage.Wallet{
+			ID:        cursor.ID,
+			Name:      cursor.Name,
+			Balance:   cursor.Balance,
+			CreatedAt: cursor.CreatedAt,
+		}
+	}
+
+	//before reports whether a goes first in requested order
+	before := func(a, b *storage.Wallet) bool {
+		if query.Desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	}
+
+	var matched []*storage.Wallet
+	for _, wallet := range s.state.Load().wallets {
+		if match(wallet, query.WalletFilter) {
+			matched = append(matched, wallet)
+		}
+	}
+
+	page := &storage.WalletPage{Wallets: []storage.Wallet{}}
+
+	if query.WithTotal {
+		total := len(matched)
+		page.Total = &total
+	}
+
+	sort.Slice(matched, func(i, j int) bool { return before(matched[i], matched[j]) })
+
+	for _, wallet := range matched {
+		if after != nil && !before(after, wallet) {
+			continue
+		}
+		if len(page.Wallets) == query.Limit {
+			page.NextCursor = storage.NewCursor(query, page.Wallets[query.Limit-1])
+			break
+		}
+
+		page.Wallets = append(page.Wallets, *copyWallet(wallet))
+	}
+
+	return page, nil
+}
+
+func (s *Storage) UpdateWallet(ctx context.Context, updatedWallet *storage.Wallet) (int64, error) {
+	const fn = "memory.UpdateWallet"
+
+	var rowsAffected int64
+
+	err := s.inTx(ctx, func(tx *Tx) (err error) {
+		rowsAffected, err = tx.UpdateWallet(ctx, updatedWallet)
+		return err
+	})
+	if errors.Is(err, storage.ErrWalletNotExist) {
+		return 0, storage.ErrWalletNotExist
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to update wallet: %w", fn, err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (s *Storage) DeactivateWallet(ctx context.Context, walletID string) (int64, error) {
+	const fn = "memory.DeactivateWallet"
+
+	err := s.inTx(ctx, func(tx *Tx) error {
+		wallet, err := tx.lockWallet(ctx, walletID)
+		if err != nil {
+			return err
+		}
+
+		wallet.Status = storage.StatusInactive
+		wallet.UpdatedAt = time.Now().UTC()
+
+		return nil
+	})
+	if errors.Is(err, storage.ErrWalletNotExist) {
+		return 0, storage.ErrWalletNotExist
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to deactivate wallet: %w", fn, err)
+	}
+
+	return 1, nil
+}
+
+func (s *Storage) BalanceAt(ctx context.Context, walletID string, at time.Time) (float64, error) {
+	const fn = "memory.BalanceAt"
+
+	state := s.state.Load()
+
+	wallet, ok := state.wallets[walletID]
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", fn, storage.ErrWalletNotExist)
+	}
+	if wallet.CreatedAt.After(at) {
+		return 0, fmt.Errorf("%s: wallet was created after %s: %w", fn, at.Format(time.RFC3339), storage.ErrWalletNotExist)
+	}
+
+	var balance float64
+	for _, op := range state.operations {
+		if op.WalletID == walletID && !op.CreatedAt.After(at) {
+			balance += op.Delta()
+		}
+	}
+
+	return balance, nil
+}
+
+func (s *Storage) BalancesAt(ctx context.Context, at time.Time) ([]storage.WalletBalance, error) {
+	state := s.state.Load()
+
+	sums := map[string]float64{}
+	for _, op := range state.operations {
+		if !op.CreatedAt.After(at) {
+			sums[op.WalletID] += op.Delta()
+		}
+	}
+
+	balances := []storage.WalletBalance{}
+	for _, wallet := range state.wallets {
+		if wallet.CreatedAt.After(at) {
+			continue
+		}
+
+		balances = append(balances, storage.WalletBalance{
+			WalletID: wallet.ID,
+			Name:     wallet.Name,
+			Balance:  sums[wallet.ID],
+			At:       at,
+		})
+	}
+
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Name < balances[j].Name })
+
+	return balances, nil
+}
+
+// WalkOperations calls visit for every operation matching filter in chronological order
+func (s *Storage) WalkOperations(ctx context.Context, filter storage.OperationFilter, visit func(op storage.Operation) error) error {
+	var operations []storage.Operation
+
+	for _, op := range s.state.Load().operations {
+		if filter.WalletID != "" && op.WalletID != filter.WalletID {
+			continue
+		}
+		if !filter.From.IsZero() && !op.CreatedAt.After(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && op.CreatedAt.After(filter.To) {
+			continue
+		}
+
+		operations = append(operations, op)
+	}
+
+	sort.SliceStable(operations, func(i, j int) bool { return operationLess(operations[i], operations[j]) })
+
+	for _, op := range operations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := visit(op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Ledger reads one snapshot, so balances and totals are consistent
+func (s *Storage) Ledger(ctx context.Context) (*storage.Ledger, error) {
+	state := s.state.Load()
+
+	ledger := &storage.Ledger{
+		Balances: []storage.LedgerBalance{},
+		Totals:   map[string]float64{},
+	}
+
+	journal := map[string]float64{}
+	for _, op := range state.operations {
+		journal[op.WalletID] += op.Delta()
+		ledger.Totals[op.Type] += op.Amount
+	}
+
+	for _, wallet := range state.wallets {
+		ledger.Balances = append(ledger.Balances, storage.LedgerBalance{
+			WalletID: wallet.ID,
+			Name:     wallet.Name,
+			Balance:  wallet.Balance,
+			Journal:  journal[wallet.ID],
+		})
+	}
+
+	sort.Slice(ledger.Balances, func(i, j int) bool { return ledger.Balances[i].WalletID < ledger.Balances[j].WalletID })
+
+	return ledger, nil
+}
+
+func (s *Storage) SaveReconciliation(ctx context.Context, report *storage.Reconciliation) error {
+	const fn = "memory.SaveReconciliation"
+
+	//reports are stored as JSON in other storages, the roundtrip gives the same copy semantics
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode report: %w", fn, err)
+	}
+
+	var saved storage.Reconciliation
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("%s failed to decode report: %w", fn, err)
+	}
+
+	s.commitMu.Lock()
+	defer s.commitMu.Unlock()
+
+	state := *s.state.Load()
+	state.reports = append(state.reports[:len(state.reports):len(state.reports)], saved)
+	s.state.Store(&state)
+
+	return nil
+}
+
+func (s *Storage) LatestReconciliation(ctx context.Context) (*storage.Reconciliation, error) {
+	var latest *storage.Reconciliation
+
+	reports := s.state.Load().reports
+	for i, report := range reports {
+		if latest == nil || report.FinishedAt.After(latest.FinishedAt) ||
+			report.FinishedAt.Equal(latest.FinishedAt) && report.ID > latest.ID {
+			latest = &reports[i]
+		}
+	}
+	if latest == nil {
+		return nil, storage.ErrReconciliationNotFound
+	}
+
+	report := *latest
+	report.Discrepancies = append([]storage.Discrepancy{}, latest.Discrepancies...)
+
+	return &report, nil
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	s.webhooks[webhook.ID] = *copyWebhook(*webhook)
+
+	return nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, webhookID string) (*storage.Webhook, error) {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	webhook, ok := s.webhooks[webhookID]
+	if !ok {
+		return nil, storage.ErrWebhookNotFound
+	}
+
+	return copyWebhook(webhook), nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	webhooks := make([]storage.Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		webhooks = append(webhooks, *copyWebhook(webhook))
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].ID < webhooks[j].ID
+	})
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes webhook along with its deliveries
+func (s *Storage) DeleteWebhook(ctx context.Context, webhookID string) error {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	if _, ok := s.webhooks[webhookID]; !ok {
+		return storage.ErrWebhookNotFound
+	}
+
+	delete(s.webhooks, webhookID)
+	for id, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID {
+			delete(s.deliveries, id)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveDelivery(ctx context.Context, delivery *storage.Delivery) error {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	if _, ok := s.webhooks[delivery.WebhookID]; !ok {
+		return storage.ErrWebhookNotFound
+	}
+
+	s.deliveries[delivery.ID] = *copyDelivery(*delivery)
+
+	return nil
+}
+
+func (s *Storage) GetDelivery(ctx context.Context, webhookID, deliveryID string) (*storage.Delivery, error) {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	delivery, ok := s.deliveries[deliveryID]
+	if !ok || delivery.WebhookID != webhookID {
+		return nil, storage.ErrDeliveryNotFound
+	}
+
+	return copyDelivery(delivery), nil
+}
+
+// ListDeliveries returns at most limit latest deliveries of webhook, newest first
+func (s *Storage) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]storage.Delivery, error) {
+	deliveries := s.findDeliveries(func(d *storage.Delivery) bool { return d.WebhookID == webhookID })
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+
+	return limitDeliveries(deliveries, limit), nil
+}
+
+// ClaimDeliveries returns at most limit pending deliveries due at the moment at, oldest first.
+// Their next attempt is moved to leaseUntil, so other dispatchers skip them till the lease expires.
+func (s *Storage) ClaimDeliveries(ctx context.Context, at, leaseUntil time.Time, limit int) ([]storage.Delivery, error) {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	deliveries := s.findDeliveriesLocked(func(d *storage.Delivery) bool {
+		return d.Status == storage.DeliveryPending && !d.NextAttemptAt.After(at)
+	})
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	deliveries = limitDeliveries(deliveries, limit)
+
+	for i := range deliveries {
+		deliveries[i].NextAttemptAt = leaseUntil
+		s.deliveries[deliveries[i].ID] = *copyDelivery(deliveries[i])
+	}
+
+	return deliveries, nil
+}
+
+// AddWebhookFailure counts a failed attempt of webhook and disables it after disableAfter failures in a row
+func (s *Storage) AddWebhookFailure(ctx context.Context, webhookID string, disableAfter int) (*storage.Webhook, error) {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	webhook, ok := s.webhooks[webhookID]
+	if !ok {
+		return nil, storage.ErrWebhookNotFound
+	}
+
+	webhook.Failures++
+	if disableAfter > 0 && webhook.Failures >= disableAfter {
+		webhook.Status = storage.WebhookDisabled
+	}
+	webhook.UpdatedAt = time.Now().UTC()
+	s.webhooks[webhookID] = webhook
+
+	return copyWebhook(webhook), nil
+}
+
+// ResetWebhookFailures forgets failed attempts of webhook after a successful one, missing webhook is ignored
+func (s *Storage) ResetWebhookFailures(ctx context.Context, webhookID string) error {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	webhook, ok := s.webhooks[webhookID]
+	if !ok || webhook.Failures == 0 {
+		return nil
+	}
+
+	webhook.Failures = 0
+	webhook.UpdatedAt = time.Now().UTC()
+	s.webhooks[webhookID] = webhook
+
+	return nil
+}
+
+func (s *Storage) findDeliveries(match func(d *storage.Delivery) bool) []storage.Delivery {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	return s.findDeliveriesLocked(match)
+}
+
+func (s *Storage) findDeliveriesLocked(match func(d *storage.Delivery) bool) []storage.Delivery {
+	deliveries := []storage.Delivery{}
+	for _, delivery := range s.deliveries {
+		if match(&delivery) {
+			deliveries = append(deliveries, *copyDelivery(delivery))
+		}
+	}
+
+	return deliveries
+}
+
+func limitDeliveries(deliveries []storage.Delivery, limit int) []storage.Delivery {
+	if limit > 0 && len(deliveries) > limit {
+		return deliveries[:limit]
+	}
+	return deliveries
+}
+
+func copyWebhook(webhook storage.Webhook) *storage.Webhook {
+	webhook.EventTypes = append([]string{}, webhook.EventTypes...)
+	webhook.WalletIDs = append([]string{}, webhook.WalletIDs...)
+	return &webhook
+}
+
+func copyDelivery(delivery storage.Delivery) *storage.Delivery {
+	delivery.Payload = append([]byte{}, delivery.Payload...)
+	return &delivery
+}
+
+func (s *Storage) NextEventSeq(ctx context.Context, walletID string) (int64, error) {
+	const fn = "memory.NextEventSeq"
+
+	var seq int64
+
+	err := s.inTx(ctx, func(tx *Tx) (err error) {
+		seq, err = tx.NextEventSeq(ctx, walletID)
+		return err
+	})
+	if errors.Is(err, storage.ErrWalletNotExist) {
+		return 0, storage.ErrWalletNotExist
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to advance event sequence: %w", fn, err)
+	}
+
+	return seq, nil
+}
+
+func (s *Storage) BeginTx(ctx context.Context) (storage.Transaction, error) {
+	return s.begin(), nil
+}
+
+func (s *Storage) begin() *Tx {
+	return &Tx{
+		storage: s,
+		wallets: map[string]*storage.Wallet{},
+	}
+}
+
+// inTx runs fn in a transaction which is committed when fn succeeds
+func (s *Storage) inTx(ctx context.Context, fn func(tx *Tx) error) error {
+	tx := s.begin()
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Tx collects changes in private copies of locked wallets. Wallets read by a transaction
+// stay locked until commit or rollback, like SELECT ... FOR UPDATE.
+type Tx struct {
+	storage    *Storage
+	wallets    map[string]*storage.Wallet //write set
+	created    []string                   //IDs of wallets created by transaction
+	operations []storage.Operation
+	seqs       map[string]int64 //advanced event sequences of locked wallets
+	locks      []string
+	done       bool
+}
+
+func (t *Tx) Commit() error {
+	if t.done {
+		return ErrTxDone
+	}
+	t.done = true
+	defer t.storage.locks.release(t)
+
+	t.storage.commitMu.Lock()
+	defer t.storage.commitMu.Unlock()
+
+	current := t.storage.state.Load()
+
+	next := &snapshot{
+		wallets:    make(map[string]*storage.Wallet, len(current.wallets)+len(t.created)),
+		names:      make(map[string]string, len(current.names)+len(t.created)),
+		operations: append(current.operations[:len(current.operations):len(current.operations)], t.operations...),
+		reports:    current.reports,
+		seqs:       current.seqs,
+	}
+	if len(t.seqs) > 0 {
+		next.seqs = make(map[string]int64, len(current.seqs)+len(t.seqs))
+		for id, seq := range current.seqs {
+			next.seqs[id] = seq
+		}
+		for id, seq := range t.seqs {
+			next.seqs[id] = seq
+		}
+	}
+	for id, wallet := range current.wallets {
+		next.wallets[id] = wallet
+	}
+	for name, id := range current.names {
+		next.names[name] = id
+	}
+
+	for id, wallet := range t.wallets {
+		if old, ok := current.wallets[id]; ok && old.Name != wallet.Name {
+			delete(next.names, old.Name)
+		}
+	}
+	for id, wallet := range t.wallets {
+		next.wallets[id] = wallet
+		next.names[wallet.Name] = id
+	}
+
+	t.storage.state.Store(next)
+
+	return nil
+}
+
+func (t *Tx) Rollback() error {
+	if t.done {
+		return ErrTxDone
+	}
+	t.done = true
+
+	t.storage.locks.release(t)
+
+	return nil
+}
+
+func (t *Tx) GetWallet(ctx context.Context, walletID string) (*storage.Wallet, error) {
+	wallet, err := t.lockWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyWallet(wallet), nil
+}
+
+func (t *Tx) CreateWallet(ctx context.Context, wallet *storage.Wallet) error {
+	const fn = "memory.CreateWallet"
+
+	if t.done {
+		return ErrTxDone
+	}
+
+	if err := t.lockName(ctx, wallet.Name, ""); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if wallet.ID == "" {
+		wallet.ID = random.NewRandomString(ID_LENGTH)
+	}
+	if wallet.Status == "" {
+		wallet.Status = storage.StatusActive
+	}
+	if wallet.CreatedAt.IsZero() {
+		wallet.CreatedAt = time.Now().UTC()
+	}
+	if wallet.UpdatedAt.IsZero() {
+		wallet.UpdatedAt = wallet.CreatedAt
+	}
+
+	if err := t.storage.locks.acquire(ctx, t, "wallet:"+wallet.ID); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if _, ok := t.storage.state.Load().wallets[wallet.ID]; ok {
+		return fmt.Errorf("%s: wallet %s: %w", fn, wallet.ID, storage.ErrWalletExists)
+	}
+
+	metadata, err := copyMetadata(wallet.Metadata)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode metadata: %w", fn, err)
+	}
+
+	created := copyWallet(wallet)
+	created.Metadata = metadata
+	created.Tags = normalizeTags(wallet.Tags)
+	created.CreatedAt = created.CreatedAt.UTC()
+	created.UpdatedAt = created.UpdatedAt.UTC()
+
+	t.wallets[created.ID] = created
+	t.created = append(t.created, created.ID)
+
+	return nil
+}
+
+func (t *Tx) UpdateWallet(ctx context.Context, updatedWallet *storage.Wallet) (int64, error) {
+	wallet, err := t.lockWallet(ctx, updatedWallet.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if updatedWallet.Name != wallet.Name {
+		if err := t.lockName(ctx, updatedWallet.Name, wallet.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	wallet.Name = updatedWallet.Name
+	wallet.Balance = updatedWallet.Balance
+	wallet.Status = updatedWallet.Status
+	wallet.UpdatedAt = time.Now().UTC()
+
+	return 1, nil
+}
+
+func (t *Tx) UpdateMetadata(ctx context.Context, walletID string, metadata map[string]any) error {
+	const fn = "memory.UpdateMetadata"
+
+	copied, err := copyMetadata(metadata)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode metadata: %w", fn, err)
+	}
+
+	wallet, err := t.lockWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	wallet.Metadata = copied
+	wallet.UpdatedAt = time.Now().UTC()
+
+	return nil
+}
+
+func (t *Tx) UpdateTags(ctx context.Context, walletID string, tags []string) error {
+	wallet, err := t.lockWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	wallet.Tags = normalizeTags(tags)
+	wallet.UpdatedAt = time.Now().UTC()
+
+	return nil
+}
+
+func (t *Tx) AddOperation(ctx context.Context, op *storage.Operation) error {
+	const fn = "memory.AddOperation"
+
+	if t.done {
+		return ErrTxDone
+	}
+
+	if _, ok := t.wallets[op.WalletID]; !ok {
+		if _, ok := t.storage.state.Load().wallets[op.WalletID]; !ok {
+			return fmt.Errorf("%s failed to insert operation: %w", fn, storage.ErrWalletNotExist)
+		}
+	}
+
+	if op.ID == "" {
+		op.ID = random.NewRandomString(ID_LENGTH)
+	}
+	if op.CreatedAt.IsZero() {
+		op.CreatedAt = time.Now().UTC()
+	}
+
+	saved := *op
+	saved.CreatedAt = saved.CreatedAt.UTC()
+	t.operations = append(t.operations, saved)
+
+	return nil
+}
+
+// NextEventSeq returns the next sequence number of wallet events, it is published on commit
+func (t *Tx) NextEventSeq(ctx context.Context, walletID string) (int64, error) {
+	//the wallet stays locked, so sequences are committed in order
+	if _, err := t.lockWallet(ctx, walletID); err != nil {
+		return 0, err
+	}
+
+	seq, ok := t.seqs[walletID]
+	if !ok {
+		seq = t.storage.state.Load().seqs[walletID]
+	}
+	seq++
+
+	if t.seqs == nil {
+		t.seqs = map[string]int64{}
+	}
+	t.seqs[walletID] = seq
+
+	return seq, nil
+}
+
+// lockWallet locks wallet and returns its private copy, changes of the copy are published on commit
+func (t *Tx) lockWallet(ctx context.Context, walletID string) (*storage.Wallet, error) {
+	if t.done {
+		return nil, ErrTxDone
+	}
+
+	if wallet, ok := t.wallets[walletID]; ok {
+		return wallet, nil
+	}
+
+	if err := t.storage.locks.acquire(ctx, t, "wallet:"+walletID); err != nil {
+		return nil, err
+	}
+
+	committed, ok := t.storage.state.Load().wallets[walletID]
+	if !ok {
+		return nil, storage.ErrWalletNotExist
+	}
+
+	wallet := copyWallet(committed)
+	t.wallets[walletID] = wallet
+
+	return wallet, nil
+}
+
+// lockName reserves wallet name until the end of transaction, so concurrent transactions can't take it
+func (t *Tx) lockName(ctx context.Context, name, walletID string) error {
+	if err := t.storage.locks.acquire(ctx, t, "name:"+name); err != nil {
+		return err
+	}
+
+	if id, ok := t.storage.state.Load().names[name]; ok && id != walletID {
+		if renamed, ok := t.wallets[id]; !ok || renamed.Name == name {
+			return storage.ErrWalletExists
+		}
+	}
+	for id, wallet := range t.wallets {
+		if wallet.Name == name && id != walletID {
+			return storage.ErrWalletExists
+		}
+	}
+
+	return nil
+}
+
+var sortFuncs = map[string]func(a, b *storage.Wallet) bool{
+	storage.SortByName: func(a, b *storage.Wallet) bool {
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	},
+	storage.SortByBalance: func(a, b *storage.Wallet) bool {
+		if a.Balance != b.Balance {
+			return a.Balance < b.Balance
+		}
+		return a.ID < b.ID
+	},
+	storage.SortByCreatedAt: func(a, b *storage.Wallet) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	},
+}
+
+func match(wallet *storage.Wallet, filter storage.WalletFilter) bool {
+	if len(filter.Names) > 0 && !contains(filter.Names, wallet.Name) {
+		return false
+	}
+	for _, tag := range filter.Tags {
+		if !contains(wallet.Tags, tag) {
+			return false
+		}
+	}
+	for key, value := range filter.Metadata {
+		if !matchMetadata(wallet.Metadata, key, value) {
+			return false
+		}
+	}
+	if filter.Status != "" && wallet.Status != filter.Status {
+		return false
+	}
+	if filter.MinBalance != nil && wallet.Balance < *filter.MinBalance {
+		return false
+	}
+	if filter.MaxBalance != nil && wallet.Balance > *filter.MaxBalance {
+		return false
+	}
+	if filter.NamePrefix != "" && !strings.HasPrefix(wallet.Name, filter.NamePrefix) {
+		return false
+	}
+
+	return true
+}
+
+// matchMetadata compares metadata value with JSON candidates of storage.MetadataValues
+func matchMetadata(metadata map[string]any, key, value string) bool {
+	actual, ok := metadata[key]
+	if !ok {
+		return false
+	}
+
+	for _, candidate := range storage.MetadataValues(value) {
+		var expected any
+		if err := json.Unmarshal([]byte(candidate), &expected); err == nil && reflect.DeepEqual(actual, expected) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func operationLess(a, b storage.Operation) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// normalizeTags sorts tags and removes duplicates, as tag table of SQL storages does
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+
+	return normalized
+}
+
+func copyWallet(wallet *storage.Wallet) *storage.Wallet {
+	copied := *wallet
+	copied.Metadata, _ = copyMetadata(wallet.Metadata) //stored metadata is always encodable
+	copied.Tags = append([]string{}, wallet.Tags...)
+
+	return &copied
+}
+
+// copyMetadata makes a deep copy through JSON, values get the same types as in SQL storages
+func copyMetadata(metadata map[string]any) (map[string]any, error) {
+	copied := map[string]any{}
+	if len(metadata) == 0 {
+		return copied, nil
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+
+	return copied, nil
+}
@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"testing"
+	"wallet/internal/storage"
+	"wallet/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return New()
+	})
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_wallet_name_pattern;
+DROP INDEX IF EXISTS idx_wallet_status_balance;
+DROP INDEX IF EXISTS idx_wallet_metadata;
+DROP TABLE IF EXISTS wallet_tag;
+ALTER TABLE wallet DROP COLUMN IF EXISTS metadata;
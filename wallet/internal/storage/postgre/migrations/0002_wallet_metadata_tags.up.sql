@@ -0,0 +1,11 @@
+ALTER TABLE wallet ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE TABLE IF NOT EXISTS wallet_tag(
+	wallet_id TEXT NOT NULL REFERENCES wallet(id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (wallet_id, tag));
+
+CREATE INDEX IF NOT EXISTS idx_wallet_tag_tag ON wallet_tag(tag, wallet_id);
+CREATE INDEX IF NOT EXISTS idx_wallet_metadata ON wallet USING GIN (metadata jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_wallet_status_balance ON wallet(status, balance);
+CREATE INDEX IF NOT EXISTS idx_wallet_name_pattern ON wallet(name text_pattern_ops);
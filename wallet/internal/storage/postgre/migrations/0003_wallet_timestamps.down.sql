@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_wallet_balance;
+DROP INDEX IF EXISTS idx_wallet_created_at;
+ALTER TABLE wallet DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE wallet DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,5 @@
+ALTER TABLE wallet ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+ALTER TABLE wallet ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_wallet_created_at ON wallet(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_wallet_balance ON wallet(balance, id);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS operation(
+	id TEXT PRIMARY KEY,
+	wallet_id TEXT NOT NULL REFERENCES wallet(id),
+	type VARCHAR(24) NOT NULL,
+	amount FLOAT NOT NULL,
+	counterparty TEXT,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW());
+
+CREATE INDEX IF NOT EXISTS idx_operation_wallet_created_at ON operation(wallet_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_operation_created_at ON operation(created_at);
+
+-- кошельки, созданные до журнала, получают начальный остаток на дату создания
+INSERT INTO operation(id, wallet_id, type, amount, created_at)
+SELECT substr(md5(random()::text || wallet.id), 1, 16), wallet.id, 'opening_balance', wallet.balance, wallet.created_at
+FROM wallet
+WHERE wallet.balance <> 0
+	AND NOT EXISTS (SELECT 1 FROM operation WHERE operation.wallet_id = wallet.id);
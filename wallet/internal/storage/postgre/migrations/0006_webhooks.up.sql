@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS webhook(
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	webhook JSONB NOT NULL);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery(
+	id TEXT PRIMARY KEY,
+	webhook_id TEXT NOT NULL REFERENCES webhook(id) ON DELETE CASCADE,
+	status TEXT NOT NULL,
+	next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	delivery JSONB NOT NULL);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_webhook_id ON webhook_delivery(webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_due ON webhook_delivery(next_attempt_at) WHERE status = 'pending';
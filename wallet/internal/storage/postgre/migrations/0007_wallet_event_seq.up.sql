@@ -0,0 +1,2 @@
+-- последний номер события кошелька, консьюмеры по нему находят пропуски и перестановки
+ALTER TABLE wallet ADD COLUMN IF NOT EXISTS event_seq BIGINT NOT NULL DEFAULT 0;
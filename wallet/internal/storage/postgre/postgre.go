@@ -3,14 +3,19 @@ package postgre
 import (
 	"context"
 	"database/sql"
+	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"migrate"
+	"strings"
 	"time"
+	"wallet/internal/domain"
 	"wallet/internal/storage"
 	"wallet/internal/utils/random"
 
-	_ "github.com/lib/pq"
-	"github.com/mattn/go-sqlite3"
+	"github.com/lib/pq"
 )
 
 type Storage struct {
@@ -19,23 +24,75 @@ type Storage struct {
 
 const ID_LENGTH = 16
 
-func New(dbhost string, dbport int) (*Storage, error) {
-	const fn = "storage.postgre.New"
-	const (
-		user     = "wallets_admin"
-		password = "admin"
-		dbname   = "wallets_db"
-	)
+// postgres error codes translated to storage errors
+const (
+	uniqueViolation      = "23505"
+	foreignKeyViolation  = "23503"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	lockNotAvailable     = "55P03"
+)
+
+// translateError converts driver error to storage error, other errors are returned as is
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
 
-	connStr := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		dbhost, dbport, user, password, dbname,
+	switch pqErr.Code {
+	case uniqueViolation:
+		return domain.Wrap(storage.ErrWalletExists, err)
+	case foreignKeyViolation:
+		return domain.Wrap(storage.ErrWalletNotExist, err)
+	case serializationFailure, deadlockDetected, lockNotAvailable:
+		return domain.Wrap(storage.ErrConflict, err)
+	}
+
+	return err
+}
+
+// walletColumns is a select list for scanWallet
+const walletColumns = `w.id, w.name, w.balance, w.status, w.metadata, w.created_at, w.updated_at,
+	(SELECT COALESCE(json_agg(t.tag ORDER BY t.tag), '[]'::json) FROM wallet_tag t WHERE t.wallet_id = w.id)`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWallet(row rowScanner) (*storage.Wallet, error) {
+	var (
+		wallet   storage.Wallet
+		metadata []byte
+		tags     []byte
 	)
 
+	if err := row.Scan(&wallet.ID, &wallet.Name, &wallet.Balance, &wallet.Status, &metadata, &wallet.CreatedAt, &wallet.UpdatedAt, &tags); err != nil {
+		return nil, err
+	}
+
+	wallet.CreatedAt = wallet.CreatedAt.UTC()
+	wallet.UpdatedAt = wallet.UpdatedAt.UTC()
+
+	if err := json.Unmarshal(metadata, &wallet.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if err := json.Unmarshal(tags, &wallet.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+
+	return &wallet, nil
+}
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// New connects to postgres by dsn: "host=localhost port=5432 user=... dbname=..." or postgres:// URL
+func New(dsn string) (*Storage, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open db: %w", err)
 	}
@@ -44,24 +101,6 @@ func New(dbhost string, dbport int) (*Storage, error) {
 		return nil, fmt.Errorf("failed to ping db: %w", err)
 	}
 
-	stmt, err := db.Prepare(`
-		CREATE TABLE IF NOT EXISTS wallet(
-			id TEXT PRIMARY KEY,
-			name TEXT NOT NULL UNIQUE,
-			balance FLOAT DEFAULT 0.0,
-			status TEXT DEFAULT 'active');
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("%s:%w", fn, err)
-	}
-
-	defer stmt.Close()
-
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("%s:%w", fn, err)
-	}
-
 	return &Storage{db: db}, nil
 }
 
@@ -69,10 +108,20 @@ func (s *Storage) Close() error {
 	return s.db.Close()
 }
 
+// Migrator applies versioned schema migrations of postgres dialect
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	scripts, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(s.db, migrate.Postgres, scripts, storage.Migrations)
+}
+
 func (s *Storage) CreateWallet(ctx context.Context, name string) (string, error) {
 	const fn = "postgre.CreateWallet"
 
-	stmt, err := s.db.Prepare(`INSERT INTO wallet(id, name) VALUES($1, $2)`)
+	stmt, err := s.db.Prepare(`INSERT INTO wallet(id, name, created_at, updated_at) VALUES($1, $2, $3, $3)`)
 	if err != nil {
 		return "", fmt.Errorf("%s failed to prepare query for creating wallet: %w", fn, err)
 	}
@@ -81,11 +130,9 @@ func (s *Storage) CreateWallet(ctx context.Context, name string) (string, error)
 
 	walletID := random.NewRandomString(ID_LENGTH)
 
-	_, err = stmt.ExecContext(ctx, walletID, name)
+	_, err = stmt.ExecContext(ctx, walletID, name, time.Now().UTC())
 	if err != nil {
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return "", fmt.Errorf("%s: %w", fn, storage.ErrWalletExists)
-		}
+		return "", fmt.Errorf("%s failed to create wallet: %w", fn, translateError(err))
 	}
 
 	return walletID, nil
@@ -94,70 +141,177 @@ func (s *Storage) CreateWallet(ctx context.Context, name string) (string, error)
 func (s *Storage) GetWallet(ctx context.Context, walletID string) (*storage.Wallet, error) {
 	const fn = "postgre.GetWallet"
 
-	stmt, err := s.db.Prepare(`SELECT id, name, balance, status FROM wallet WHERE id = $1`)
+	stmt, err := s.db.Prepare(`SELECT ` + walletColumns + ` FROM wallet w WHERE w.id = $1`)
 	if err != nil {
 		return nil, fmt.Errorf("%s failed to prepare query for get wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	var wallet storage.Wallet
-
-	err = stmt.QueryRowContext(ctx, walletID).Scan(&wallet.ID, &wallet.Name, &wallet.Balance, &wallet.Status)
+	wallet, err := scanWallet(stmt.QueryRowContext(ctx, walletID))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("%s failed to get wallet: %w", fn, storage.ErrWalletNotExist)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to get wallet: %w", fn, translateError(err))
+	}
 
-	return &wallet, nil
+	return wallet, nil
 }
 
-func (s *Storage) GetWallets(ctx context.Context) ([]storage.Wallet, error) {
-	const fn = "postgre.GetWallets"
+func (s *Storage) ListWallets(ctx context.Context, query storage.WalletQuery) (*storage.WalletPage, error) {
+	const fn = "postgre.ListWallets"
 
-	stmt, err := s.db.Prepare(`SELECT id, name, balance, status FROM wallet`)
-	if err != nil {
-		return nil, fmt.Errorf("%s failed to prepare query for get wallets: %w", fn, err)
+	sortColumn, ok := sortColumns[query.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown sort field %q", fn, query.SortBy)
 	}
 
-	defer stmt.Close()
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds := filterConditions(query.WalletFilter, arg)
+	page := &storage.WalletPage{Wallets: []storage.Wallet{}}
+
+	if query.WithTotal {
+		var total int
+
+		countQuery := `SELECT COUNT(*) FROM wallet w` + where(conds)
+		if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, fmt.Errorf("%s failed to count wallets: %w", fn, err)
+		}
+
+		page.Total = &total
+	}
+
+	direction, compare := "ASC", ">"
+	if query.Desc {
+		direction, compare = "DESC", "<"
+	}
 
-	rows, err := stmt.QueryContext(ctx)
+	if query.Cursor != "" {
+		cursor, err := storage.DecodeCursor(query)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		var value any
+		switch query.SortBy {
+		case storage.SortByName:
+			value = cursor.Name
+		case storage.SortByBalance:
+			value = cursor.Balance
+		case storage.SortByCreatedAt:
+			value = cursor.CreatedAt
+		}
+
+		conds = append(conds, fmt.Sprintf("(%s, w.id) %s (%s, %s)", sortColumn, compare, arg(value), arg(cursor.ID)))
+	}
+
+	listQuery := `SELECT ` + walletColumns + ` FROM wallet w` + where(conds) +
+		fmt.Sprintf(" ORDER BY %s %s, w.id %s LIMIT %s", sortColumn, direction, direction, arg(query.Limit+1))
+
+	rows, err := s.db.QueryContext(ctx, listQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	var wallets []storage.Wallet
+	defer rows.Close()
 
 	for rows.Next() {
-		var wallet storage.Wallet
-
-		if err := rows.Scan(&wallet.ID, &wallet.Name, &wallet.Balance, &wallet.Status); err != nil {
+		wallet, err := scanWallet(rows)
+		if err != nil {
 			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
 		}
 
-		wallets = append(wallets, wallet)
+		page.Wallets = append(page.Wallets, *wallet)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error during rows iteration: %v", err)
 	}
 
-	return wallets, nil
+	if len(page.Wallets) > query.Limit {
+		page.Wallets = page.Wallets[:query.Limit]
+		page.NextCursor = storage.NewCursor(query, page.Wallets[query.Limit-1])
+	}
+
+	return page, nil
+}
+
+var sortColumns = map[string]string{
+	storage.SortByName:      "w.name",
+	storage.SortByBalance:   "w.balance",
+	storage.SortByCreatedAt: "w.created_at",
+}
+
+// filterConditions builds WHERE conditions for filter, arg registers query argument and returns its placeholder
+func filterConditions(filter storage.WalletFilter, arg func(v any) string) []string {
+	var conds []string
+
+	if len(filter.Names) > 0 {
+		placeholders := make([]string, len(filter.Names))
+		for i, name := range filter.Names {
+			placeholders[i] = arg(name)
+		}
+		conds = append(conds, "w.name IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	for _, tag := range filter.Tags {
+		conds = append(conds, "EXISTS (SELECT 1 FROM wallet_tag t WHERE t.wallet_id = w.id AND t.tag = "+arg(tag)+")")
+	}
+	for key, value := range filter.Metadata {
+		var variants []string
+		for _, v := range storage.MetadataValues(value) {
+			variants = append(variants, "w.metadata @> jsonb_build_object("+arg(key)+"::text, "+arg(v)+"::jsonb)")
+		}
+		conds = append(conds, "("+strings.Join(variants, " OR ")+")")
+	}
+	if filter.Status != "" {
+		conds = append(conds, "w.status = "+arg(filter.Status))
+	}
+	if filter.MinBalance != nil {
+		conds = append(conds, "w.balance >= "+arg(*filter.MinBalance))
+	}
+	if filter.MaxBalance != nil {
+		conds = append(conds, "w.balance <= "+arg(*filter.MaxBalance))
+	}
+	if filter.NamePrefix != "" {
+		conds = append(conds, "w.name LIKE "+arg(likePrefix(filter.NamePrefix))+" ESCAPE '\\'")
+	}
+
+	return conds
+}
+
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// likePrefix escapes LIKE wildcards in prefix
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
 }
 
 func (s *Storage) UpdateWallet(ctx context.Context, updatedWallet *storage.Wallet) (int64, error) {
 	const fn = "postgre.UpdateWallet"
 
-	stmt, err := s.db.Prepare(`UPDATE wallet SET name = $1, balance = $2, status = $3 WHERE id = $4`)
+	stmt, err := s.db.Prepare(`UPDATE wallet SET name = $1, balance = $2, status = $3, updated_at = $4 WHERE id = $5`)
 	if err != nil {
 		return 0, fmt.Errorf("%s failed to prepare query for update wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, updatedWallet.Name, updatedWallet.Balance, updatedWallet.Status, updatedWallet.ID)
+	res, err := stmt.ExecContext(ctx, updatedWallet.Name, updatedWallet.Balance, updatedWallet.Status, time.Now().UTC(), updatedWallet.ID)
 	if err != nil {
-		return 0, fmt.Errorf("%s failed to update wallet: %w", fn, err)
+		return 0, fmt.Errorf("%s failed to update wallet: %w", fn, translateError(err))
 	}
 
 	rowsAffected, err := res.RowsAffected()
@@ -175,16 +329,16 @@ func (s *Storage) UpdateWallet(ctx context.Context, updatedWallet *storage.Walle
 func (s *Storage) DeactivateWallet(ctx context.Context, walletID string) (int64, error) {
 	const fn = "postgre.DeactivateWallet"
 
-	stmt, err := s.db.Prepare(`UPDATE wallet SET status = 'inactive' WHERE id = $1`)
+	stmt, err := s.db.Prepare(`UPDATE wallet SET status = 'inactive', updated_at = $1 WHERE id = $2`)
 	if err != nil {
 		return 0, fmt.Errorf("%s failed to prepare query for deactivate wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, walletID)
+	res, err := stmt.ExecContext(ctx, time.Now().UTC(), walletID)
 	if err != nil {
-		return 0, fmt.Errorf("%s failed to deactivate wallet: %w", fn, err)
+		return 0, fmt.Errorf("%s failed to deactivate wallet: %w", fn, translateError(err))
 	}
 
 	rowsAffected, err := res.RowsAffected()
@@ -199,12 +353,528 @@ func (s *Storage) DeactivateWallet(ctx context.Context, walletID string) (int64,
 	return rowsAffected, nil
 }
 
+// deltaExpr is a signed balance change of operation o
+const deltaExpr = `CASE WHEN o.type IN ('withdraw', 'transfer_out') THEN -o.amount ELSE o.amount END`
+
+func (s *Storage) BalanceAt(ctx context.Context, walletID string, at time.Time) (float64, error) {
+	const fn = "postgre.BalanceAt"
+
+	var createdAt time.Time
+
+	err := s.db.QueryRowContext(ctx, `SELECT created_at FROM wallet WHERE id = $1`, walletID).Scan(&createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", fn, storage.ErrWalletNotExist)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to get wallet: %w", fn, translateError(err))
+	}
+	if createdAt.After(at) {
+		return 0, fmt.Errorf("%s: wallet was created after %s: %w", fn, at.Format(time.RFC3339), storage.ErrWalletNotExist)
+	}
+
+	var balance float64
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(`+deltaExpr+`), 0)
+		FROM operation o
+		WHERE o.wallet_id = $1 AND o.created_at <= $2`,
+		walletID, at,
+	).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to sum operations: %w", fn, err)
+	}
+
+	return balance, nil
+}
+
+func (s *Storage) BalancesAt(ctx context.Context, at time.Time) ([]storage.WalletBalance, error) {
+	const fn = "postgre.BalancesAt"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT w.id, w.name, COALESCE(SUM(`+deltaExpr+`), 0)
+		FROM wallet w
+		LEFT JOIN operation o ON o.wallet_id = w.id AND o.created_at <= $1
+		WHERE w.created_at <= $1
+		GROUP BY w.id, w.name
+		ORDER BY w.name`,
+		at,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	defer rows.Close()
+
+	balances := []storage.WalletBalance{}
+
+	for rows.Next() {
+		balance := storage.WalletBalance{At: at}
+
+		if err := rows.Scan(&balance.WalletID, &balance.Name, &balance.Balance); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+
+		balances = append(balances, balance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return balances, nil
+}
+
+// WalkOperations calls visit for every operation matching filter in chronological order.
+// Rows are read one by one, so any range can be walked without loading it into memory.
+func (s *Storage) WalkOperations(ctx context.Context, filter storage.OperationFilter, visit func(op storage.Operation) error) error {
+	const fn = "postgre.WalkOperations"
+
+	var (
+		conds []string
+		args  []any
+	)
+
+	if filter.WalletID != "" {
+		args = append(args, filter.WalletID)
+		conds = append(conds, fmt.Sprintf("o.wallet_id = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conds = append(conds, fmt.Sprintf("o.created_at > $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conds = append(conds, fmt.Sprintf("o.created_at <= $%d", len(args)))
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.id, o.wallet_id, o.type, o.amount, COALESCE(o.counterparty, ''), o.created_at
+		FROM operation o`+where(conds)+`
+		ORDER BY o.created_at, o.id`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var op storage.Operation
+
+		if err := rows.Scan(&op.ID, &op.WalletID, &op.Type, &op.Amount, &op.Counterparty, &op.CreatedAt); err != nil {
+			return fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+		op.CreatedAt = op.CreatedAt.UTC()
+
+		if err := visit(op); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return nil
+}
+
+// Ledger reads stored balances and journal totals in one read-only transaction,
+// so both parts of the snapshot are consistent with each other.
+func (s *Storage) Ledger(ctx context.Context) (*storage.Ledger, error) {
+	const fn = "postgre.Ledger"
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to begin transaction: %w", fn, translateError(err))
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT w.id, w.name, w.balance, COALESCE(SUM(`+deltaExpr+`), 0)
+		FROM wallet w
+		LEFT JOIN operation o ON o.wallet_id = w.id
+		GROUP BY w.id, w.name, w.balance
+		ORDER BY w.id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	defer rows.Close()
+
+	ledger := &storage.Ledger{
+		Balances: []storage.LedgerBalance{},
+		Totals:   map[string]float64{},
+	}
+
+	for rows.Next() {
+		var balance storage.LedgerBalance
+
+		if err := rows.Scan(&balance.WalletID, &balance.Name, &balance.Balance, &balance.Journal); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+
+		ledger.Balances = append(ledger.Balances, balance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	totals, err := tx.QueryContext(ctx, `SELECT type, SUM(amount) FROM operation GROUP BY type`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	defer totals.Close()
+
+	for totals.Next() {
+		var (
+			opType string
+			amount float64
+		)
+
+		if err := totals.Scan(&opType, &amount); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+
+		ledger.Totals[opType] = amount
+	}
+
+	if err := totals.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return ledger, nil
+}
+
+func (s *Storage) SaveReconciliation(ctx context.Context, report *storage.Reconciliation) error {
+	const fn = "postgre.SaveReconciliation"
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode report: %w", fn, err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO reconciliation (id, created_at, ok, report) VALUES ($1, $2, $3, $4)`,
+		report.ID, report.FinishedAt, report.OK, data,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) LatestReconciliation(ctx context.Context) (*storage.Reconciliation, error) {
+	const fn = "postgre.LatestReconciliation"
+
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT report FROM reconciliation ORDER BY created_at DESC, id DESC LIMIT 1`,
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrReconciliationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var report storage.Reconciliation
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s failed to decode report: %w", fn, err)
+	}
+
+	return &report, nil
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	const fn = "postgre.SaveWebhook"
+
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode webhook: %w", fn, err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO webhook (id, created_at, webhook) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET webhook = EXCLUDED.webhook`,
+		webhook.ID, webhook.CreatedAt, data,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, webhookID string) (*storage.Webhook, error) {
+	const fn = "postgre.GetWebhook"
+
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx, `SELECT webhook FROM webhook WHERE id = $1`, webhookID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var webhook storage.Webhook
+	if err := json.Unmarshal(data, &webhook); err != nil {
+		return nil, fmt.Errorf("%s failed to decode webhook: %w", fn, err)
+	}
+
+	return &webhook, nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	const fn = "postgre.ListWebhooks"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT webhook FROM webhook ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	webhooks := []storage.Webhook{}
+	for rows.Next() {
+		var (
+			data    []byte
+			webhook storage.Webhook
+		)
+
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+		if err := json.Unmarshal(data, &webhook); err != nil {
+			return nil, fmt.Errorf("%s failed to decode webhook: %w", fn, err)
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes webhook along with its deliveries
+func (s *Storage) DeleteWebhook(ctx context.Context, webhookID string) error {
+	const fn = "postgre.DeleteWebhook"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook WHERE id = $1`, webhookID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if deleted == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveDelivery(ctx context.Context, delivery *storage.Delivery) error {
+	const fn = "postgre.SaveDelivery"
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode delivery: %w", fn, err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO webhook_delivery (id, webhook_id, status, next_attempt_at, created_at, delivery)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			status = EXCLUDED.status,
+			next_attempt_at = EXCLUDED.next_attempt_at,
+			delivery = EXCLUDED.delivery`,
+		delivery.ID, delivery.WebhookID, delivery.Status, delivery.NextAttemptAt, delivery.CreatedAt, data,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return storage.ErrWebhookNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetDelivery(ctx context.Context, webhookID, deliveryID string) (*storage.Delivery, error) {
+	const fn = "postgre.GetDelivery"
+
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT delivery FROM webhook_delivery WHERE id = $1 AND webhook_id = $2`,
+		deliveryID, webhookID,
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var delivery storage.Delivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, fmt.Errorf("%s failed to decode delivery: %w", fn, err)
+	}
+
+	return &delivery, nil
+}
+
+// ListDeliveries returns at most limit latest deliveries of webhook, newest first
+func (s *Storage) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]storage.Delivery, error) {
+	const fn = "postgre.ListDeliveries"
+
+	return s.queryDeliveries(ctx, fn,
+		`SELECT delivery FROM webhook_delivery WHERE webhook_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`,
+		webhookID, limit,
+	)
+}
+
+// ClaimDeliveries returns at most limit pending deliveries due at the moment at, oldest first.
+// Their next attempt is moved to leaseUntil, so other instances skip them till the lease expires.
+func (s *Storage) ClaimDeliveries(ctx context.Context, at, leaseUntil time.Time, limit int) ([]storage.Delivery, error) {
+	const fn = "postgre.ClaimDeliveries"
+
+	return s.queryDeliveries(ctx, fn,
+		`WITH due AS (
+			SELECT id FROM webhook_delivery WHERE status = $1 AND next_attempt_at <= $2
+			ORDER BY next_attempt_at, id LIMIT $4
+			FOR UPDATE SKIP LOCKED),
+		claimed AS (
+			UPDATE webhook_delivery SET
+				next_attempt_at = $3,
+				delivery = jsonb_set(delivery, '{next_attempt_at}', to_jsonb($3::timestamptz))
+			FROM due WHERE webhook_delivery.id = due.id
+			RETURNING webhook_delivery.id, webhook_delivery.created_at, webhook_delivery.delivery)
+		SELECT delivery FROM claimed ORDER BY created_at, id`,
+		storage.DeliveryPending, at, leaseUntil, limit,
+	)
+}
+
+// AddWebhookFailure counts a failed attempt of webhook and disables it after disableAfter failures in a row
+func (s *Storage) AddWebhookFailure(ctx context.Context, webhookID string, disableAfter int) (*storage.Webhook, error) {
+	const fn = "postgre.AddWebhookFailure"
+
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE webhook SET webhook = webhook || jsonb_build_object(
+			'failures', (webhook->>'failures')::int + 1,
+			'status', CASE WHEN $2::int > 0 AND (webhook->>'failures')::int + 1 >= $2::int THEN $3 ELSE webhook->>'status' END,
+			'updated_at', $4::timestamptz)
+		WHERE id = $1
+		RETURNING webhook`,
+		webhookID, disableAfter, storage.WebhookDisabled, time.Now().UTC(),
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var webhook storage.Webhook
+	if err := json.Unmarshal(data, &webhook); err != nil {
+		return nil, fmt.Errorf("%s failed to decode webhook: %w", fn, err)
+	}
+
+	return &webhook, nil
+}
+
+// ResetWebhookFailures forgets failed attempts of webhook after a successful one, missing webhook is ignored
+func (s *Storage) ResetWebhookFailures(ctx context.Context, webhookID string) error {
+	const fn = "postgre.ResetWebhookFailures"
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE webhook SET webhook = webhook || jsonb_build_object('failures', 0, 'updated_at', $2::timestamptz)
+		WHERE id = $1 AND (webhook->>'failures')::int <> 0`,
+		webhookID, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) queryDeliveries(ctx context.Context, fn, query string, args ...any) ([]storage.Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	deliveries := []storage.Delivery{}
+	for rows.Next() {
+		var (
+			data     []byte
+			delivery storage.Delivery
+		)
+
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+		if err := json.Unmarshal(data, &delivery); err != nil {
+			return nil, fmt.Errorf("%s failed to decode delivery: %w", fn, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) NextEventSeq(ctx context.Context, walletID string) (int64, error) {
+	return nextEventSeq(ctx, s.db, walletID)
+}
+
+// rowQuerier is *sql.DB or *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func nextEventSeq(ctx context.Context, q rowQuerier, walletID string) (int64, error) {
+	const fn = "postgre.NextEventSeq"
+
+	var seq int64
+	err := q.QueryRowContext(ctx, `UPDATE wallet SET event_seq = event_seq + 1 WHERE id = $1 RETURNING event_seq`, walletID).Scan(&seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrWalletNotExist
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to advance event sequence: %w", fn, translateError(err))
+	}
+
+	return seq, nil
+}
+
 func (s *Storage) BeginTx(ctx context.Context) (storage.Transaction, error) {
 	const fn = "postgre.BeginTx"
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%s failed to begin transaction: %w", fn, err)
+		return nil, fmt.Errorf("%s failed to begin transaction: %w", fn, translateError(err))
 	}
 
 	return &PostgreTx{tx: tx}, nil
@@ -215,7 +885,7 @@ type PostgreTx struct {
 }
 
 func (t *PostgreTx) Commit() error {
-	return t.tx.Commit()
+	return translateError(t.tx.Commit())
 }
 
 func (t *PostgreTx) Rollback() error {
@@ -225,39 +895,41 @@ func (t *PostgreTx) Rollback() error {
 func (t *PostgreTx) GetWallet(ctx context.Context, walletID string) (*storage.Wallet, error) {
 	const fn = "postgre.GetWallet"
 
-	stmt, err := t.tx.Prepare(`SELECT id, name, balance, status FROM wallet WHERE id = $1`)
+	//row stays locked until the end of transaction, concurrent updates of the wallet wait for it
+	stmt, err := t.tx.Prepare(`SELECT ` + walletColumns + ` FROM wallet w WHERE w.id = $1 FOR UPDATE`)
 	if err != nil {
 		return nil, fmt.Errorf("%s failed to prepare query for get wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	var wallet storage.Wallet
-
-	err = stmt.QueryRowContext(ctx, walletID).Scan(&wallet.ID, &wallet.Name, &wallet.Balance, &wallet.Status)
+	wallet, err := scanWallet(stmt.QueryRowContext(ctx, walletID))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, storage.ErrWalletNotExist
 	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to get wallet: %w", fn, translateError(err))
+	}
 
-	return &wallet, nil
+	return wallet, nil
 }
 
 func (t *PostgreTx) UpdateWallet(ctx context.Context, updatedWallet *storage.Wallet) (int64, error) {
 	const fn = "postgre.UpdateWallet"
 
-	stmt, err := t.tx.Prepare(`UPDATE wallet SET name = $1, balance = $2, status = $3 WHERE id = $4`)
+	stmt, err := t.tx.Prepare(`UPDATE wallet SET name = $1, balance = $2, status = $3, updated_at = $4 WHERE id = $5`)
 	if err != nil {
 		return 0, fmt.Errorf("%s failed to prepare query for update wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, updatedWallet.Name, updatedWallet.Balance, updatedWallet.Status, updatedWallet.ID)
+	res, err := stmt.ExecContext(ctx, updatedWallet.Name, updatedWallet.Balance, updatedWallet.Status, time.Now().UTC(), updatedWallet.ID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return 0, storage.ErrWalletNotExist
 	}
 	if err != nil {
-		return 0, fmt.Errorf("%s failed to update wallet: %w", fn, err)
+		return 0, fmt.Errorf("%s failed to update wallet: %w", fn, translateError(err))
 	}
 
 	rowsAffected, err := res.RowsAffected()
@@ -271,3 +943,140 @@ func (t *PostgreTx) UpdateWallet(ctx context.Context, updatedWallet *storage.Wal
 
 	return rowsAffected, nil
 }
+
+func (t *PostgreTx) UpdateMetadata(ctx context.Context, walletID string, metadata map[string]any) error {
+	const fn = "postgre.UpdateMetadata"
+
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode metadata: %w", fn, err)
+	}
+
+	res, err := t.tx.ExecContext(ctx, `UPDATE wallet SET metadata = $1, updated_at = $2 WHERE id = $3`, data, time.Now().UTC(), walletID)
+	if err != nil {
+		return fmt.Errorf("%s failed to update metadata: %w", fn, translateError(err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s failed to get affected rows: %w", fn, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrWalletNotExist
+	}
+
+	return nil
+}
+
+func (t *PostgreTx) UpdateTags(ctx context.Context, walletID string, tags []string) error {
+	const fn = "postgre.UpdateTags"
+
+	if _, err := t.tx.ExecContext(ctx, `DELETE FROM wallet_tag WHERE wallet_id = $1`, walletID); err != nil {
+		return fmt.Errorf("%s failed to delete tags: %w", fn, translateError(err))
+	}
+
+	if _, err := t.tx.ExecContext(ctx, `UPDATE wallet SET updated_at = $1 WHERE id = $2`, time.Now().UTC(), walletID); err != nil {
+		return fmt.Errorf("%s failed to update wallet: %w", fn, translateError(err))
+	}
+
+	if err := t.insertTags(ctx, walletID, tags); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (t *PostgreTx) insertTags(ctx context.Context, walletID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	stmt, err := t.tx.Prepare(`INSERT INTO wallet_tag(wallet_id, tag) VALUES($1, $2) ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare query for insert tag: %w", err)
+	}
+
+	defer stmt.Close()
+
+	for _, tag := range tags {
+		if _, err := stmt.ExecContext(ctx, walletID, tag); err != nil {
+			return fmt.Errorf("failed to insert tag: %w", translateError(err))
+		}
+	}
+
+	return nil
+}
+
+func (t *PostgreTx) AddOperation(ctx context.Context, op *storage.Operation) error {
+	const fn = "postgre.AddOperation"
+
+	if op.ID == "" {
+		op.ID = random.NewRandomString(ID_LENGTH)
+	}
+	if op.CreatedAt.IsZero() {
+		op.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := t.tx.ExecContext(ctx, `
+		INSERT INTO operation(id, wallet_id, type, amount, counterparty, created_at)
+		VALUES($1, $2, $3, $4, NULLIF($5, ''), $6)`,
+		op.ID, op.WalletID, op.Type, op.Amount, op.Counterparty, op.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s failed to insert operation: %w", fn, translateError(err))
+	}
+
+	return nil
+}
+
+// CreateWallet inserts wallet with its metadata and tags. Empty ID, status and timestamps are filled.
+func (t *PostgreTx) CreateWallet(ctx context.Context, wallet *storage.Wallet) error {
+	const fn = "postgre.CreateWallet"
+
+	if wallet.ID == "" {
+		wallet.ID = random.NewRandomString(ID_LENGTH)
+	}
+	if wallet.Status == "" {
+		wallet.Status = storage.StatusActive
+	}
+	if wallet.CreatedAt.IsZero() {
+		wallet.CreatedAt = time.Now().UTC()
+	}
+	if wallet.UpdatedAt.IsZero() {
+		wallet.UpdatedAt = wallet.CreatedAt
+	}
+
+	metadata := wallet.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode metadata: %w", fn, err)
+	}
+
+	_, err = t.tx.ExecContext(ctx, `
+		INSERT INTO wallet(id, name, balance, status, metadata, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7)`,
+		wallet.ID, wallet.Name, wallet.Balance, wallet.Status, data, wallet.CreatedAt, wallet.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s failed to insert wallet: %w", fn, translateError(err))
+	}
+
+	if err := t.insertTags(ctx, wallet.ID, wallet.Tags); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (t *PostgreTx) NextEventSeq(ctx context.Context, walletID string) (int64, error) {
+	return nextEventSeq(ctx, t.tx, walletID)
+}
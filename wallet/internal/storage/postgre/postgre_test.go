@@ -0,0 +1,42 @@
+package postgre
+
+import (
+	"context"
+	"os"
+	"testing"
+	"wallet/internal/storage"
+	"wallet/internal/storage/storagetest"
+)
+
+// dsnEnv points to a disposable database, its wallet tables are truncated before every test
+const dsnEnv = "WALLET_TEST_POSTGRES_DSN"
+
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		ctx := context.Background()
+
+		s, err := New(dsn)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+
+		migrator, err := s.Migrator()
+		if err != nil {
+			t.Fatalf("Migrator: %v", err)
+		}
+		if _, err := migrator.Up(ctx); err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+
+		if _, err := s.db.ExecContext(ctx, `TRUNCATE wallet, wallet_tag, operation, reconciliation, webhook, webhook_delivery CASCADE`); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+
+		return s
+	})
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS wallet(
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	balance FLOAT DEFAULT 0.0,
+	status TEXT DEFAULT 'active');
+
+CREATE INDEX IF NOT EXISTS idx_name ON wallet(name);
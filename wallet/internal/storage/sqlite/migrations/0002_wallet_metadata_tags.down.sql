@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_wallet_status_balance;
+DROP TABLE IF EXISTS wallet_tag;
+ALTER TABLE wallet DROP COLUMN metadata;
@@ -0,0 +1,9 @@
+ALTER TABLE wallet ADD COLUMN metadata TEXT NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS wallet_tag(
+	wallet_id TEXT NOT NULL REFERENCES wallet(id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (wallet_id, tag));
+
+CREATE INDEX IF NOT EXISTS idx_wallet_tag_tag ON wallet_tag(tag, wallet_id);
+CREATE INDEX IF NOT EXISTS idx_wallet_status_balance ON wallet(status, balance);
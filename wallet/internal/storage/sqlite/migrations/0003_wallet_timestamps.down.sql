@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_wallet_balance;
+DROP INDEX IF EXISTS idx_wallet_created_at;
+ALTER TABLE wallet DROP COLUMN updated_at;
+ALTER TABLE wallet DROP COLUMN created_at;
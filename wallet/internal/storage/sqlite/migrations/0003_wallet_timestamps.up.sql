@@ -0,0 +1,11 @@
+-- время хранится текстом в формате 2006-01-02T15:04:05.000000000Z, см. formatTime
+-- SQLite не допускает вычисляемый DEFAULT в ADD COLUMN, поэтому существующим кошелькам время проставляется отдельно
+ALTER TABLE wallet ADD COLUMN created_at TEXT NOT NULL DEFAULT '1970-01-01T00:00:00.000000000Z';
+ALTER TABLE wallet ADD COLUMN updated_at TEXT NOT NULL DEFAULT '1970-01-01T00:00:00.000000000Z';
+
+UPDATE wallet SET
+	created_at = strftime('%Y-%m-%dT%H:%M:%S', 'now') || '.000000000Z',
+	updated_at = strftime('%Y-%m-%dT%H:%M:%S', 'now') || '.000000000Z';
+
+CREATE INDEX IF NOT EXISTS idx_wallet_created_at ON wallet(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_wallet_balance ON wallet(balance, id);
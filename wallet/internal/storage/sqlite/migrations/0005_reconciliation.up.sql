@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS reconciliation(
+	id TEXT PRIMARY KEY,
+	created_at TEXT NOT NULL,
+	ok BOOLEAN NOT NULL,
+	report TEXT NOT NULL);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_created_at ON reconciliation(created_at);
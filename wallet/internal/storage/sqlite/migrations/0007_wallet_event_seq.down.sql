@@ -0,0 +1 @@
+ALTER TABLE wallet DROP COLUMN event_seq;
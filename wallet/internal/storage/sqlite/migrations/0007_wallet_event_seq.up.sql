@@ -0,0 +1,2 @@
+-- последний номер события кошелька, консьюмеры по нему находят пропуски и перестановки
+ALTER TABLE wallet ADD COLUMN event_seq INTEGER NOT NULL DEFAULT 0;
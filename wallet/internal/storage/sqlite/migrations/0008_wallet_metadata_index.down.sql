@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS wallet_metadata_update;
+DROP TRIGGER IF EXISTS wallet_metadata_insert;
+DROP TABLE IF EXISTS wallet_metadata;
@@ -0,0 +1,23 @@
+-- выражение metadata -> ключ нельзя проиндексировать для произвольных ключей, поэтому фильтры по метаданным
+-- ищут по таблице пар ключ-значение, её заполняют триггеры при записи кошелька
+CREATE TABLE IF NOT EXISTS wallet_metadata(
+	wallet_id TEXT NOT NULL REFERENCES wallet(id) ON DELETE CASCADE,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL, -- JSON значения, как его возвращает metadata -> ключ
+	PRIMARY KEY (wallet_id, key));
+
+CREATE INDEX IF NOT EXISTS idx_wallet_metadata_key_value ON wallet_metadata(key, value, wallet_id);
+
+INSERT OR IGNORE INTO wallet_metadata(wallet_id, key, value)
+SELECT w.id, m.key, w.metadata -> ('$.' || json_quote(m.key)) FROM wallet w, json_each(w.metadata) m;
+
+CREATE TRIGGER IF NOT EXISTS wallet_metadata_insert AFTER INSERT ON wallet BEGIN
+	INSERT INTO wallet_metadata(wallet_id, key, value)
+	SELECT NEW.id, m.key, NEW.metadata -> ('$.' || json_quote(m.key)) FROM json_each(NEW.metadata) m;
+END;
+
+CREATE TRIGGER IF NOT EXISTS wallet_metadata_update AFTER UPDATE OF metadata ON wallet BEGIN
+	DELETE FROM wallet_metadata WHERE wallet_id = NEW.id;
+	INSERT INTO wallet_metadata(wallet_id, key, value)
+	SELECT NEW.id, m.key, NEW.metadata -> ('$.' || json_quote(m.key)) FROM json_each(NEW.metadata) m;
+END;
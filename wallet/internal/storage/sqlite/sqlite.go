@@ -3,10 +3,20 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"migrate"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"wallet/internal/domain"
 	"wallet/internal/storage"
 	"wallet/internal/utils/random"
 
@@ -19,9 +29,94 @@ type Storage struct {
 
 const ID_LENGTH = 16
 
-func New(path string) (*Storage, error) {
+// walletColumns is a select list for scanWallet
+const walletColumns = `w.id, w.name, w.balance, w.status, w.metadata, w.created_at, w.updated_at,
+	(SELECT json_group_array(t.tag) FROM (SELECT tag FROM wallet_tag WHERE wallet_id = w.id ORDER BY tag) t)`
+
+// translateError converts driver error to storage error, other errors are returned as is
+func translateError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch {
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique,
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		return domain.Wrap(storage.ErrWalletExists, err)
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+		return domain.Wrap(storage.ErrWalletNotExist, err)
+	case sqliteErr.Code == sqlite3.ErrBusy, sqliteErr.Code == sqlite3.ErrLocked:
+		return domain.Wrap(storage.ErrConflict, err)
+	}
+
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWallet(row rowScanner) (*storage.Wallet, error) {
+	var (
+		wallet    storage.Wallet
+		metadata  string
+		createdAt string
+		updatedAt string
+		tags      string
+	)
+
+	if err := row.Scan(&wallet.ID, &wallet.Name, &wallet.Balance, &wallet.Status, &metadata, &createdAt, &updatedAt, &tags); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if wallet.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+	if wallet.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse updated_at: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(metadata), &wallet.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if err := json.Unmarshal([]byte(tags), &wallet.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+
+	return &wallet, nil
+}
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// defaultParams are applied unless DSN sets them: WAL lets readers work alongside the writer,
+// immediate transactions take the write lock at BEGIN, so concurrent read-modify-write
+// transactions wait for busy_timeout instead of failing on lock upgrade.
+var defaultParams = map[string]string{
+	"_journal_mode": "WAL",
+	"_busy_timeout": "5000",
+	"_txlock":       "immediate",
+	"_foreign_keys": "1",
+}
+
+// New opens SQLite database. dsn is a file path with optional go-sqlite3 parameters: path/to/wallet.db?_busy_timeout=10000
+func New(dsn string) (*Storage, error) {
 	const fn = "storage.sqlite.New"
 
+	path, rawParams, _ := strings.Cut(dsn, "?")
+
+	params, err := url.ParseQuery(rawParams)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid dsn parameters: %w", fn, err)
+	}
+	for key, value := range defaultParams {
+		if !params.Has(key) {
+			params.Set(key, value)
+		}
+	}
+
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		//creating db dir
 		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
@@ -38,7 +133,7 @@ func New(path string) (*Storage, error) {
 	}
 
 	//open bd
-	db, err := sql.Open("sqlite3", path)
+	db, err := sql.Open("sqlite3", path+"?"+params.Encode())
 	if err != nil {
 		return nil, fmt.Errorf("%s:%w", fn, err)
 	}
@@ -48,32 +143,27 @@ func New(path string) (*Storage, error) {
 		return nil, fmt.Errorf("%s:%w", fn, err)
 	}
 
-	stmt, err := db.Prepare(`
-		CREATE TABLE IF NOT EXISTS wallet(
-			id TEXT PRIMARY KEY,
-			name TEXT NOT NULL UNIQUE,
-			balance FLOAT DEFAULT 0.0,
-			status TEXT DEFAULT 'active');
-		CREATE INDEX IF NOT EXISTS idx_name ON wallet(name);
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("%s:%w", fn, err)
-	}
+	return &Storage{db: db}, nil
+}
 
-	defer stmt.Close()
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
 
-	_, err = stmt.Exec()
+// Migrator applies versioned schema migrations of sqlite dialect
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	scripts, err := fs.Sub(migrations, "migrations")
 	if err != nil {
-		return nil, fmt.Errorf("%s:%w", fn, err)
+		return nil, err
 	}
 
-	return &Storage{db: db}, nil
+	return migrate.New(s.db, migrate.SQLite, scripts, storage.Migrations)
 }
 
 func (s *Storage) CreateWallet(ctx context.Context, name string) (string, error) {
 	const fn = "sqlite.CreateWallet"
 
-	stmt, err := s.db.Prepare(`INSERT INTO wallet(id, name) VALUES(?, ?)`)
+	stmt, err := s.db.Prepare(`INSERT INTO wallet(id, name, created_at, updated_at) VALUES(?, ?, ?, ?)`)
 	if err != nil {
 		return "", fmt.Errorf("%s failed to prepare query for creating wallet: %w", fn, err)
 	}
@@ -82,11 +172,11 @@ func (s *Storage) CreateWallet(ctx context.Context, name string) (string, error)
 
 	walletID := random.NewRandomString(ID_LENGTH)
 
-	_, err = stmt.ExecContext(ctx, walletID, name)
+	now := formatTime(time.Now())
+
+	_, err = stmt.ExecContext(ctx, walletID, name, now, now)
 	if err != nil {
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return "", fmt.Errorf("%s: %w", fn, storage.ErrWalletExists)
-		}
+		return "", fmt.Errorf("%s failed to create wallet: %w", fn, translateError(err))
 	}
 
 	return walletID, nil
@@ -95,107 +185,762 @@ func (s *Storage) CreateWallet(ctx context.Context, name string) (string, error)
 func (s *Storage) GetWallet(ctx context.Context, walletID string) (*storage.Wallet, error) {
 	const fn = "sqlite.GetWallet"
 
-	stmt, err := s.db.Prepare(`SELECT id, name, balance, status FROM wallet WHERE id = ?`)
+	stmt, err := s.db.Prepare(`SELECT ` + walletColumns + ` FROM wallet w WHERE w.id = ?`)
 	if err != nil {
 		return nil, fmt.Errorf("%s failed to prepare query for get wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	var wallet storage.Wallet
-
-	err = stmt.QueryRowContext(ctx, walletID).Scan(&wallet.ID, &wallet.Name, &wallet.Balance, &wallet.Status)
+	wallet, err := scanWallet(stmt.QueryRowContext(ctx, walletID))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("%s failed to get wallet: %w", fn, storage.ErrWalletNotExist)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to get wallet: %w", fn, translateError(err))
+	}
 
-	return &wallet, nil
+	return wallet, nil
 }
 
-func (s *Storage) GetWallets(ctx context.Context) ([]storage.Wallet, error) {
-	const fn = "sqlite.GetWallets"
+func (s *Storage) ListWallets(ctx context.Context, query storage.WalletQuery) (*storage.WalletPage, error) {
+	const fn = "sqlite.ListWallets"
 
-	stmt, err := s.db.Prepare(`SELECT id, name, balance, status FROM wallet`)
-	if err != nil {
-		return nil, fmt.Errorf("%s failed to prepare query for get wallets: %w", fn, err)
+	sortColumn, ok := sortColumns[query.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown sort field %q", fn, query.SortBy)
 	}
 
-	defer stmt.Close()
+	conds, args := filterConditions(query.WalletFilter)
+	page := &storage.WalletPage{Wallets: []storage.Wallet{}}
+
+	if query.WithTotal {
+		var total int
+
+		countQuery := `SELECT COUNT(*) FROM wallet w` + where(conds)
+		if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, fmt.Errorf("%s failed to count wallets: %w", fn, err)
+		}
+
+		page.Total = &total
+	}
+
+	direction, compare := "ASC", ">"
+	if query.Desc {
+		direction, compare = "DESC", "<"
+	}
 
-	rows, err := stmt.QueryContext(ctx)
+	if query.Cursor != "" {
+		cursor, err := storage.DecodeCursor(query)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		var value any
+		switch query.SortBy {
+		case storage.SortByName:
+			value = cursor.Name
+		case storage.SortByBalance:
+			value = cursor.Balance
+		case storage.SortByCreatedAt:
+			value = formatTime(cursor.CreatedAt)
+		}
+
+		conds = append(conds, fmt.Sprintf("(%s, w.id) %s (?, ?)", sortColumn, compare))
+		args = append(args, value, cursor.ID)
+	}
+
+	listQuery := `SELECT ` + walletColumns + ` FROM wallet w` + where(conds) +
+		fmt.Sprintf(" ORDER BY %s %s, w.id %s LIMIT ?", sortColumn, direction, direction)
+	args = append(args, query.Limit+1)
+
+	rows, err := s.db.QueryContext(ctx, listQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	var wallets []storage.Wallet
+	defer rows.Close()
 
 	for rows.Next() {
-		var wallet storage.Wallet
-
-		if err := rows.Scan(&wallet.ID, &wallet.Name, &wallet.Balance, &wallet.Status); err != nil {
+		wallet, err := scanWallet(rows)
+		if err != nil {
 			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
 		}
 
-		wallets = append(wallets, wallet)
+		page.Wallets = append(page.Wallets, *wallet)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error during rows iteration: %v", err)
 	}
 
-	return wallets, nil
+	if len(page.Wallets) > query.Limit {
+		page.Wallets = page.Wallets[:query.Limit]
+		page.NextCursor = storage.NewCursor(query, page.Wallets[query.Limit-1])
+	}
+
+	return page, nil
+}
+
+var sortColumns = map[string]string{
+	storage.SortByName:      "w.name",
+	storage.SortByBalance:   "w.balance",
+	storage.SortByCreatedAt: "w.created_at",
+}
+
+func filterConditions(filter storage.WalletFilter) ([]string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+
+	if len(filter.Names) > 0 {
+		conds = append(conds, "w.name IN (?"+strings.Repeat(", ?", len(filter.Names)-1)+")")
+		for _, name := range filter.Names {
+			args = append(args, name)
+		}
+	}
+	//subqueries of tags and metadata search their indexes and then wallets by ID
+	for _, tag := range filter.Tags {
+		conds = append(conds, "w.id IN (SELECT t.wallet_id FROM wallet_tag t WHERE t.tag = ?)")
+		args = append(args, tag)
+	}
+	for key, value := range filter.Metadata {
+		values := storage.MetadataValues(value)
+		conds = append(conds, "w.id IN (SELECT m.wallet_id FROM wallet_metadata m WHERE m.key = ? AND m.value IN (?"+strings.Repeat(", ?", len(values)-1)+"))")
+		args = append(args, key)
+		for _, v := range values {
+			args = append(args, v)
+		}
+	}
+	if filter.Status != "" {
+		conds = append(conds, "w.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.MinBalance != nil {
+		conds = append(conds, "w.balance >= ?")
+		args = append(args, *filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		conds = append(conds, "w.balance <= ?")
+		args = append(args, *filter.MaxBalance)
+	}
+	if filter.NamePrefix != "" {
+		//range condition uses index on name and keeps prefix match case sensitive
+		conds = append(conds, "w.name >= ? AND w.name < ?")
+		args = append(args, filter.NamePrefix, filter.NamePrefix+string(utf8.MaxRune))
+	}
+
+	return conds, args
+}
+
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// timeLayout keeps fixed width, so stored timestamps are ordered as text
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func parseTime(value string) (time.Time, error) {
+	return time.Parse(timeLayout, value)
 }
 
 func (s *Storage) UpdateWallet(ctx context.Context, updatedWallet *storage.Wallet) (int64, error) {
 	const fn = "sqlite.UpdateWallet"
 
-	stmt, err := s.db.Prepare(`UPDATE wallet SET name = ?, balance = ?, status = ? WHERE id = ?`)
+	stmt, err := s.db.Prepare(`UPDATE wallet SET name = ?, balance = ?, status = ?, updated_at = ? WHERE id = ?`)
 	if err != nil {
 		return 0, fmt.Errorf("%s failed to prepare query for update wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, updatedWallet.Name, updatedWallet.Balance, updatedWallet.Status, updatedWallet.ID)
+	res, err := stmt.ExecContext(ctx, updatedWallet.Name, updatedWallet.Balance, updatedWallet.Status, formatTime(time.Now()), updatedWallet.ID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return 0, storage.ErrWalletNotExist
 	}
 	if err != nil {
-		return 0, fmt.Errorf("%s failed to update wallet: %w", fn, err)
+		return 0, fmt.Errorf("%s failed to update wallet: %w", fn, translateError(err))
 	}
 
-	id, err := res.LastInsertId()
+	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		return 0, fmt.Errorf("%s failed to get last insert id: %w", fn, err)
+		return 0, fmt.Errorf("%s failed to get affected rows: %w", fn, err)
+	}
+
+	if rowsAffected == 0 {
+		return 0, storage.ErrWalletNotExist
 	}
 
-	return id, nil
+	return rowsAffected, nil
 }
 
 func (s *Storage) DeactivateWallet(ctx context.Context, walletID string) (int64, error) {
 	const fn = "sqlite.DeactivateWallet"
 
-	stmt, err := s.db.Prepare(`UPDATE wallet SET status = "inactive" WHERE id = ?`)
+	stmt, err := s.db.Prepare(`UPDATE wallet SET status = 'inactive', updated_at = ? WHERE id = ?`)
 	if err != nil {
 		return 0, fmt.Errorf("%s failed to prepare query for deactivate wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, walletID)
+	res, err := stmt.ExecContext(ctx, formatTime(time.Now()), walletID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return 0, storage.ErrWalletNotExist
 	}
 	if err != nil {
-		return 0, fmt.Errorf("%s failed to deactivate wallet: %w", fn, err)
+		return 0, fmt.Errorf("%s failed to deactivate wallet: %w", fn, translateError(err))
 	}
 
-	id, err := res.LastInsertId()
+	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		return 0, fmt.Errorf("%s failed to get last insert id: %w", fn, err)
+		return 0, fmt.Errorf("%s failed to get affected rows: %w", fn, err)
 	}
 
-	return id, nil
+	if rowsAffected == 0 {
+		return 0, storage.ErrWalletNotExist
+	}
+
+	return rowsAffected, nil
+}
+
+// deltaExpr is a signed balance change of operation o
+const deltaExpr = `CASE WHEN o.type IN ('withdraw', 'transfer_out') THEN -o.amount ELSE o.amount END`
+
+func (s *Storage) BalanceAt(ctx context.Context, walletID string, at time.Time) (float64, error) {
+	const fn = "sqlite.BalanceAt"
+
+	var createdAt string
+
+	err := s.db.QueryRowContext(ctx, `SELECT created_at FROM wallet WHERE id = ?`, walletID).Scan(&createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", fn, storage.ErrWalletNotExist)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to get wallet: %w", fn, translateError(err))
+	}
+	if createdAt > formatTime(at) {
+		return 0, fmt.Errorf("%s: wallet was created after %s: %w", fn, at.Format(time.RFC3339), storage.ErrWalletNotExist)
+	}
+
+	var balance float64
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(`+deltaExpr+`), 0)
+		FROM operation o
+		WHERE o.wallet_id = ? AND o.created_at <= ?`,
+		walletID, formatTime(at),
+	).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to sum operations: %w", fn, err)
+	}
+
+	return balance, nil
+}
+
+func (s *Storage) BalancesAt(ctx context.Context, at time.Time) ([]storage.WalletBalance, error) {
+	const fn = "sqlite.BalancesAt"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT w.id, w.name, COALESCE(SUM(`+deltaExpr+`), 0)
+		FROM wallet w
+		LEFT JOIN operation o ON o.wallet_id = w.id AND o.created_at <= ?1
+		WHERE w.created_at <= ?1
+		GROUP BY w.id, w.name
+		ORDER BY w.name`,
+		formatTime(at),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	defer rows.Close()
+
+	balances := []storage.WalletBalance{}
+
+	for rows.Next() {
+		balance := storage.WalletBalance{At: at}
+
+		if err := rows.Scan(&balance.WalletID, &balance.Name, &balance.Balance); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+
+		balances = append(balances, balance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return balances, nil
+}
+
+// WalkOperations calls visit for every operation matching filter in chronological order.
+// Rows are read one by one, so any range can be walked without loading it into memory.
+func (s *Storage) WalkOperations(ctx context.Context, filter storage.OperationFilter, visit func(op storage.Operation) error) error {
+	const fn = "sqlite.WalkOperations"
+
+	var (
+		conds []string
+		args  []any
+	)
+
+	if filter.WalletID != "" {
+		args = append(args, filter.WalletID)
+		conds = append(conds, "o.wallet_id = ?")
+	}
+	if !filter.From.IsZero() {
+		args = append(args, formatTime(filter.From))
+		conds = append(conds, "o.created_at > ?")
+	}
+	if !filter.To.IsZero() {
+		args = append(args, formatTime(filter.To))
+		conds = append(conds, "o.created_at <= ?")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.id, o.wallet_id, o.type, o.amount, COALESCE(o.counterparty, ''), o.created_at
+		FROM operation o`+where(conds)+`
+		ORDER BY o.created_at, o.id`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			op        storage.Operation
+			createdAt string
+		)
+
+		if err := rows.Scan(&op.ID, &op.WalletID, &op.Type, &op.Amount, &op.Counterparty, &createdAt); err != nil {
+			return fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+		if op.CreatedAt, err = parseTime(createdAt); err != nil {
+			return fmt.Errorf("%s failed to parse created_at: %w", fn, err)
+		}
+
+		if err := visit(op); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return nil
+}
+
+// Ledger reads stored balances and journal totals in one read-only transaction,
+// so both parts of the snapshot are consistent with each other.
+func (s *Storage) Ledger(ctx context.Context) (*storage.Ledger, error) {
+	const fn = "sqlite.Ledger"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to begin transaction: %w", fn, translateError(err))
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT w.id, w.name, w.balance, COALESCE(SUM(`+deltaExpr+`), 0)
+		FROM wallet w
+		LEFT JOIN operation o ON o.wallet_id = w.id
+		GROUP BY w.id, w.name, w.balance
+		ORDER BY w.id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	defer rows.Close()
+
+	ledger := &storage.Ledger{
+		Balances: []storage.LedgerBalance{},
+		Totals:   map[string]float64{},
+	}
+
+	for rows.Next() {
+		var balance storage.LedgerBalance
+
+		if err := rows.Scan(&balance.WalletID, &balance.Name, &balance.Balance, &balance.Journal); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+
+		ledger.Balances = append(ledger.Balances, balance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	totals, err := tx.QueryContext(ctx, `SELECT type, SUM(amount) FROM operation GROUP BY type`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	defer totals.Close()
+
+	for totals.Next() {
+		var (
+			opType string
+			amount float64
+		)
+
+		if err := totals.Scan(&opType, &amount); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+
+		ledger.Totals[opType] = amount
+	}
+
+	if err := totals.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return ledger, nil
+}
+
+func (s *Storage) SaveReconciliation(ctx context.Context, report *storage.Reconciliation) error {
+	const fn = "sqlite.SaveReconciliation"
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode report: %w", fn, err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO reconciliation (id, created_at, ok, report) VALUES (?, ?, ?, ?)`,
+		report.ID, formatTime(report.FinishedAt), report.OK, string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) LatestReconciliation(ctx context.Context) (*storage.Reconciliation, error) {
+	const fn = "sqlite.LatestReconciliation"
+
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT report FROM reconciliation ORDER BY created_at DESC, id DESC LIMIT 1`,
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrReconciliationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var report storage.Reconciliation
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s failed to decode report: %w", fn, err)
+	}
+
+	return &report, nil
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	const fn = "sqlite.SaveWebhook"
+
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode webhook: %w", fn, err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO webhook (id, created_at, webhook) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET webhook = EXCLUDED.webhook`,
+		webhook.ID, formatTime(webhook.CreatedAt), string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, webhookID string) (*storage.Webhook, error) {
+	const fn = "sqlite.GetWebhook"
+
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx, `SELECT webhook FROM webhook WHERE id = ?`, webhookID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var webhook storage.Webhook
+	if err := json.Unmarshal(data, &webhook); err != nil {
+		return nil, fmt.Errorf("%s failed to decode webhook: %w", fn, err)
+	}
+
+	return &webhook, nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	const fn = "sqlite.ListWebhooks"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT webhook FROM webhook ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	webhooks := []storage.Webhook{}
+	for rows.Next() {
+		var (
+			data    []byte
+			webhook storage.Webhook
+		)
+
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+		if err := json.Unmarshal(data, &webhook); err != nil {
+			return nil, fmt.Errorf("%s failed to decode webhook: %w", fn, err)
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes webhook along with its deliveries
+func (s *Storage) DeleteWebhook(ctx context.Context, webhookID string) error {
+	const fn = "sqlite.DeleteWebhook"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook WHERE id = ?`, webhookID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if deleted == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveDelivery(ctx context.Context, delivery *storage.Delivery) error {
+	const fn = "sqlite.SaveDelivery"
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode delivery: %w", fn, err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO webhook_delivery (id, webhook_id, status, next_attempt_at, created_at, delivery)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			status = EXCLUDED.status,
+			next_attempt_at = EXCLUDED.next_attempt_at,
+			delivery = EXCLUDED.delivery`,
+		delivery.ID, delivery.WebhookID, delivery.Status, formatTime(delivery.NextAttemptAt), formatTime(delivery.CreatedAt), string(data),
+	)
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
+		return storage.ErrWebhookNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetDelivery(ctx context.Context, webhookID, deliveryID string) (*storage.Delivery, error) {
+	const fn = "sqlite.GetDelivery"
+
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT delivery FROM webhook_delivery WHERE id = ? AND webhook_id = ?`,
+		deliveryID, webhookID,
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var delivery storage.Delivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, fmt.Errorf("%s failed to decode delivery: %w", fn, err)
+	}
+
+	return &delivery, nil
+}
+
+// ListDeliveries returns at most limit latest deliveries of webhook, newest first
+func (s *Storage) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]storage.Delivery, error) {
+	const fn = "sqlite.ListDeliveries"
+
+	return s.queryDeliveries(ctx, fn,
+		`SELECT delivery FROM webhook_delivery WHERE webhook_id = ? ORDER BY created_at DESC, id DESC LIMIT ?`,
+		webhookID, limit,
+	)
+}
+
+// ClaimDeliveries returns at most limit pending deliveries due at the moment at, oldest first.
+// Their next attempt is moved to leaseUntil, so other instances skip them till the lease expires.
+func (s *Storage) ClaimDeliveries(ctx context.Context, at, leaseUntil time.Time, limit int) ([]storage.Delivery, error) {
+	const fn = "sqlite.ClaimDeliveries"
+
+	deliveries, err := s.queryDeliveries(ctx, fn,
+		`UPDATE webhook_delivery SET
+			next_attempt_at = ?,
+			delivery = json_set(delivery, '$.next_attempt_at', ?)
+		WHERE id IN (
+			SELECT id FROM webhook_delivery WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at, id LIMIT ?)
+		RETURNING delivery`,
+		formatTime(leaseUntil), leaseUntil.UTC().Format(time.RFC3339Nano), storage.DeliveryPending, formatTime(at), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	//RETURNING keeps no order
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+
+	return deliveries, nil
+}
+
+// AddWebhookFailure counts a failed attempt of webhook and disables it after disableAfter failures in a row
+func (s *Storage) AddWebhookFailure(ctx context.Context, webhookID string, disableAfter int) (*storage.Webhook, error) {
+	const fn = "sqlite.AddWebhookFailure"
+
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE webhook SET webhook = json_set(webhook,
+			'$.failures', json_extract(webhook, '$.failures') + 1,
+			'$.status', CASE WHEN ? > 0 AND json_extract(webhook, '$.failures') + 1 >= ? THEN ? ELSE json_extract(webhook, '$.status') END,
+			'$.updated_at', ?)
+		WHERE id = ?
+		RETURNING webhook`,
+		disableAfter, disableAfter, storage.WebhookDisabled, time.Now().UTC().Format(time.RFC3339Nano), webhookID,
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var webhook storage.Webhook
+	if err := json.Unmarshal(data, &webhook); err != nil {
+		return nil, fmt.Errorf("%s failed to decode webhook: %w", fn, err)
+	}
+
+	return &webhook, nil
+}
+
+// ResetWebhookFailures forgets failed attempts of webhook after a successful one, missing webhook is ignored
+func (s *Storage) ResetWebhookFailures(ctx context.Context, webhookID string) error {
+	const fn = "sqlite.ResetWebhookFailures"
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE webhook SET webhook = json_set(webhook, '$.failures', 0, '$.updated_at', ?)
+		WHERE id = ? AND json_extract(webhook, '$.failures') <> 0`,
+		time.Now().UTC().Format(time.RFC3339Nano), webhookID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) queryDeliveries(ctx context.Context, fn, query string, args ...any) ([]storage.Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	deliveries := []storage.Delivery{}
+	for rows.Next() {
+		var (
+			data     []byte
+			delivery storage.Delivery
+		)
+
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("%s failed to scan row: %w", fn, err)
+		}
+		if err := json.Unmarshal(data, &delivery); err != nil {
+			return nil, fmt.Errorf("%s failed to decode delivery: %w", fn, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) NextEventSeq(ctx context.Context, walletID string) (int64, error) {
+	return nextEventSeq(ctx, s.db, walletID)
+}
+
+// rowQuerier is *sql.DB or *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func nextEventSeq(ctx context.Context, q rowQuerier, walletID string) (int64, error) {
+	const fn = "sqlite.NextEventSeq"
+
+	var seq int64
+	err := q.QueryRowContext(ctx, `UPDATE wallet SET event_seq = event_seq + 1 WHERE id = ? RETURNING event_seq`, walletID).Scan(&seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrWalletNotExist
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to advance event sequence: %w", fn, translateError(err))
+	}
+
+	return seq, nil
 }
 
 func (s *Storage) BeginTx(ctx context.Context) (storage.Transaction, error) {
@@ -203,7 +948,7 @@ func (s *Storage) BeginTx(ctx context.Context) (storage.Transaction, error) {
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%s failed to begin transaction: %w", fn, err)
+		return nil, fmt.Errorf("%s failed to begin transaction: %w", fn, translateError(err))
 	}
 
 	return &SQLiteTx{tx: tx}, nil
@@ -214,7 +959,7 @@ type SQLiteTx struct {
 }
 
 func (t *SQLiteTx) Commit() error {
-	return t.tx.Commit()
+	return translateError(t.tx.Commit())
 }
 
 func (t *SQLiteTx) Rollback() error {
@@ -224,45 +969,187 @@ func (t *SQLiteTx) Rollback() error {
 func (t *SQLiteTx) GetWallet(ctx context.Context, walletID string) (*storage.Wallet, error) {
 	const fn = "sqlite.GetWallet"
 
-	stmt, err := t.tx.Prepare(`SELECT id, name, balance, status FROM wallet WHERE id = ?`)
+	stmt, err := t.tx.Prepare(`SELECT ` + walletColumns + ` FROM wallet w WHERE w.id = ?`)
 	if err != nil {
 		return nil, fmt.Errorf("%s failed to prepare query for get wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	var wallet storage.Wallet
-
-	err = stmt.QueryRowContext(ctx, walletID).Scan(&wallet.ID, &wallet.Name, &wallet.Balance, &wallet.Status)
+	wallet, err := scanWallet(stmt.QueryRowContext(ctx, walletID))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, storage.ErrWalletNotExist
 	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to get wallet: %w", fn, translateError(err))
+	}
 
-	return &wallet, nil
+	return wallet, nil
 }
 
 func (t *SQLiteTx) UpdateWallet(ctx context.Context, updatedWallet *storage.Wallet) (int64, error) {
 	const fn = "sqlite.UpdateWallet"
 
-	stmt, err := t.tx.Prepare(`UPDATE wallet SET name = ?, balance = ?, status = ? WHERE id = ?`)
+	stmt, err := t.tx.Prepare(`UPDATE wallet SET name = ?, balance = ?, status = ?, updated_at = ? WHERE id = ?`)
 	if err != nil {
 		return 0, fmt.Errorf("%s failed to prepare query for update wallet: %w", fn, err)
 	}
 
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, updatedWallet.Name, updatedWallet.Balance, updatedWallet.Status, updatedWallet.ID)
+	res, err := stmt.ExecContext(ctx, updatedWallet.Name, updatedWallet.Balance, updatedWallet.Status, formatTime(time.Now()), updatedWallet.ID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return 0, storage.ErrWalletNotExist
 	}
 	if err != nil {
-		return 0, fmt.Errorf("%s failed to update wallet: %w", fn, err)
+		return 0, fmt.Errorf("%s failed to update wallet: %w", fn, translateError(err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to get affected rows: %w", fn, err)
+	}
+
+	if rowsAffected == 0 {
+		return 0, storage.ErrWalletNotExist
+	}
+
+	return rowsAffected, nil
+}
+
+func (t *SQLiteTx) UpdateMetadata(ctx context.Context, walletID string, metadata map[string]any) error {
+	const fn = "sqlite.UpdateMetadata"
+
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode metadata: %w", fn, err)
+	}
+
+	res, err := t.tx.ExecContext(ctx, `UPDATE wallet SET metadata = ?, updated_at = ? WHERE id = ?`, string(data), formatTime(time.Now()), walletID)
+	if err != nil {
+		return fmt.Errorf("%s failed to update metadata: %w", fn, translateError(err))
 	}
 
-	id, err := res.LastInsertId()
+	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		return 0, fmt.Errorf("%s failed to get last insert id: %w", fn, err)
+		return fmt.Errorf("%s failed to get affected rows: %w", fn, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrWalletNotExist
 	}
 
-	return id, nil
+	return nil
+}
+
+func (t *SQLiteTx) UpdateTags(ctx context.Context, walletID string, tags []string) error {
+	const fn = "sqlite.UpdateTags"
+
+	if _, err := t.tx.ExecContext(ctx, `DELETE FROM wallet_tag WHERE wallet_id = ?`, walletID); err != nil {
+		return fmt.Errorf("%s failed to delete tags: %w", fn, translateError(err))
+	}
+
+	if _, err := t.tx.ExecContext(ctx, `UPDATE wallet SET updated_at = ? WHERE id = ?`, formatTime(time.Now()), walletID); err != nil {
+		return fmt.Errorf("%s failed to update wallet: %w", fn, translateError(err))
+	}
+
+	if err := t.insertTags(ctx, walletID, tags); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (t *SQLiteTx) insertTags(ctx context.Context, walletID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	stmt, err := t.tx.Prepare(`INSERT OR IGNORE INTO wallet_tag(wallet_id, tag) VALUES(?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare query for insert tag: %w", err)
+	}
+
+	defer stmt.Close()
+
+	for _, tag := range tags {
+		if _, err := stmt.ExecContext(ctx, walletID, tag); err != nil {
+			return fmt.Errorf("failed to insert tag: %w", translateError(err))
+		}
+	}
+
+	return nil
+}
+
+func (t *SQLiteTx) AddOperation(ctx context.Context, op *storage.Operation) error {
+	const fn = "sqlite.AddOperation"
+
+	if op.ID == "" {
+		op.ID = random.NewRandomString(ID_LENGTH)
+	}
+	if op.CreatedAt.IsZero() {
+		op.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := t.tx.ExecContext(ctx, `
+		INSERT INTO operation(id, wallet_id, type, amount, counterparty, created_at)
+		VALUES(?, ?, ?, ?, NULLIF(?, ''), ?)`,
+		op.ID, op.WalletID, op.Type, op.Amount, op.Counterparty, formatTime(op.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("%s failed to insert operation: %w", fn, translateError(err))
+	}
+
+	return nil
+}
+
+// CreateWallet inserts wallet with its metadata and tags. Empty ID, status and timestamps are filled.
+func (t *SQLiteTx) CreateWallet(ctx context.Context, wallet *storage.Wallet) error {
+	const fn = "sqlite.CreateWallet"
+
+	if wallet.ID == "" {
+		wallet.ID = random.NewRandomString(ID_LENGTH)
+	}
+	if wallet.Status == "" {
+		wallet.Status = storage.StatusActive
+	}
+	if wallet.CreatedAt.IsZero() {
+		wallet.CreatedAt = time.Now().UTC()
+	}
+	if wallet.UpdatedAt.IsZero() {
+		wallet.UpdatedAt = wallet.CreatedAt
+	}
+
+	metadata := wallet.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode metadata: %w", fn, err)
+	}
+
+	_, err = t.tx.ExecContext(ctx, `
+		INSERT INTO wallet(id, name, balance, status, metadata, created_at, updated_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?)`,
+		wallet.ID, wallet.Name, wallet.Balance, wallet.Status, string(data), formatTime(wallet.CreatedAt), formatTime(wallet.UpdatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("%s failed to insert wallet: %w", fn, translateError(err))
+	}
+
+	if err := t.insertTags(ctx, wallet.ID, wallet.Tags); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (t *SQLiteTx) NextEventSeq(ctx context.Context, walletID string) (int64, error) {
+	return nextEventSeq(ctx, t.tx, walletID)
 }
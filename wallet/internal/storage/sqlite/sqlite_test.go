@@ -0,0 +1,169 @@
+package sqlite
+
+import (
+	"context"
+	"io/fs"
+	"migrate"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+	"wallet/internal/storage"
+	"wallet/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := New(filepath.Join(t.TempDir(), "wallet.db"))
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+
+		migrator, err := s.Migrator()
+		if err != nil {
+			t.Fatalf("Migrator: %v", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+
+		return s
+	})
+}
+
+// migrateBefore applies migrations whose files sort before version
+func migrateBefore(t *testing.T, s *Storage, version string) {
+	t.Helper()
+
+	early := fstest.MapFS{}
+	err := fs.WalkDir(migrations, "migrations", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() >= version {
+			return err
+		}
+		data, err := fs.ReadFile(migrations, path)
+		early[d.Name()] = &fstest.MapFile{Data: data}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("read migrations: %v", err)
+	}
+
+	migrator, err := migrate.New(s.db, migrate.SQLite, early, storage.Migrations)
+	if err != nil {
+		t.Fatalf("migrate.New: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+}
+
+// TestJournalBackfill checks that wallets created before the operation journal get their balance as history
+func TestJournalBackfill(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	//schema before timestamps and journal
+	migrateBefore(t, s, "0003_")
+
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO wallet(id, name, balance) VALUES ('funded', 'alice', 42.5), ('empty', 'bob', 0)`); err != nil {
+		t.Fatalf("insert wallets: %v", err)
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+
+	migrator, err := s.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	wallet, err := s.GetWallet(ctx, "funded")
+	if err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+	if wallet.CreatedAt.Before(before) {
+		t.Errorf("CreatedAt = %v, want time of migration", wallet.CreatedAt)
+	}
+
+	balance, err := s.BalanceAt(ctx, "funded", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("BalanceAt: %v", err)
+	}
+	if balance != 42.5 {
+		t.Errorf("BalanceAt = %v, want 42.5", balance)
+	}
+
+	var operations int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM operation WHERE wallet_id = 'empty'`).Scan(&operations); err != nil {
+		t.Fatalf("count operations: %v", err)
+	}
+	if operations != 0 {
+		t.Errorf("empty wallet has %d operations, want 0", operations)
+	}
+}
+
+// TestMetadataBackfill checks that metadata of wallets created before its index is found by filters
+func TestMetadataBackfill(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	migrateBefore(t, s, "0008_")
+
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO wallet(id, name, balance, metadata) VALUES ('gold', 'alice', 0, '{"tier":"gold","level":3}'), ('plain', 'bob', 0, '{}')`); err != nil {
+		t.Fatalf("insert wallets: %v", err)
+	}
+
+	migrator, err := s.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	for _, metadata := range []map[string]string{{"tier": "gold"}, {"level": "3"}} {
+		page, err := s.ListWallets(ctx, storage.WalletQuery{
+			WalletFilter: storage.WalletFilter{Metadata: metadata},
+			SortBy:       storage.SortByName,
+			Limit:        10,
+		})
+		if err != nil {
+			t.Fatalf("ListWallets: %v", err)
+		}
+		if len(page.Wallets) != 1 || page.Wallets[0].ID != "gold" {
+			t.Errorf("ListWallets(%v) = %+v, want the gold wallet", metadata, page.Wallets)
+		}
+	}
+
+	var plan strings.Builder
+	rows, err := s.db.QueryContext(ctx, `EXPLAIN QUERY PLAN SELECT w.id FROM wallet w
+		WHERE w.id IN (SELECT m.wallet_id FROM wallet_metadata m WHERE m.key = ? AND m.value IN (?))`, "tier", `"gold"`)
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		plan.WriteString(detail + "\n")
+	}
+	if !strings.Contains(plan.String(), "idx_wallet_metadata_key_value") {
+		t.Errorf("metadata filter does not use its index:\n%s", plan.String())
+	}
+}
@@ -2,36 +2,315 @@ package storage
 
 import (
 	"context"
-	"errors"
+	"encoding/base64"
+	"encoding/json"
+	"migrate"
+	"time"
+	"wallet/internal/domain"
 )
 
+// Migrations keeps applied schema versions apart from stats service sharing the database
+var Migrations = migrate.Config{
+	Table:   "wallet_schema_migrations",
+	LockKey: 0x77616c6c6574, // "wallet"
+}
+
 type Storage interface {
 	CreateWallet(ctx context.Context, name string) (string, error)
 	GetWallet(ctx context.Context, walletID string) (*Wallet, error)
-	GetWallets(ctx context.Context) ([]Wallet, error)
+	ListWallets(ctx context.Context, query WalletQuery) (*WalletPage, error)
 	UpdateWallet(ctx context.Context, updatedWallet *Wallet) (int64, error)
 	DeactivateWallet(ctx context.Context, walletID string) (int64, error)
+	//Исторические балансы
+	BalanceAt(ctx context.Context, walletID string, at time.Time) (float64, error)
+	BalancesAt(ctx context.Context, at time.Time) ([]WalletBalance, error)
+	WalkOperations(ctx context.Context, filter OperationFilter, visit func(op Operation) error) error
+	//Сверка журнала
+	Ledger(ctx context.Context) (*Ledger, error)
+	SaveReconciliation(ctx context.Context, report *Reconciliation) error
+	LatestReconciliation(ctx context.Context) (*Reconciliation, error)
+	//Вебхуки
+	SaveWebhook(ctx context.Context, webhook *Webhook) error
+	GetWebhook(ctx context.Context, webhookID string) (*Webhook, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID string) error
+	SaveDelivery(ctx context.Context, delivery *Delivery) error
+	GetDelivery(ctx context.Context, webhookID, deliveryID string) (*Delivery, error)
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]Delivery, error)
+	ClaimDeliveries(ctx context.Context, at, leaseUntil time.Time, limit int) ([]Delivery, error)
+	AddWebhookFailure(ctx context.Context, webhookID string, disableAfter int) (*Webhook, error)
+	ResetWebhookFailures(ctx context.Context, webhookID string) error
+	//Порядок событий
+	NextEventSeq(ctx context.Context, walletID string) (int64, error)
 	//Транзакции
 	BeginTx(ctx context.Context) (Transaction, error)
+	Close() error
 }
 
 type Transaction interface {
 	Commit() error
 	Rollback() error
 	GetWallet(ctx context.Context, walletID string) (*Wallet, error)
+	CreateWallet(ctx context.Context, wallet *Wallet) error
 	UpdateWallet(ctx context.Context, updatedWallet *Wallet) (int64, error)
+	UpdateMetadata(ctx context.Context, walletID string, metadata map[string]any) error
+	UpdateTags(ctx context.Context, walletID string, tags []string) error
+	AddOperation(ctx context.Context, op *Operation) error
+	//NextEventSeq returns the next sequence number of wallet events, it is not advanced on rollback
+	NextEventSeq(ctx context.Context, walletID string) (int64, error)
 }
 
 type Wallet struct {
-	ID      string  `json:"id"`
-	Name    string  `json:"name,omitempty"`
-	Balance float64 `json:"balance,omitempty"`
-	Status  string  `json:"status,omitempty"`
+	ID        string         `json:"id"`
+	Name      string         `json:"name,omitempty"`
+	Balance   float64        `json:"balance,omitempty"`
+	Status    string         `json:"status,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Tags      []string       `json:"tags,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+)
+
+// Operation types of the wallet journal
+const (
+	OpDeposit     = "deposit"
+	OpWithdraw    = "withdraw"
+	OpTransferIn  = "transfer_in"
+	OpTransferOut = "transfer_out"
+	//OpOpeningBalance is a balance of the wallet migrated from another system
+	OpOpeningBalance = "opening_balance"
+)
+
+// Operation is a journal entry, every balance change of a wallet is persisted as operation.
+// Amount is always positive, direction is defined by Type.
+type Operation struct {
+	ID           string    `json:"id"`
+	WalletID     string    `json:"wallet_id"`
+	Type         string    `json:"type"`
+	Amount       float64   `json:"amount"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Delta returns signed balance change made by operation
+func (o Operation) Delta() float64 {
+	switch o.Type {
+	case OpWithdraw, OpTransferOut:
+		return -o.Amount
+	default:
+		return o.Amount
+	}
+}
+
+// OperationFilter selects operations made after From up to and including To.
+// Zero values are not applied.
+type OperationFilter struct {
+	WalletID string
+	From     time.Time
+	To       time.Time
+}
+
+// WalletBalance is a wallet balance computed from journal at the moment At
+type WalletBalance struct {
+	WalletID string    `json:"wallet_id"`
+	Name     string    `json:"name,omitempty"`
+	Balance  float64   `json:"balance"`
+	At       time.Time `json:"at"`
+}
+
+// LedgerBalance is a stored wallet balance along with the balance recomputed from its operations
+type LedgerBalance struct {
+	WalletID string
+	Name     string
+	Balance  float64 //wallet.balance
+	Journal  float64 //sum of operation deltas
+}
+
+// Ledger is a consistent snapshot of all wallet balances and journal totals
+type Ledger struct {
+	Balances []LedgerBalance
+	Totals   map[string]float64 //sum of operation amounts by operation type
+}
+
+// Discrepancy kinds of reconciliation report
+const (
+	DiscrepancyBalance   = "balance"   //wallet.balance differs from its journal
+	DiscrepancyTotal     = "total"     //sum of balances differs from external inflows minus outflows
+	DiscrepancyTransfers = "transfers" //incoming transfers differ from outgoing ones
+)
+
+type Discrepancy struct {
+	Kind       string  `json:"kind"`
+	WalletID   string  `json:"wallet_id,omitempty"`
+	Name       string  `json:"name,omitempty"`
+	Expected   float64 `json:"expected"`
+	Actual     float64 `json:"actual"`
+	Difference float64 `json:"difference"`
+}
+
+// Reconciliation is a report of the ledger reconciliation job
+type Reconciliation struct {
+	ID               string        `json:"id"`
+	StartedAt        time.Time     `json:"started_at"`
+	FinishedAt       time.Time     `json:"finished_at"`
+	Wallets          int           `json:"wallets"`
+	TotalBalance     float64       `json:"total_balance"`
+	ExternalInflows  float64       `json:"external_inflows"`  //deposits and opening balances
+	ExternalOutflows float64       `json:"external_outflows"` //withdrawals
+	OK               bool          `json:"ok"`
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+}
+
+// Webhook statuses
+const (
+	WebhookActive   = "active"
+	WebhookDisabled = "disabled" //endpoint kept failing or was disabled by partner
+)
+
+// Webhook is a partner subscription to wallet events
+type Webhook struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"` //empty matches every event
+	WalletIDs  []string  `json:"wallet_ids"`  //empty matches every wallet
+	Secret     string    `json:"secret"`      //key of delivery signatures
+	Status     string    `json:"status"`
+	Failures   int       `json:"failures"` //consecutive failed delivery attempts
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed" //attempts are exhausted or webhook is disabled
+)
+
+// Delivery is an event sent to a webhook along with the result of its last attempt
+type Delivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhook_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"` //request body
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"` //meaningful for pending deliveries
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	ReplayOf       string          `json:"replay_of,omitempty"` //delivery which was replayed
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// WalletFilter describes search conditions for ListWallets.
+// Empty fields are not applied, all set fields are combined with AND.
+type WalletFilter struct {
+	Names      []string          //wallet name must be one of names
+	Tags       []string          //wallet must have every tag
+	Metadata   map[string]string //metadata key must be equal to value
+	Status     string
+	MinBalance *float64
+	MaxBalance *float64
+	NamePrefix string
+}
+
+// Sort fields of WalletQuery
+const (
+	SortByName      = "name"
+	SortByBalance   = "balance"
+	SortByCreatedAt = "created_at"
+)
+
+// WalletQuery describes one page of ListWallets.
+// Wallets are ordered by SortBy field and then by ID, so every page is stable.
+type WalletQuery struct {
+	WalletFilter
+	SortBy    string
+	Desc      bool
+	Limit     int
+	Cursor    string //NextCursor of previous page
+	WithTotal bool   //count all wallets matching filter
+}
+
+type WalletPage struct {
+	Wallets    []Wallet `json:"wallets"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	Total      *int     `json:"total,omitempty"`
+}
+
+// Cursor points to the last wallet of a page
+type Cursor struct {
+	SortBy    string    `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	ID        string    `json:"id"`
+	Name      string    `json:"n,omitempty"`
+	Balance   float64   `json:"b,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+}
+
+func NewCursor(query WalletQuery, last Wallet) string {
+	data, _ := json.Marshal(Cursor{
+		SortBy:    query.SortBy,
+		Desc:      query.Desc,
+		ID:        last.ID,
+		Name:      last.Name,
+		Balance:   last.Balance,
+		CreatedAt: last.CreatedAt,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes query cursor. Cursor must be issued for the same sort order.
+func DecodeCursor(query WalletQuery) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.SortBy != query.SortBy || cursor.Desc != query.Desc || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
 }
 
-// TODO: add more errors
 var (
-	ErrWalletExists   = errors.New("wallet already exists")
-	ErrWalletNotExist = errors.New("wallet not exists")
-	ErrWalletNotFound = errors.New("wallet not found")
+	ErrWalletExists   = domain.New(domain.ErrExists, "wallet_exists", "wallet already exists")
+	ErrWalletNotExist = domain.New(domain.ErrNotFound, "wallet_not_found", "wallet not exists")
+	ErrWalletNotFound = domain.New(domain.ErrNotFound, "wallet_not_found", "wallet not found")
+	ErrInvalidCursor  = domain.New(domain.ErrInvalidArgument, "invalid_cursor", "invalid cursor")
+	//ErrConflict is returned when transaction is aborted because of concurrent one, it may be retried
+	ErrConflict = domain.New(domain.ErrConflict, "concurrent_update", "wallet is being updated concurrently, retry the request")
+
+	ErrReconciliationNotFound = domain.New(domain.ErrNotFound, "reconciliation_not_found", "reconciliation report not found")
+	ErrWebhookNotFound        = domain.New(domain.ErrNotFound, "webhook_not_found", "webhook not found")
+	ErrDeliveryNotFound       = domain.New(domain.ErrNotFound, "delivery_not_found", "webhook delivery not found")
 )
+
+// MetadataValues returns JSON representations which match metadata filter value:
+// value as a JSON string and value itself when it is a JSON number, boolean or null.
+func MetadataValues(value string) []string {
+	quoted, _ := json.Marshal(value)
+	values := []string{string(quoted)}
+
+	var scalar any
+	if err := json.Unmarshal([]byte(value), &scalar); err == nil {
+		switch scalar.(type) {
+		case float64, bool, nil:
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,1074 @@
+// Package storagetest is a conformance suite for storage.Storage implementations.
+// Every backend runs the same suite from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage { ... })
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/domain"
+	"wallet/internal/storage"
+)
+
+// Factory returns an empty storage with applied schema. It is called for every test,
+// the storage is closed by the suite.
+type Factory func(t *testing.T) storage.Storage
+
+// Run runs the conformance suite against storages made by newStorage
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"DuplicateName", testDuplicateName},
+		{"NotFound", testNotFound},
+		{"UpdateWallet", testUpdateWallet},
+		{"DeactivateWallet", testDeactivateWallet},
+		{"ListFilters", testListFilters},
+		{"ListPagination", testListPagination},
+		{"MetadataAndTags", testMetadataAndTags},
+		{"Commit", testCommit},
+		{"Rollback", testRollback},
+		{"TxCreateWallet", testTxCreateWallet},
+		{"Operations", testOperations},
+		{"ConcurrentTransfers", testConcurrentTransfers},
+		{"Reconciliation", testReconciliation},
+		{"Webhooks", testWebhooks},
+		{"Deliveries", testDeliveries},
+		{"WebhookFailures", testWebhookFailures},
+		{"EventSeq", testEventSeq},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStorage(t)
+			t.Cleanup(func() { s.Close() })
+
+			tt.test(t, s)
+		})
+	}
+}
+
+func createWallet(t *testing.T, s storage.Storage, name string) string {
+	t.Helper()
+
+	id, err := s.CreateWallet(context.Background(), name)
+	if err != nil {
+		t.Fatalf("CreateWallet(%q): %v", name, err)
+	}
+	if id == "" {
+		t.Fatalf("CreateWallet(%q) returned empty ID", name)
+	}
+
+	return id
+}
+
+func getWallet(t *testing.T, s storage.Storage, id string) *storage.Wallet {
+	t.Helper()
+
+	wallet, err := s.GetWallet(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetWallet(%q): %v", id, err)
+	}
+
+	return wallet
+}
+
+// deposit sets wallet balance and records the operation in one transaction
+func deposit(t *testing.T, s storage.Storage, id string, amount float64) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := tx.GetWallet(ctx, id)
+	if err != nil {
+		t.Fatalf("Tx.GetWallet: %v", err)
+	}
+
+	wallet.Balance += amount
+	if _, err := tx.UpdateWallet(ctx, wallet); err != nil {
+		t.Fatalf("Tx.UpdateWallet: %v", err)
+	}
+	if err := tx.AddOperation(ctx, &storage.Operation{WalletID: id, Type: storage.OpDeposit, Amount: amount}); err != nil {
+		t.Fatalf("Tx.AddOperation: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+}
+
+func names(wallets []storage.Wallet) []string {
+	result := make([]string, len(wallets))
+	for i, wallet := range wallets {
+		result[i] = wallet.Name
+	}
+
+	return result
+}
+
+func equal(a, b []string) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func testCreateAndGet(t *testing.T, s storage.Storage) {
+	before := time.Now().Add(-time.Second)
+	id := createWallet(t, s, "alice")
+
+	wallet := getWallet(t, s, id)
+
+	if wallet.ID != id || wallet.Name != "alice" {
+		t.Errorf("got wallet %q %q, want %q alice", wallet.ID, wallet.Name, id)
+	}
+	if wallet.Balance != 0 {
+		t.Errorf("new wallet balance = %v, want 0", wallet.Balance)
+	}
+	if wallet.Status != storage.StatusActive {
+		t.Errorf("new wallet status = %q, want %q", wallet.Status, storage.StatusActive)
+	}
+	if wallet.CreatedAt.Before(before) || wallet.UpdatedAt.Before(before) {
+		t.Errorf("timestamps are not set: created %v, updated %v", wallet.CreatedAt, wallet.UpdatedAt)
+	}
+	if wallet.Metadata == nil || len(wallet.Metadata) != 0 {
+		t.Errorf("new wallet metadata = %#v, want empty map", wallet.Metadata)
+	}
+	if wallet.Tags == nil || len(wallet.Tags) != 0 {
+		t.Errorf("new wallet tags = %#v, want empty slice", wallet.Tags)
+	}
+}
+
+func testDuplicateName(t *testing.T, s storage.Storage) {
+	createWallet(t, s, "alice")
+
+	_, err := s.CreateWallet(context.Background(), "alice")
+	if !errors.Is(err, storage.ErrWalletExists) {
+		t.Fatalf("CreateWallet with taken name: got %v, want %v", err, storage.ErrWalletExists)
+	}
+
+	bob := createWallet(t, s, "bob")
+	_, err = s.UpdateWallet(context.Background(), &storage.Wallet{ID: bob, Name: "alice", Status: storage.StatusActive})
+	if !errors.Is(err, storage.ErrWalletExists) {
+		t.Fatalf("UpdateWallet with taken name: got %v, want %v", err, storage.ErrWalletExists)
+	}
+
+	page, err := s.ListWallets(context.Background(), storage.WalletQuery{SortBy: storage.SortByName, Limit: 10, WithTotal: true})
+	if err != nil {
+		t.Fatalf("ListWallets: %v", err)
+	}
+	if *page.Total != 2 {
+		t.Errorf("wallets after duplicate = %d, want 2", *page.Total)
+	}
+}
+
+func testNotFound(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	if _, err := s.GetWallet(ctx, "missing"); !errors.Is(err, storage.ErrWalletNotExist) {
+		t.Errorf("GetWallet: got %v, want %v", err, storage.ErrWalletNotExist)
+	}
+	if _, err := s.UpdateWallet(ctx, &storage.Wallet{ID: "missing", Name: "x", Status: storage.StatusActive}); !errors.Is(err, storage.ErrWalletNotExist) {
+		t.Errorf("UpdateWallet: got %v, want %v", err, storage.ErrWalletNotExist)
+	}
+	if _, err := s.DeactivateWallet(ctx, "missing"); !errors.Is(err, storage.ErrWalletNotExist) {
+		t.Errorf("DeactivateWallet: got %v, want %v", err, storage.ErrWalletNotExist)
+	}
+	if _, err := s.BalanceAt(ctx, "missing", time.Now()); !errors.Is(err, storage.ErrWalletNotExist) {
+		t.Errorf("BalanceAt: got %v, want %v", err, storage.ErrWalletNotExist)
+	}
+	if _, err := s.LatestReconciliation(ctx); !errors.Is(err, storage.ErrReconciliationNotFound) {
+		t.Errorf("LatestReconciliation: got %v, want %v", err, storage.ErrReconciliationNotFound)
+	}
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.GetWallet(ctx, "missing"); !errors.Is(err, storage.ErrWalletNotExist) {
+		t.Errorf("Tx.GetWallet: got %v, want %v", err, storage.ErrWalletNotExist)
+	}
+	err = tx.AddOperation(ctx, &storage.Operation{WalletID: "missing", Type: storage.OpDeposit, Amount: 1})
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Tx.AddOperation: got %v, want %v", err, domain.ErrNotFound)
+	}
+}
+
+func testUpdateWallet(t *testing.T, s storage.Storage) {
+	id := createWallet(t, s, "alice")
+	createWallet(t, s, "bob")
+
+	wallet := getWallet(t, s, id)
+	wallet.Name = "carol"
+	wallet.Balance = 12.5
+
+	if _, err := s.UpdateWallet(context.Background(), wallet); err != nil {
+		t.Fatalf("UpdateWallet: %v", err)
+	}
+
+	updated := getWallet(t, s, id)
+	if updated.Name != "carol" || updated.Balance != 12.5 {
+		t.Errorf("updated wallet = %q %v, want carol 12.5", updated.Name, updated.Balance)
+	}
+	if updated.UpdatedAt.Before(updated.CreatedAt) {
+		t.Errorf("updated_at %v is before created_at %v", updated.UpdatedAt, updated.CreatedAt)
+	}
+
+	//freed name can be taken again, taken one can't
+	createWallet(t, s, "alice")
+
+	updated.Name = "bob"
+	if _, err := s.UpdateWallet(context.Background(), updated); err == nil {
+		t.Errorf("UpdateWallet to taken name succeeded")
+	}
+}
+
+func testDeactivateWallet(t *testing.T, s storage.Storage) {
+	id := createWallet(t, s, "alice")
+
+	if _, err := s.DeactivateWallet(context.Background(), id); err != nil {
+		t.Fatalf("DeactivateWallet: %v", err)
+	}
+
+	if wallet := getWallet(t, s, id); wallet.Status != storage.StatusInactive {
+		t.Errorf("status = %q, want %q", wallet.Status, storage.StatusInactive)
+	}
+}
+
+func testListFilters(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	for _, name := range []string{"alpha", "alpine", "beta", "gamma", "al%_"} {
+		createWallet(t, s, name)
+	}
+
+	page, err := s.ListWallets(ctx, storage.WalletQuery{
+		WalletFilter: storage.WalletFilter{Names: []string{"beta", "gamma", "delta"}},
+		SortBy:       storage.SortByName,
+		Limit:        10,
+	})
+	if err != nil {
+		t.Fatalf("ListWallets: %v", err)
+	}
+	if got := names(page.Wallets); !equal(got, []string{"beta", "gamma"}) {
+		t.Errorf("names filter = %v, want [beta gamma]", got)
+	}
+
+	page, err = s.ListWallets(ctx, storage.WalletQuery{
+		WalletFilter: storage.WalletFilter{NamePrefix: "alp"},
+		SortBy:       storage.SortByName,
+		Limit:        10,
+	})
+	if err != nil {
+		t.Fatalf("ListWallets: %v", err)
+	}
+	if got := names(page.Wallets); !equal(got, []string{"alpha", "alpine"}) {
+		t.Errorf("prefix filter = %v, want [alpha alpine]", got)
+	}
+
+	page, err = s.ListWallets(ctx, storage.WalletQuery{
+		WalletFilter: storage.WalletFilter{NamePrefix: "al%"},
+		SortBy:       storage.SortByName,
+		Limit:        10,
+	})
+	if err != nil {
+		t.Fatalf("ListWallets: %v", err)
+	}
+	if got := names(page.Wallets); !equal(got, []string{"al%_"}) {
+		t.Errorf("prefix with wildcard characters = %v, want [al%%_]", got)
+	}
+
+	deposit(t, s, page.Wallets[0].ID, 10)
+	minBalance := 5.0
+
+	page, err = s.ListWallets(ctx, storage.WalletQuery{
+		WalletFilter: storage.WalletFilter{MinBalance: &minBalance, Status: storage.StatusActive},
+		SortBy:       storage.SortByName,
+		Limit:        10,
+	})
+	if err != nil {
+		t.Fatalf("ListWallets: %v", err)
+	}
+	if got := names(page.Wallets); !equal(got, []string{"al%_"}) {
+		t.Errorf("balance filter = %v, want [al%%_]", got)
+	}
+}
+
+func testListPagination(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	var want []string
+	for i := 0; i < 7; i++ {
+		name := fmt.Sprintf("wallet-%02d", i)
+		id := createWallet(t, s, name)
+		deposit(t, s, id, float64(i%3))
+		want = append(want, name)
+	}
+
+	for _, sortBy := range []string{storage.SortByName, storage.SortByBalance, storage.SortByCreatedAt} {
+		for _, desc := range []bool{false, true} {
+			query := storage.WalletQuery{SortBy: sortBy, Desc: desc, Limit: 3, WithTotal: true}
+
+			var got []storage.Wallet
+			for pages := 0; ; pages++ {
+				if pages > 7 {
+					t.Fatalf("%s desc=%v: pagination doesn't end", sortBy, desc)
+				}
+
+				page, err := s.ListWallets(ctx, query)
+				if err != nil {
+					t.Fatalf("%s desc=%v: ListWallets: %v", sortBy, desc, err)
+				}
+				if page.Total == nil || *page.Total != 7 {
+					t.Errorf("%s desc=%v: total = %v, want 7", sortBy, desc, page.Total)
+				}
+
+				got = append(got, page.Wallets...)
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+
+			if len(got) != 7 {
+				t.Fatalf("%s desc=%v: got %d wallets, want 7", sortBy, desc, len(got))
+			}
+
+			sorted := sort.SliceIsSorted(got, func(i, j int) bool {
+				a, b := got[i], got[j]
+				if desc {
+					a, b = b, a
+				}
+				switch sortBy {
+				case storage.SortByBalance:
+					return a.Balance < b.Balance || a.Balance == b.Balance && a.ID < b.ID
+				case storage.SortByCreatedAt:
+					return a.CreatedAt.Before(b.CreatedAt) || a.CreatedAt.Equal(b.CreatedAt) && a.ID < b.ID
+				default:
+					return a.Name < b.Name
+				}
+			})
+			if !sorted {
+				t.Errorf("%s desc=%v: wallets are not sorted: %v", sortBy, desc, names(got))
+			}
+
+			seen := names(got)
+			sort.Strings(seen)
+			if !equal(seen, want) {
+				t.Errorf("%s desc=%v: pages contain %v, want %v", sortBy, desc, seen, want)
+			}
+		}
+	}
+
+	_, err := s.ListWallets(ctx, storage.WalletQuery{SortBy: storage.SortByName, Limit: 3, Cursor: "garbage"})
+	if !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("invalid cursor: got %v, want %v", err, storage.ErrInvalidCursor)
+	}
+}
+
+func testMetadataAndTags(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	alice := createWallet(t, s, "alice")
+	createWallet(t, s, "bob")
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.UpdateMetadata(ctx, alice, map[string]any{"tier": "gold", "level": 3}); err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+	if err := tx.UpdateTags(ctx, alice, []string{"vip", "eu"}); err != nil {
+		t.Fatalf("UpdateTags: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	wallet := getWallet(t, s, alice)
+	if wallet.Metadata["tier"] != "gold" || wallet.Metadata["level"] != float64(3) {
+		t.Errorf("metadata = %#v", wallet.Metadata)
+	}
+	if !equal(wallet.Tags, []string{"eu", "vip"}) {
+		t.Errorf("tags = %v, want sorted [eu vip]", wallet.Tags)
+	}
+
+	for _, filter := range []storage.WalletFilter{
+		{Tags: []string{"vip"}},
+		{Tags: []string{"vip", "eu"}},
+		{Metadata: map[string]string{"tier": "gold"}},
+		{Metadata: map[string]string{"level": "3"}},
+	} {
+		page, err := s.ListWallets(ctx, storage.WalletQuery{WalletFilter: filter, SortBy: storage.SortByName, Limit: 10})
+		if err != nil {
+			t.Fatalf("ListWallets(%+v): %v", filter, err)
+		}
+		if got := names(page.Wallets); !equal(got, []string{"alice"}) {
+			t.Errorf("ListWallets(%+v) = %v, want [alice]", filter, got)
+		}
+	}
+
+	page, err := s.ListWallets(ctx, storage.WalletQuery{
+		WalletFilter: storage.WalletFilter{Tags: []string{"vip", "us"}},
+		SortBy:       storage.SortByName,
+		Limit:        10,
+	})
+	if err != nil {
+		t.Fatalf("ListWallets: %v", err)
+	}
+	if len(page.Wallets) != 0 {
+		t.Errorf("all tags must match, got %v", names(page.Wallets))
+	}
+
+	//replaced metadata no longer matches
+	tx, err = s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	defer tx.Rollback()
+	if err := tx.UpdateMetadata(ctx, alice, map[string]any{"tier": "silver"}); err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	for filter, want := range map[string][]string{"gold": nil, "silver": {"alice"}} {
+		page, err := s.ListWallets(ctx, storage.WalletQuery{
+			WalletFilter: storage.WalletFilter{Metadata: map[string]string{"tier": filter}},
+			SortBy:       storage.SortByName,
+			Limit:        10,
+		})
+		if err != nil {
+			t.Fatalf("ListWallets: %v", err)
+		}
+		if got := names(page.Wallets); !equal(got, want) {
+			t.Errorf("tier %s: ListWallets = %v, want %v", filter, got, want)
+		}
+	}
+}
+
+func testCommit(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	id := createWallet(t, s, "alice")
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := tx.GetWallet(ctx, id)
+	if err != nil {
+		t.Fatalf("Tx.GetWallet: %v", err)
+	}
+
+	wallet.Balance = 42
+	if _, err := tx.UpdateWallet(ctx, wallet); err != nil {
+		t.Fatalf("Tx.UpdateWallet: %v", err)
+	}
+
+	inTx, err := tx.GetWallet(ctx, id)
+	if err != nil {
+		t.Fatalf("Tx.GetWallet: %v", err)
+	}
+	if inTx.Balance != 42 {
+		t.Errorf("transaction doesn't see its own change: balance %v", inTx.Balance)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if balance := getWallet(t, s, id).Balance; balance != 42 {
+		t.Errorf("committed balance = %v, want 42", balance)
+	}
+}
+
+func testRollback(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	id := createWallet(t, s, "alice")
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+
+	wallet, err := tx.GetWallet(ctx, id)
+	if err != nil {
+		t.Fatalf("Tx.GetWallet: %v", err)
+	}
+
+	wallet.Balance = 42
+	if _, err := tx.UpdateWallet(ctx, wallet); err != nil {
+		t.Fatalf("Tx.UpdateWallet: %v", err)
+	}
+	if err := tx.AddOperation(ctx, &storage.Operation{WalletID: id, Type: storage.OpDeposit, Amount: 42}); err != nil {
+		t.Fatalf("Tx.AddOperation: %v", err)
+	}
+	if err := tx.UpdateTags(ctx, id, []string{"vip"}); err != nil {
+		t.Fatalf("Tx.UpdateTags: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+
+	wallet = getWallet(t, s, id)
+	if wallet.Balance != 0 || len(wallet.Tags) != 0 {
+		t.Errorf("rolled back changes are visible: balance %v, tags %v", wallet.Balance, wallet.Tags)
+	}
+
+	balance, err := s.BalanceAt(ctx, id, time.Now())
+	if err != nil {
+		t.Fatalf("BalanceAt: %v", err)
+	}
+	if balance != 0 {
+		t.Errorf("rolled back operation is in journal: balance %v", balance)
+	}
+
+	if err := tx.Commit(); err == nil {
+		t.Errorf("Commit after Rollback succeeded")
+	}
+}
+
+func testTxCreateWallet(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	createWallet(t, s, "alice")
+	openedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	defer tx.Rollback()
+
+	wallet := &storage.Wallet{
+		Name:      "bob",
+		Balance:   10,
+		Metadata:  map[string]any{"source": "legacy"},
+		Tags:      []string{"imported"},
+		CreatedAt: openedAt,
+	}
+	if err := tx.CreateWallet(ctx, wallet); err != nil {
+		t.Fatalf("Tx.CreateWallet: %v", err)
+	}
+	if wallet.ID == "" {
+		t.Fatalf("Tx.CreateWallet didn't set ID")
+	}
+	if err := tx.AddOperation(ctx, &storage.Operation{WalletID: wallet.ID, Type: storage.OpOpeningBalance, Amount: 10, CreatedAt: openedAt}); err != nil {
+		t.Fatalf("Tx.AddOperation: %v", err)
+	}
+
+	if _, err := s.GetWallet(ctx, wallet.ID); !errors.Is(err, storage.ErrWalletNotExist) {
+		t.Errorf("uncommitted wallet is visible: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	created := getWallet(t, s, wallet.ID)
+	if created.Balance != 10 || !created.CreatedAt.Equal(openedAt) || created.Status != storage.StatusActive {
+		t.Errorf("created wallet = %+v", created)
+	}
+	if created.Metadata["source"] != "legacy" || !equal(created.Tags, []string{"imported"}) {
+		t.Errorf("created wallet metadata %v, tags %v", created.Metadata, created.Tags)
+	}
+
+	balance, err := s.BalanceAt(ctx, wallet.ID, openedAt)
+	if err != nil {
+		t.Fatalf("BalanceAt: %v", err)
+	}
+	if balance != 10 {
+		t.Errorf("opening balance = %v, want 10", balance)
+	}
+}
+
+func testOperations(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	alice := createWallet(t, s, "alice")
+	bob := createWallet(t, s, "bob")
+	base := time.Now().UTC().Truncate(time.Millisecond).Add(time.Hour)
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	defer tx.Rollback()
+
+	for i, op := range []storage.Operation{
+		{WalletID: alice, Type: storage.OpDeposit, Amount: 100},
+		{WalletID: alice, Type: storage.OpWithdraw, Amount: 30},
+		{WalletID: alice, Type: storage.OpTransferOut, Amount: 20, Counterparty: bob},
+		{WalletID: bob, Type: storage.OpTransferIn, Amount: 20, Counterparty: alice},
+	} {
+		op.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		if err := tx.AddOperation(ctx, &op); err != nil {
+			t.Fatalf("Tx.AddOperation: %v", err)
+		}
+		if op.ID == "" {
+			t.Fatalf("Tx.AddOperation didn't set ID")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	for _, tt := range []struct {
+		wallet string
+		at     time.Time
+		want   float64
+	}{
+		{alice, base.Add(-time.Second), 0},
+		{alice, base, 100},
+		{alice, base.Add(90 * time.Second), 70},
+		{alice, base.Add(time.Hour), 50},
+		{bob, base.Add(time.Hour), 20},
+	} {
+		balance, err := s.BalanceAt(ctx, tt.wallet, tt.at)
+		if err != nil {
+			t.Fatalf("BalanceAt: %v", err)
+		}
+		if balance != tt.want {
+			t.Errorf("BalanceAt(%s, %v) = %v, want %v", tt.wallet, tt.at, balance, tt.want)
+		}
+	}
+
+	balances, err := s.BalancesAt(ctx, base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("BalancesAt: %v", err)
+	}
+	if len(balances) != 2 || balances[0].Name != "alice" || balances[0].Balance != 50 || balances[1].Balance != 20 {
+		t.Errorf("BalancesAt = %+v", balances)
+	}
+
+	var walked []storage.Operation
+	err = s.WalkOperations(ctx, storage.OperationFilter{WalletID: alice, From: base, To: base.Add(2 * time.Minute)}, func(op storage.Operation) error {
+		walked = append(walked, op)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkOperations: %v", err)
+	}
+	if len(walked) != 2 || walked[0].Type != storage.OpWithdraw || walked[1].Type != storage.OpTransferOut || walked[1].Counterparty != bob {
+		t.Errorf("WalkOperations after From up to To = %+v", walked)
+	}
+	if !walked[0].CreatedAt.Equal(base.Add(time.Minute)) {
+		t.Errorf("operation time = %v, want %v", walked[0].CreatedAt, base.Add(time.Minute))
+	}
+
+	stop := errors.New("stop")
+	if err := s.WalkOperations(ctx, storage.OperationFilter{}, func(storage.Operation) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("WalkOperations doesn't return visit error: %v", err)
+	}
+}
+
+// testConcurrentTransfers moves money between wallets from many goroutines.
+// Transactions lock both wallets in ID order, so lost updates show up as wrong balances.
+func testConcurrentTransfers(t *testing.T, s storage.Storage) {
+	const (
+		wallets   = 4
+		workers   = 8
+		transfers = 10
+		initial   = 1000.0
+	)
+
+	ctx := context.Background()
+
+	ids := make([]string, wallets)
+	for i := range ids {
+		ids[i] = createWallet(t, s, fmt.Sprintf("wallet-%d", i))
+		deposit(t, s, ids[i], initial)
+	}
+
+	transfer := func(from, to string, amount float64) error {
+		tx, err := s.BeginTx(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		first, second := from, to
+		if second < first {
+			first, second = second, first
+		}
+
+		locked := map[string]*storage.Wallet{}
+		for _, id := range []string{first, second} {
+			if locked[id], err = tx.GetWallet(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		//let other transfers run between read and write, lost updates show up only then
+		runtime.Gosched()
+
+		locked[from].Balance -= amount
+		locked[to].Balance += amount
+
+		for _, id := range []string{first, second} {
+			if _, err := tx.UpdateWallet(ctx, locked[id]); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now().UTC()
+		if err := tx.AddOperation(ctx, &storage.Operation{WalletID: from, Type: storage.OpTransferOut, Amount: amount, Counterparty: to, CreatedAt: now}); err != nil {
+			return err
+		}
+		if err := tx.AddOperation(ctx, &storage.Operation{WalletID: to, Type: storage.OpTransferIn, Amount: amount, Counterparty: from, CreatedAt: now}); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*transfers)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			//offset is never a multiple of wallets, so from and to always differ
+			offset := 1 + w%(wallets-1)
+			for i := 0; i < transfers; i++ {
+				from, to := ids[(w+i)%wallets], ids[(w+i+offset)%wallets]
+				if err := transfer(from, to, float64(1+i)); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("transfer: %v", err)
+	}
+
+	ledger, err := s.Ledger(ctx)
+	if err != nil {
+		t.Fatalf("Ledger: %v", err)
+	}
+
+	var total float64
+	for _, balance := range ledger.Balances {
+		total += balance.Balance
+		if balance.Balance != balance.Journal {
+			t.Errorf("wallet %s balance %v differs from journal %v", balance.Name, balance.Balance, balance.Journal)
+		}
+	}
+	if total != wallets*initial {
+		t.Errorf("total balance = %v, want %v", total, wallets*initial)
+	}
+	if ledger.Totals[storage.OpTransferIn] != ledger.Totals[storage.OpTransferOut] {
+		t.Errorf("transfers in %v != out %v", ledger.Totals[storage.OpTransferIn], ledger.Totals[storage.OpTransferOut])
+	}
+}
+
+func testReconciliation(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for i, ok := range []bool{false, true} {
+		report := &storage.Reconciliation{
+			ID:            fmt.Sprintf("report-%d", i),
+			StartedAt:     now.Add(time.Duration(i) * time.Minute),
+			FinishedAt:    now.Add(time.Duration(i)*time.Minute + time.Second),
+			OK:            ok,
+			Discrepancies: []storage.Discrepancy{},
+		}
+		if !ok {
+			report.Discrepancies = append(report.Discrepancies, storage.Discrepancy{Kind: storage.DiscrepancyTotal, Expected: 1, Actual: 2, Difference: 1})
+		}
+
+		if err := s.SaveReconciliation(ctx, report); err != nil {
+			t.Fatalf("SaveReconciliation: %v", err)
+		}
+	}
+
+	latest, err := s.LatestReconciliation(ctx)
+	if err != nil {
+		t.Fatalf("LatestReconciliation: %v", err)
+	}
+	if latest.ID != "report-1" || !latest.OK || len(latest.Discrepancies) != 0 {
+		t.Errorf("latest report = %+v, want report-1", latest)
+	}
+}
+
+func testWebhooks(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	if _, err := s.GetWebhook(ctx, "missing"); !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("GetWebhook: got %v, want %v", err, storage.ErrWebhookNotFound)
+	}
+	if err := s.DeleteWebhook(ctx, "missing"); !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("DeleteWebhook: got %v, want %v", err, storage.ErrWebhookNotFound)
+	}
+
+	for i, id := range []string{"hook-b", "hook-a"} {
+		webhook := &storage.Webhook{
+			ID:         id,
+			URL:        "https://example.com/" + id,
+			EventTypes: []string{"Wallet_Created"},
+			WalletIDs:  []string{},
+			Secret:     "secret",
+			Status:     storage.WebhookActive,
+			CreatedAt:  now.Add(time.Duration(i) * time.Second),
+			UpdatedAt:  now.Add(time.Duration(i) * time.Second),
+		}
+		if err := s.SaveWebhook(ctx, webhook); err != nil {
+			t.Fatalf("SaveWebhook: %v", err)
+		}
+	}
+
+	webhook, err := s.GetWebhook(ctx, "hook-a")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	webhook.Status = storage.WebhookDisabled
+	webhook.Failures = 3
+	if err := s.SaveWebhook(ctx, webhook); err != nil {
+		t.Fatalf("SaveWebhook update: %v", err)
+	}
+
+	webhooks, err := s.ListWebhooks(ctx)
+	if err != nil {
+		t.Fatalf("ListWebhooks: %v", err)
+	}
+	if len(webhooks) != 2 || webhooks[0].ID != "hook-b" || webhooks[1].ID != "hook-a" {
+		t.Fatalf("ListWebhooks = %+v, want hook-b, hook-a", webhooks)
+	}
+	updated := webhooks[1]
+	if updated.Status != storage.WebhookDisabled || updated.Failures != 3 || updated.Secret != "secret" ||
+		len(updated.EventTypes) != 1 || !updated.CreatedAt.Equal(now.Add(time.Second)) {
+		t.Errorf("updated webhook = %+v", updated)
+	}
+
+	if err := s.DeleteWebhook(ctx, "hook-b"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err := s.GetWebhook(ctx, "hook-b"); !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("GetWebhook after delete: got %v, want %v", err, storage.ErrWebhookNotFound)
+	}
+}
+
+func testDeliveries(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for _, id := range []string{"hook-1", "hook-2"} {
+		if err := s.SaveWebhook(ctx, &storage.Webhook{ID: id, URL: "https://example.com", Status: storage.WebhookActive, CreatedAt: now}); err != nil {
+			t.Fatalf("SaveWebhook: %v", err)
+		}
+	}
+
+	err := s.SaveDelivery(ctx, &storage.Delivery{ID: "orphan", WebhookID: "missing", Payload: []byte(`{}`), CreatedAt: now})
+	if !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("SaveDelivery of unknown webhook: got %v, want %v", err, storage.ErrWebhookNotFound)
+	}
+
+	//delivery-0 is the oldest, delivery-2 is not due yet
+	for i := 0; i < 3; i++ {
+		delivery := &storage.Delivery{
+			ID:            fmt.Sprintf("delivery-%d", i),
+			WebhookID:     "hook-1",
+			EventType:     "Wallet_Created",
+			Payload:       []byte(fmt.Sprintf(`{"n":%d}`, i)),
+			Status:        storage.DeliveryPending,
+			NextAttemptAt: now.Add(time.Duration(i-1) * time.Minute),
+			CreatedAt:     now.Add(time.Duration(i) * time.Second),
+			UpdatedAt:     now.Add(time.Duration(i) * time.Second),
+		}
+		if err := s.SaveDelivery(ctx, delivery); err != nil {
+			t.Fatalf("SaveDelivery: %v", err)
+		}
+	}
+	if err := s.SaveDelivery(ctx, &storage.Delivery{ID: "other", WebhookID: "hook-2", Payload: []byte(`{}`), Status: storage.DeliverySucceeded, NextAttemptAt: now, CreatedAt: now}); err != nil {
+		t.Fatalf("SaveDelivery: %v", err)
+	}
+
+	lease := now.Add(time.Hour)
+
+	due, err := s.ClaimDeliveries(ctx, now, lease, 1)
+	if err != nil {
+		t.Fatalf("ClaimDeliveries: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != "delivery-0" || !due[0].NextAttemptAt.Equal(lease) {
+		t.Errorf("ClaimDeliveries = %+v, want delivery-0 leased till %v", due, lease)
+	}
+
+	//claimed deliveries are skipped till their lease expires
+	if due, err = s.ClaimDeliveries(ctx, now, lease, 10); err != nil || len(due) != 1 || due[0].ID != "delivery-1" {
+		t.Errorf("ClaimDeliveries of leased = %+v, %v, want delivery-1", due, err)
+	}
+	if due, err = s.ClaimDeliveries(ctx, now, lease, 10); err != nil || len(due) != 0 {
+		t.Errorf("ClaimDeliveries of all leased = %+v, %v, want none", due, err)
+	}
+	if due, err = s.ClaimDeliveries(ctx, lease, lease.Add(time.Hour), 10); err != nil || len(due) != 3 || due[0].ID != "delivery-0" {
+		t.Errorf("ClaimDeliveries after lease = %+v, %v, want all deliveries of hook-1", due, err)
+	}
+
+	delivery, err := s.GetDelivery(ctx, "hook-1", "delivery-0")
+	if err != nil {
+		t.Fatalf("GetDelivery: %v", err)
+	}
+	if string(delivery.Payload) != `{"n":0}` {
+		t.Errorf("payload = %s, want {\"n\":0}", delivery.Payload)
+	}
+
+	delivery.Status = storage.DeliverySucceeded
+	delivery.Attempts = 1
+	delivery.ResponseStatus = 200
+	if err := s.SaveDelivery(ctx, delivery); err != nil {
+		t.Fatalf("SaveDelivery update: %v", err)
+	}
+
+	if due, err = s.ClaimDeliveries(ctx, lease.Add(2*time.Hour), lease, 10); err != nil || len(due) != 2 || due[0].ID != "delivery-1" {
+		t.Errorf("ClaimDeliveries after success = %+v, %v, want delivery-1, delivery-2", due, err)
+	}
+
+	if _, err := s.GetDelivery(ctx, "hook-2", "delivery-0"); !errors.Is(err, storage.ErrDeliveryNotFound) {
+		t.Errorf("GetDelivery of another webhook: got %v, want %v", err, storage.ErrDeliveryNotFound)
+	}
+
+	latest, err := s.ListDeliveries(ctx, "hook-1", 2)
+	if err != nil {
+		t.Fatalf("ListDeliveries: %v", err)
+	}
+	if len(latest) != 2 || latest[0].ID != "delivery-2" || latest[1].ID != "delivery-1" {
+		t.Errorf("ListDeliveries = %+v, want delivery-2, delivery-1", latest)
+	}
+
+	if err := s.DeleteWebhook(ctx, "hook-1"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err := s.GetDelivery(ctx, "hook-1", "delivery-0"); !errors.Is(err, storage.ErrDeliveryNotFound) {
+		t.Errorf("GetDelivery after webhook delete: got %v, want %v", err, storage.ErrDeliveryNotFound)
+	}
+}
+
+func testWebhookFailures(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	if err := s.SaveWebhook(ctx, &storage.Webhook{ID: "hook", URL: "https://example.com", Status: storage.WebhookActive, CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("SaveWebhook: %v", err)
+	}
+
+	for want := 1; want <= 3; want++ {
+		webhook, err := s.AddWebhookFailure(ctx, "hook", 3)
+		if err != nil {
+			t.Fatalf("AddWebhookFailure: %v", err)
+		}
+		wantStatus := storage.WebhookActive
+		if want == 3 {
+			wantStatus = storage.WebhookDisabled
+		}
+		if webhook.Failures != want || webhook.Status != wantStatus {
+			t.Errorf("failure %d: webhook has %d failures and status %s, want %s", want, webhook.Failures, webhook.Status, wantStatus)
+		}
+	}
+
+	if err := s.ResetWebhookFailures(ctx, "hook"); err != nil {
+		t.Fatalf("ResetWebhookFailures: %v", err)
+	}
+	webhook, err := s.GetWebhook(ctx, "hook")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if webhook.Failures != 0 || webhook.Status != storage.WebhookDisabled {
+		t.Errorf("after reset webhook has %d failures and status %s, want 0 and %s", webhook.Failures, webhook.Status, storage.WebhookDisabled)
+	}
+
+	//without the limit webhook is never disabled
+	if err := s.SaveWebhook(ctx, &storage.Webhook{ID: "unlimited", URL: "https://example.com", Status: storage.WebhookActive, CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("SaveWebhook: %v", err)
+	}
+	if webhook, err := s.AddWebhookFailure(ctx, "unlimited", 0); err != nil || webhook.Status != storage.WebhookActive {
+		t.Errorf("AddWebhookFailure without limit = %+v, %v", webhook, err)
+	}
+
+	if _, err := s.AddWebhookFailure(ctx, "missing", 3); !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("AddWebhookFailure of unknown webhook: got %v, want %v", err, storage.ErrWebhookNotFound)
+	}
+	if err := s.ResetWebhookFailures(ctx, "missing"); err != nil {
+		t.Errorf("ResetWebhookFailures of unknown webhook: %v", err)
+	}
+}
+
+func testEventSeq(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := createWallet(t, s, "alice")
+	bob := createWallet(t, s, "bob")
+
+	for want := int64(1); want <= 2; want++ {
+		seq, err := s.NextEventSeq(ctx, alice)
+		if err != nil {
+			t.Fatalf("NextEventSeq: %v", err)
+		}
+		if seq != want {
+			t.Errorf("NextEventSeq = %d, want %d", seq, want)
+		}
+	}
+
+	//sequences are per wallet
+	if seq, err := s.NextEventSeq(ctx, bob); err != nil || seq != 1 {
+		t.Errorf("NextEventSeq of another wallet = %d, %v, want 1", seq, err)
+	}
+
+	if _, err := s.NextEventSeq(ctx, "missing"); !errors.Is(err, storage.ErrWalletNotExist) {
+		t.Errorf("NextEventSeq of missing wallet error = %v, want ErrWalletNotExist", err)
+	}
+
+	//rolled back sequence numbers are taken again, consumers would see them as gaps otherwise
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	if seq, err := tx.NextEventSeq(ctx, alice); err != nil || seq != 3 {
+		t.Errorf("Tx.NextEventSeq = %d, %v, want 3", seq, err)
+	}
+	if seq, err := tx.NextEventSeq(ctx, alice); err != nil || seq != 4 {
+		t.Errorf("second Tx.NextEventSeq = %d, %v, want 4", seq, err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+
+	tx, err = s.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	if seq, err := tx.NextEventSeq(ctx, alice); err != nil || seq != 3 {
+		t.Errorf("Tx.NextEventSeq after rollback = %d, %v, want 3", seq, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if seq, err := s.NextEventSeq(ctx, alice); err != nil || seq != 4 {
+		t.Errorf("NextEventSeq after commit = %d, %v, want 4", seq, err)
+	}
+}
@@ -0,0 +1,396 @@
+package walletctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"wallet/pkg/client"
+)
+
+func createCmd(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	args, err := e.parse(flags, args, "NAME")
+	if err != nil {
+		return err
+	}
+
+	wallet, err := e.wallets.CreateWallet(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return showCmd(ctx, e, []string{wallet.ID})
+}
+
+// listFlags registers filters of list and search
+func listFlags(flags *flag.FlagSet, opts *client.ListOptions) (all *bool) {
+	flags.Var((*stringsFlag)(&opts.Tags), "tag", "wallet must have the tag, repeatable")
+	flags.Var((*metadataFlag)(&opts.Metadata), "meta", "wallet metadata key=value, repeatable")
+	flags.StringVar(&opts.Status, "status", "", "active (default), inactive or all")
+	flags.StringVar(&opts.NamePrefix, "prefix", "", "wallet name prefix")
+	flags.Var(floatFlag{&opts.MinBalance}, "min-balance", "minimal balance")
+	flags.Var(floatFlag{&opts.MaxBalance}, "max-balance", "maximal balance")
+	flags.StringVar(&opts.Sort, "sort", "", "name, balance or created_at (default)")
+	flags.BoolVar(&opts.Desc, "desc", false, "descending order")
+	flags.IntVar(&opts.Limit, "limit", 0, "page size")
+	flags.StringVar(&opts.Cursor, "cursor", "", "next_cursor of the previous page")
+	flags.BoolVar(&opts.WithTotal, "total", false, "count all matching wallets")
+	return flags.Bool("all", false, "fetch all pages")
+}
+
+func listCmd(ctx context.Context, e *env, args []string) error {
+	var opts client.ListOptions
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	all := listFlags(flags, &opts)
+	if _, err := e.parse(flags, args); err != nil {
+		return err
+	}
+
+	return list(ctx, e, opts, *all)
+}
+
+func searchCmd(ctx context.Context, e *env, args []string) error {
+	var opts client.ListOptions
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	all := listFlags(flags, &opts)
+	args, err := e.parse(flags, args, "PREFIX")
+	if err != nil {
+		return err
+	}
+	opts.NamePrefix = args[0]
+
+	return list(ctx, e, opts, *all)
+}
+
+func list(ctx context.Context, e *env, opts client.ListOptions, all bool) error {
+	page, err := e.wallets.ListWallets(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	for all && page.NextCursor != "" {
+		opts.Cursor, opts.WithTotal = page.NextCursor, false
+
+		next, err := e.wallets.ListWallets(ctx, opts)
+		if err != nil {
+			return err
+		}
+		page.Wallets = append(page.Wallets, next.Wallets...)
+		page.NextCursor = next.NextCursor
+	}
+
+	if err := e.out.wallets(page.Wallets, page); err != nil {
+		return err
+	}
+
+	//paging hints are part of JSON output, table shows them after rows
+	if e.out.format == outputTable {
+		if page.Total != nil {
+			fmt.Fprintf(e.stdout, "\ntotal: %d\n", *page.Total)
+		}
+		if page.NextCursor != "" {
+			fmt.Fprintf(e.stdout, "\nnext page: -cursor %s\n", page.NextCursor)
+		}
+	}
+
+	return nil
+}
+
+func showCmd(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("show", flag.ContinueOnError)
+	args, err := e.parse(flags, args, "ID")
+	if err != nil {
+		return err
+	}
+
+	wallet, err := e.wallets.GetWallet(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return e.out.wallet(wallet)
+}
+
+func depositCmd(ctx context.Context, e *env, args []string) error {
+	return balanceOperation(ctx, e, "deposit", args, e.wallets.Deposit)
+}
+
+func withdrawCmd(ctx context.Context, e *env, args []string) error {
+	return balanceOperation(ctx, e, "withdraw", args, e.wallets.Withdraw)
+}
+
+// balanceOperation runs deposit or withdraw and shows the wallet after it
+func balanceOperation(ctx context.Context, e *env, name string, args []string, op func(ctx context.Context, id string, amount float64) error) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	args, err := e.parse(flags, args, "ID", "AMOUNT")
+	if err != nil {
+		return err
+	}
+
+	amount, err := parseAmount(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := op(ctx, args[0], amount); err != nil {
+		return err
+	}
+
+	return showCmd(ctx, e, args[:1])
+}
+
+func transferCmd(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("transfer", flag.ContinueOnError)
+	args, err := e.parse(flags, args, "FROM_ID", "TO_ID", "AMOUNT")
+	if err != nil {
+		return err
+	}
+
+	amount, err := parseAmount(args[2])
+	if err != nil {
+		return err
+	}
+
+	if err := e.wallets.Transfer(ctx, args[0], args[1], amount); err != nil {
+		return err
+	}
+
+	wallets := make([]client.Wallet, 0, 2)
+	for _, id := range args[:2] {
+		wallet, err := e.wallets.GetWallet(ctx, id)
+		if err != nil {
+			return err
+		}
+		wallets = append(wallets, *wallet)
+	}
+
+	return e.out.wallets(wallets, wallets)
+}
+
+func renameCmd(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("rename", flag.ContinueOnError)
+	args, err := e.parse(flags, args, "ID", "NAME")
+	if err != nil {
+		return err
+	}
+
+	if err := e.wallets.RenameWallet(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+
+	return showCmd(ctx, e, args[:1])
+}
+
+func deactivateCmd(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("deactivate", flag.ContinueOnError)
+	args, err := e.parse(flags, args, "ID")
+	if err != nil {
+		return err
+	}
+
+	if err := e.wallets.DeactivateWallet(ctx, args[0]); err != nil {
+		return err
+	}
+
+	return e.out.wallet(&client.Wallet{ID: args[0], Status: client.StatusInactive})
+}
+
+func exportCmd(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("export: journal or statement is required")
+	}
+
+	what, args := args[0], args[1:]
+
+	var opts client.PeriodOptions
+	flags := flag.NewFlagSet("export "+what, flag.ContinueOnError)
+	flags.Var((*timeFlag)(&opts.From), "from", "operations after this time, RFC3339 or YYYY-MM-DD")
+	flags.Var((*timeFlag)(&opts.To), "to", "operations up to and including this time, RFC3339 or YYYY-MM-DD")
+	file := flags.String("file", "", "output file, stdout by default")
+
+	var stream io.ReadCloser
+
+	switch what {
+	case "journal":
+		flags.StringVar(&opts.Format, "format", client.JournalBeancount, "beancount or ledger")
+		if _, err := e.parse(flags, args); err != nil {
+			return err
+		}
+
+		var err error
+		if stream, err = e.wallets.ExportJournal(ctx, opts); err != nil {
+			return err
+		}
+	case "statement":
+		flags.StringVar(&opts.Format, "format", client.StatementCSV, "json, csv or ofx")
+		args, err := e.parse(flags, args, "ID")
+		if err != nil {
+			return err
+		}
+
+		if stream, err = e.wallets.Statement(ctx, args[0], opts); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("export: unknown export %q, use journal or statement", what)
+	}
+	defer stream.Close()
+
+	out := e.stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return fmt.Errorf("can't create output file: %w", err)
+		}
+		defer f.Close()
+
+		out = f
+	}
+
+	_, err := io.Copy(out, stream)
+	return err
+}
+
+func statsCmd(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	if _, err := e.parse(flags, args); err != nil {
+		return err
+	}
+
+	stats, err := e.stats.WalletStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.out.stats(stats)
+}
+
+// parse parses flags placed anywhere among arguments and checks the number of positional arguments.
+// Output format flag is accepted by every command.
+func (e *env) parse(flags *flag.FlagSet, args []string, names ...string) ([]string, error) {
+	flags.StringVar(&e.out.format, "o", e.out.format, "output format: table or json")
+
+	if len(names) > 0 {
+		flags.Usage = func() {
+			fmt.Fprintf(flags.Output(), "Usage: walletctl %s [flags] %s\n", flags.Name(), strings.Join(names, " "))
+			flags.PrintDefaults()
+		}
+	}
+
+	var positional []string
+	for len(args) > 0 {
+		//negative numbers are arguments, not flags
+		if _, err := strconv.ParseFloat(args[0], 64); err == nil {
+			positional, args = append(positional, args[0]), args[1:]
+			continue
+		}
+
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	if len(positional) != len(names) {
+		flags.Usage()
+		return nil, fmt.Errorf("%s: expected arguments %s", flags.Name(), strings.Join(names, " "))
+	}
+	if e.out.format != outputTable && e.out.format != outputJSON {
+		return nil, fmt.Errorf("unknown output format %q, use table or json", e.out.format)
+	}
+
+	return positional, nil
+}
+
+func parseAmount(raw string) (float64, error) {
+	amount, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", raw)
+	}
+	return amount, nil
+}
+
+// stringsFlag collects values of repeated flag
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// metadataFlag collects key=value pairs of repeated flag
+type metadataFlag map[string]string
+
+func (f *metadataFlag) String() string {
+	return ""
+}
+
+func (f *metadataFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.New("key=value expected")
+	}
+	if *f == nil {
+		*f = make(map[string]string)
+	}
+	(*f)[key] = val
+	return nil
+}
+
+// floatFlag sets optional number
+type floatFlag struct {
+	value **float64
+}
+
+func (f floatFlag) String() string {
+	if f.value == nil || *f.value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(**f.value, 'f', -1, 64)
+}
+
+func (f floatFlag) Set(value string) error {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return errors.New("number expected")
+	}
+	*f.value = &v
+	return nil
+}
+
+// timeFlag accepts RFC3339 time or date, dates are taken in the local time zone
+type timeFlag time.Time
+
+func (f *timeFlag) String() string {
+	if f == nil || time.Time(*f).IsZero() {
+		return ""
+	}
+	return time.Time(*f).Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(value string) error {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		*f = timeFlag(t)
+		return nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return errors.New("RFC3339 time or YYYY-MM-DD date expected")
+	}
+	*f = timeFlag(t)
+	return nil
+}
@@ -0,0 +1,95 @@
+package walletctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	statsclient "stats/pkg/client"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"wallet/pkg/client"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer writes command results as JSON or as aligned table
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// print encodes v as JSON or calls table to write rows, the first row is a header
+func (p printer) print(v any, table func(t *tabwriter.Writer)) error {
+	if p.format == outputJSON {
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	t := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	table(t)
+	return t.Flush()
+}
+
+func (p printer) wallets(wallets []client.Wallet, v any) error {
+	return p.print(v, func(t *tabwriter.Writer) {
+		fmt.Fprintln(t, "ID\tNAME\tBALANCE\tSTATUS\tTAGS\tCREATED AT")
+		for _, w := range wallets {
+			fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				w.ID, w.Name, amount(w.Balance), w.Status, strings.Join(w.Tags, ","), timestamp(w.CreatedAt))
+		}
+	})
+}
+
+// wallet prints details of one wallet as key-value table
+func (p printer) wallet(w *client.Wallet) error {
+	return p.print(w, func(t *tabwriter.Writer) {
+		fmt.Fprintf(t, "ID\t%s\n", w.ID)
+		fmt.Fprintf(t, "NAME\t%s\n", w.Name)
+		fmt.Fprintf(t, "BALANCE\t%s\n", amount(w.Balance))
+		fmt.Fprintf(t, "STATUS\t%s\n", w.Status)
+		fmt.Fprintf(t, "TAGS\t%s\n", strings.Join(w.Tags, ","))
+
+		keys := make([]string, 0, len(w.Metadata))
+		for key := range w.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, _ := json.Marshal(w.Metadata[key])
+			fmt.Fprintf(t, "META %s\t%s\n", key, value)
+		}
+
+		fmt.Fprintf(t, "CREATED AT\t%s\n", timestamp(w.CreatedAt))
+		fmt.Fprintf(t, "UPDATED AT\t%s\n", timestamp(w.UpdatedAt))
+	})
+}
+
+func (p printer) stats(s *statsclient.Stats) error {
+	return p.print(s, func(t *tabwriter.Writer) {
+		fmt.Fprintf(t, "TOTAL\t%d\n", s.Total)
+		fmt.Fprintf(t, "ACTIVE\t%d\n", s.Active)
+		fmt.Fprintf(t, "INACTIVE\t%d\n", s.Inactive)
+		fmt.Fprintf(t, "DEPOSITED\t%s\n", amount(s.Deposited))
+		fmt.Fprintf(t, "WITHDRAWN\t%s\n", amount(s.Withdrawn))
+		fmt.Fprintf(t, "TRANSFERRED\t%s\n", amount(s.Transfered))
+	})
+}
+
+func amount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,116 @@
+package walletctl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// Config is a walletctl configuration file, by default $XDG_CONFIG_HOME/walletctl/config.yaml:
+//
+//	current: local
+//	profiles:
+//	  local:
+//	    wallet_url: http://localhost:8081
+//	    stats_url: http://localhost:8082
+//	  prod:
+//	    wallet_url: https://wallet.example.com
+//	    stats_url: https://stats.example.com
+//	    output: json
+//	    timeout: 30s
+type Config struct {
+	Current  string             `yaml:"current"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile describes an environment walletctl talks to
+type Profile struct {
+	WalletURL string        `yaml:"wallet_url"`
+	StatsURL  string        `yaml:"stats_url"`
+	Output    string        `yaml:"output"`  //table or json
+	Timeout   time.Duration `yaml:"timeout"` //of one command including retries
+}
+
+const defaultProfile = "local"
+
+// localProfile is used when config file does not exist
+var localProfile = Profile{
+	WalletURL: "http://localhost:8081",
+	StatsURL:  "http://localhost:8082",
+	Output:    outputTable,
+	Timeout:   30 * time.Second,
+}
+
+// configPath returns WALLETCTL_CONFIG or the default path
+func configPath() string {
+	if path := os.Getenv("WALLETCTL_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "walletctl.yaml"
+	}
+	return filepath.Join(dir, "walletctl", "config.yaml")
+}
+
+// loadConfig reads config file, missing file gives config with the local profile only
+func loadConfig(path string) (*Config, error) {
+	var config Config
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return &Config{Current: defaultProfile, Profiles: map[string]Profile{defaultProfile: localProfile}}, nil
+	}
+
+	if err := cleanenv.ReadConfig(path, &config); err != nil {
+		return nil, fmt.Errorf("can't read config %s: %w", path, err)
+	}
+
+	return &config, nil
+}
+
+// profile returns named profile, current one when name is empty. Unset fields are taken from the local profile.
+func (c *Config) profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		name = defaultProfile
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if name == defaultProfile {
+			return localProfile, nil
+		}
+		return Profile{}, fmt.Errorf("unknown profile %q, available: %v", name, c.names())
+	}
+
+	if profile.WalletURL == "" {
+		profile.WalletURL = localProfile.WalletURL
+	}
+	if profile.StatsURL == "" {
+		profile.StatsURL = localProfile.StatsURL
+	}
+	if profile.Output == "" {
+		profile.Output = localProfile.Output
+	}
+	if profile.Timeout <= 0 {
+		profile.Timeout = localProfile.Timeout
+	}
+
+	return profile, nil
+}
+
+func (c *Config) names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,209 @@
+// Package walletctl implements the walletctl admin tool, which manages wallets through the HTTP API.
+package walletctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	statsclient "stats/pkg/client"
+	"strings"
+	"wallet/pkg/client"
+)
+
+const usage = `Usage: walletctl [global flags] <command> [flags] [args]
+
+Commands:
+  create NAME                     create wallet
+  list [flags]                    list wallets
+  search PREFIX [flags]           list wallets with names starting with PREFIX
+  show ID                         show wallet details
+  deposit ID AMOUNT               deposit to wallet
+  withdraw ID AMOUNT              withdraw from wallet
+  transfer FROM_ID TO_ID AMOUNT   transfer between wallets
+  rename ID NAME                  rename wallet
+  deactivate ID                   deactivate wallet
+  export journal [flags]          export double-entry journal of all operations
+  export statement ID [flags]     export wallet statement
+  stats                           show wallet statistics
+  profiles                        list configured profiles
+
+Run "walletctl <command> -h" for command flags.
+
+Global flags:
+`
+
+// env is what commands need to run
+type env struct {
+	wallets *client.Client
+	stats   *statsclient.Client
+	out     printer
+	stdout  io.Writer
+}
+
+type command func(ctx context.Context, e *env, args []string) error
+
+var commands = map[string]command{
+	"create":     createCmd,
+	"list":       listCmd,
+	"search":     searchCmd,
+	"show":       showCmd,
+	"deposit":    depositCmd,
+	"withdraw":   withdrawCmd,
+	"transfer":   transferCmd,
+	"rename":     renameCmd,
+	"deactivate": deactivateCmd,
+	"export":     exportCmd,
+	"stats":      statsCmd,
+}
+
+// Run executes walletctl command line, output is written to stdout
+func Run(ctx context.Context, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("walletctl", flag.ContinueOnError)
+	profileName := flags.String("profile", os.Getenv("WALLETCTL_PROFILE"), "profile of the config file, the current one by default")
+	configFile := flags.String("config", configPath(), "config file with profiles")
+	walletURL := flags.String("url", "", "wallet service URL, overrides profile")
+	statsURL := flags.String("stats-url", "", "stats service URL, overrides profile")
+	output := flags.String("o", "", "output format: table or json, overrides profile")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("command is required")
+	}
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	name, args := flags.Arg(0), flags.Args()[1:]
+
+	if name == "profiles" {
+		return profilesCmd(stdout, config)
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	profile, err := config.profile(*profileName)
+	if err != nil {
+		return err
+	}
+	if *walletURL != "" {
+		profile.WalletURL = *walletURL
+	}
+	if *statsURL != "" {
+		profile.StatsURL = *statsURL
+	}
+	if *output != "" {
+		profile.Output = *output
+	}
+	if profile.Output != outputTable && profile.Output != outputJSON {
+		return fmt.Errorf("unknown output format %q, use table or json", profile.Output)
+	}
+
+	wallets, err := client.New(profile.WalletURL, client.WithUserAgent("walletctl"))
+	if err != nil {
+		return err
+	}
+	stats, err := statsclient.New(profile.StatsURL, statsclient.WithUserAgent("walletctl"))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, profile.Timeout)
+	defer cancel()
+
+	return cmd(ctx, &env{
+		wallets: wallets,
+		stats:   stats,
+		out:     printer{w: stdout, format: profile.Output},
+		stdout:  stdout,
+	}, args)
+}
+
+// Describe formats error for operators, API errors are shown with code, request ID and invalid fields
+func Describe(err error) string {
+	var (
+		apiErr   *client.Error
+		statsErr *statsclient.Error
+	)
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.As(err, &statsErr):
+		apiErr = fromStats(statsErr)
+	default:
+		return err.Error()
+	}
+
+	var b strings.Builder
+	b.WriteString(apiErr.Detail)
+	if apiErr.Detail == "" {
+		b.WriteString(apiErr.Title)
+	}
+	fmt.Fprintf(&b, " (%s", apiErr.Code)
+	if apiErr.RequestID != "" {
+		fmt.Fprintf(&b, ", request %s", apiErr.RequestID)
+	}
+	b.WriteString(")")
+	for _, field := range apiErr.Fields {
+		fmt.Fprintf(&b, "\n  %s: %s", field.Field, field.Message)
+	}
+
+	return b.String()
+}
+
+// fromStats converts problem of the stats service, it has the same shape as the wallet one
+func fromStats(e *statsclient.Error) *client.Error {
+	apiErr := &client.Error{
+		StatusCode: e.StatusCode,
+		Type:       e.Type,
+		Title:      e.Title,
+		Detail:     e.Detail,
+		Instance:   e.Instance,
+		Code:       e.Code,
+		RequestID:  e.RequestID,
+	}
+	for _, field := range e.Fields {
+		apiErr.Fields = append(apiErr.Fields, client.FieldError{Field: field.Field, Code: field.Code, Message: field.Message})
+	}
+
+	return apiErr
+}
+
+func profilesCmd(stdout io.Writer, config *Config) error {
+	names := config.names()
+	if len(names) == 0 {
+		names = []string{defaultProfile}
+	}
+	sort.Strings(names)
+
+	current := config.Current
+	if current == "" {
+		current = defaultProfile
+	}
+
+	for _, name := range names {
+		profile, _ := config.profile(name)
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		fmt.Fprintf(stdout, "%s %s\t%s\t%s\n", marker, name, profile.WalletURL, profile.StatsURL)
+	}
+
+	return nil
+}
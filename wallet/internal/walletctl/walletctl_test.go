@@ -0,0 +1,215 @@
+package walletctl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const walletJSON = `{"id":"w1","name":"alice","balance":10.5,"status":"active","tags":["a","b"],"metadata":{"tier":"gold"},"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`
+
+// recorder is a fake of the wallet and stats services which remembers mutating and list requests
+type recorder struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (rec *recorder) record(r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	request := r.Method + " " + r.URL.RequestURI()
+	if len(body) > 0 {
+		request += " " + string(body)
+	}
+
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, request)
+	rec.mu.Unlock()
+}
+
+func (rec *recorder) handler() http.Handler {
+	respond := func(w http.ResponseWriter, contentType string, status int, body string) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /wallets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, "application/json", http.StatusOK, walletJSON)
+	})
+	mux.HandleFunc("GET /wallets", func(w http.ResponseWriter, r *http.Request) {
+		rec.record(r)
+		respond(w, "application/json", http.StatusOK, `{"wallets":[`+walletJSON+`],"total":1}`)
+	})
+	mux.HandleFunc("POST /wallets/w1/{operation}", func(w http.ResponseWriter, r *http.Request) {
+		rec.record(r)
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET /wallets/missing", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, "application/problem+json", http.StatusNotFound,
+			`{"status":404,"title":"Not found","detail":"wallet not found","code":"wallet_not_found","request_id":"req-1"}`)
+	})
+	mux.HandleFunc("GET /stats/wallets", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, "application/json", http.StatusOK,
+			`{"total":3,"active":2,"inactive":1,"deposited":100,"withdrawn":20.5,"transfered":5}`)
+	})
+	return mux
+}
+
+func TestRun(t *testing.T) {
+	rec := &recorder{}
+	wallets := httptest.NewServer(rec.handler())
+	defer wallets.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"status":500,"title":"Internal error","detail":"storage is down","code":"internal","request_id":"req-2"}`)
+	}))
+	defer broken.Close()
+
+	tests := []struct {
+		name        string
+		args        []string
+		statsURL    string //wallets server by default
+		wantOut     string
+		wantErr     string //described error
+		wantRequest string
+	}{
+		{
+			name: "show table",
+			args: []string{"show", "w1"},
+			wantOut: "ID          w1\n" +
+				"NAME        alice\n" +
+				"BALANCE     10.50\n" +
+				"STATUS      active\n" +
+				"TAGS        a,b\n" +
+				"META tier   \"gold\"\n" +
+				"CREATED AT  -\n" +
+				"UPDATED AT  -\n",
+		},
+		{
+			name:    "show json flag after argument",
+			args:    []string{"show", "w1", "-o", "json"},
+			wantOut: `"name": "alice"`,
+		},
+		{
+			name:    "show not found",
+			args:    []string{"show", "missing"},
+			wantErr: "wallet not found (wallet_not_found, request req-1)",
+		},
+		{
+			name:    "show without ID",
+			args:    []string{"show"},
+			wantErr: "show: expected arguments ID",
+		},
+		{
+			name:        "deposit negative amount is an argument",
+			args:        []string{"deposit", "w1", "-5"},
+			wantOut:     "ID          w1\n",
+			wantRequest: `POST /wallets/w1/deposit {"amount":-5}`,
+		},
+		{
+			name:    "deposit invalid amount",
+			args:    []string{"deposit", "w1", "ten"},
+			wantErr: `invalid amount "ten"`,
+		},
+		{
+			name:        "transfer",
+			args:        []string{"transfer", "w1", "w2", "2.5"},
+			wantRequest: `POST /wallets/w1/transfer {"amount":2.5,"transfer_to":"w2"}`,
+		},
+		{
+			name:        "list filters",
+			args:        []string{"list", "-tag", "a", "-tag", "b", "-limit", "2", "-desc", "-total"},
+			wantOut:     "\ntotal: 1\n",
+			wantRequest: "GET /wallets?limit=2&order=desc&tag=a&tag=b&total=true",
+		},
+		{
+			name: "stats table",
+			args: []string{"stats"},
+			wantOut: "TOTAL        3\n" +
+				"ACTIVE       2\n" +
+				"INACTIVE     1\n" +
+				"DEPOSITED    100.00\n" +
+				"WITHDRAWN    20.50\n" +
+				"TRANSFERRED  5.00\n",
+		},
+		{
+			name: "stats json",
+			args: []string{"-o", "json", "stats"},
+			wantOut: "{\n" +
+				"  \"total\": 3,\n" +
+				"  \"active\": 2,\n" +
+				"  \"inactive\": 1,\n" +
+				"  \"deposited\": 100,\n" +
+				"  \"withdrawn\": 20.5,\n" +
+				"  \"transfered\": 5\n" +
+				"}\n",
+		},
+		{
+			name:     "stats error",
+			args:     []string{"stats"},
+			statsURL: broken.URL,
+			wantErr:  "storage is down (internal, request req-2)",
+		},
+		{
+			name:    "unknown output format",
+			args:    []string{"stats", "-o", "xml"},
+			wantErr: `unknown output format "xml", use table or json`,
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"drop"},
+			wantErr: `unknown command "drop"`,
+		},
+	}
+
+	config := filepath.Join(t.TempDir(), "missing.yaml")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec.mu.Lock()
+			rec.requests = nil
+			rec.mu.Unlock()
+
+			statsURL := tt.statsURL
+			if statsURL == "" {
+				statsURL = wallets.URL
+			}
+			args := append([]string{"-config", config, "-url", wallets.URL, "-stats-url", statsURL}, tt.args...)
+
+			var out bytes.Buffer
+			err := Run(context.Background(), args, &out)
+
+			if tt.wantErr != "" {
+				if err == nil || Describe(err) != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run: %s", Describe(err))
+			}
+
+			if got := out.String(); tt.wantOut != "" && !strings.Contains(got, tt.wantOut) {
+				t.Errorf("output:\n%s\nwant it to contain:\n%s", got, tt.wantOut)
+			}
+			if tt.wantRequest != "" {
+				rec.mu.Lock()
+				requests := rec.requests
+				rec.mu.Unlock()
+				if len(requests) != 1 || requests[0] != tt.wantRequest {
+					t.Errorf("requests = %q, want %q", requests, tt.wantRequest)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"events"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"wallet/internal/publisher"
+	"wallet/internal/storage"
+)
+
+// Headers of delivery requests
+const (
+	SignatureHeader = "X-Wallet-Signature" //sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+	TimestampHeader = "X-Wallet-Timestamp" //unix seconds of the attempt
+	EventHeader     = "X-Wallet-Event"
+	DeliveryHeader  = "X-Wallet-Delivery" //same for all attempts, receivers use it to skip duplicates
+	WebhookHeader   = "X-Wallet-Webhook"
+)
+
+const signaturePrefix = "sha256="
+
+// maxErrorLength limits stored error of failed attempt
+const maxErrorLength = 512
+
+// batchRounds is how many deliveries a worker gets from one claimed batch
+const batchRounds = 4
+
+// Config of delivery attempts
+type Config struct {
+	MaxAttempts  int           //attempts of one delivery
+	MinBackoff   time.Duration //delay before the first retry, doubled for each next one
+	MaxBackoff   time.Duration
+	Timeout      time.Duration //of one attempt
+	DisableAfter int           //consecutive failed attempts which disable webhook
+	PollInterval time.Duration //how often due retries are checked
+	Workers      int           //concurrent attempts
+	Lease        time.Duration //how long claimed deliveries are hidden from other instances
+	CacheTTL     time.Duration //how long webhooks of other instances may be stale, 0 disables the cache
+}
+
+// Dispatcher stores deliveries of events and sends them to webhooks
+type Dispatcher struct {
+	storage storage.Storage
+	client  *http.Client
+	config  Config
+	wake    chan struct{}
+
+	cacheMu  sync.Mutex
+	cached   []storage.Webhook
+	cachedAt time.Time
+}
+
+func New(storage storage.Storage, client *http.Client, config Config) *Dispatcher {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	//the whole claimed batch must be attempted before its lease expires
+	if min := batchRounds * config.Timeout; config.Lease < min {
+		config.Lease = min
+	}
+	if config.Lease <= 0 {
+		config.Lease = time.Minute
+	}
+
+	return &Dispatcher{
+		storage: storage,
+		client:  client,
+		config:  config,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// NewClient returns HTTP client for deliveries. Redirects are not followed, they are failed attempts.
+func NewClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// SendEvent stores a delivery of event for every matching webhook, they are sent by Run
+func (d *Dispatcher) SendEvent(event events.Event) error {
+	const fn = "Dispatcher.SendEvent"
+
+	//events are sent after commit, so storing deliveries must not depend on the request context
+	ctx := context.Background()
+
+	webhooks, err := d.webhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode payload: %w", fn, err)
+	}
+	wallets := eventWallets(payload)
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s failed to encode event: %w", fn, err)
+	}
+
+	queued := false
+	for i := range webhooks {
+		if !matches(&webhooks[i], event.Type, wallets) {
+			continue
+		}
+
+		err := d.storage.SaveDelivery(ctx, newDelivery(webhooks[i].ID, event.Type, body))
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			continue //deleted meanwhile
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+		queued = true
+	}
+
+	if queued {
+		d.wakeUp()
+	}
+
+	return nil
+}
+
+// Forward returns publisher which queues webhook deliveries and then sends event to next.
+// Webhooks must not fail wallet operations, so their errors are only logged.
+func (d *Dispatcher) Forward(next publisher.Publisher) publisher.Publisher {
+	return &forwarder{dispatcher: d, next: next}
+}
+
+type forwarder struct {
+	dispatcher *Dispatcher
+	next       publisher.Publisher
+}
+
+func (f *forwarder) SendEvent(event events.Event) error {
+	if err := f.dispatcher.SendEvent(event); err != nil {
+		slog.Error("Can't queue webhook deliveries", slog.String("event", event.Type), slog.String("error", err.Error()))
+	}
+
+	return f.next.SendEvent(event)
+}
+
+func (f *forwarder) Close() error {
+	return f.next.Close()
+}
+
+// webhooks returns all webhooks, they are listed at most once per CacheTTL
+func (d *Dispatcher) webhooks(ctx context.Context) ([]storage.Webhook, error) {
+	if d.config.CacheTTL <= 0 {
+		return d.storage.ListWebhooks(ctx)
+	}
+
+	d.cacheMu.Lock()
+	defer d.cacheMu.Unlock()
+
+	if d.cached != nil && time.Since(d.cachedAt) < d.config.CacheTTL {
+		return d.cached, nil
+	}
+
+	webhooks, err := d.storage.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []storage.Webhook{}
+	}
+
+	d.cached = webhooks
+	d.cachedAt = time.Now()
+
+	return webhooks, nil
+}
+
+// invalidate drops cached webhooks after they are changed by this instance
+func (d *Dispatcher) invalidate() {
+	d.cacheMu.Lock()
+	d.cached = nil
+	d.cacheMu.Unlock()
+}
+
+func (d *Dispatcher) wakeUp() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Webhook deliveries failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// RunOnce attempts all deliveries which are due now.
+// Deliveries are claimed for Lease, so instances sharing the storage don't send them twice.
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	const fn = "Dispatcher.RunOnce"
+
+	batch := d.config.Workers * batchRounds
+
+	for ctx.Err() == nil {
+		now := time.Now().UTC()
+		due, err := d.storage.ClaimDeliveries(ctx, now, now.Add(d.config.Lease), batch)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+
+		sem := make(chan struct{}, d.config.Workers)
+		var wg sync.WaitGroup
+
+		for i := range due {
+			sem <- struct{}{}
+			wg.Add(1)
+
+			go func(delivery *storage.Delivery) {
+				defer func() { <-sem; wg.Done() }()
+
+				if err := d.attempt(ctx, delivery); err != nil {
+					slog.Error("Webhook delivery failed", slog.String("delivery", delivery.ID), slog.String("error", err.Error()))
+				}
+			}(&due[i])
+		}
+		wg.Wait()
+
+		if len(due) < batch {
+			return nil
+		}
+	}
+
+	return ctx.Err()
+}
+
+// attempt sends delivery once and records the result in delivery and its webhook
+func (d *Dispatcher) attempt(ctx context.Context, delivery *storage.Delivery) error {
+	const fn = "Dispatcher.attempt"
+
+	webhook, err := d.storage.GetWebhook(ctx, delivery.WebhookID)
+	if errors.Is(err, storage.ErrWebhookNotFound) {
+		return nil //deleted along with its deliveries
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	now := time.Now().UTC()
+	delivery.UpdatedAt = now
+
+	if webhook.Status != storage.WebhookActive {
+		delivery.Status = storage.DeliveryFailed
+		delivery.Error = ErrWebhookDisabled.Error()
+		return d.saveDelivery(ctx, fn, delivery)
+	}
+
+	status, sendErr := d.send(ctx, webhook, delivery, now)
+	if sendErr != nil && ctx.Err() != nil {
+		return nil //shutdown, the attempt is repeated when the lease expires
+	}
+
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.Error = ""
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = storage.DeliverySucceeded
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = storage.DeliveryFailed
+		delivery.Error = truncate(sendErr.Error())
+	default:
+		delivery.Error = truncate(sendErr.Error())
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	if err := d.saveDelivery(ctx, fn, delivery); err != nil {
+		return err
+	}
+
+	return d.recordResult(ctx, delivery.WebhookID, sendErr == nil)
+}
+
+func (d *Dispatcher) saveDelivery(ctx context.Context, fn string, delivery *storage.Delivery) error {
+	err := d.storage.SaveDelivery(ctx, delivery)
+	if err != nil && !errors.Is(err, storage.ErrWebhookNotFound) {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+// recordResult resets failures of webhook on success and disables webhook which keeps failing
+func (d *Dispatcher) recordResult(ctx context.Context, webhookID string, ok bool) error {
+	const fn = "Dispatcher.recordResult"
+
+	if ok {
+		if err := d.storage.ResetWebhookFailures(ctx, webhookID); err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+		return nil
+	}
+
+	//counted by storage, instances sharing it may fail attempts of one webhook at once
+	webhook, err := d.storage.AddWebhookFailure(ctx, webhookID, d.config.DisableAfter)
+	if errors.Is(err, storage.ErrWebhookNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if d.config.DisableAfter > 0 && webhook.Failures == d.config.DisableAfter {
+		d.invalidate()
+		slog.Warn("Webhook disabled after failed deliveries", slog.String("webhook", webhook.ID), slog.Int("failures", webhook.Failures))
+	}
+
+	return nil
+}
+
+// send posts delivery payload to webhook, it returns response status and error of failed attempt
+func (d *Dispatcher) send(ctx context.Context, webhook *storage.Webhook, delivery *storage.Delivery, now time.Time) (int, error) {
+	if d.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wallet-webhooks")
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, delivery.Payload))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookHeader, webhook.ID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns delay before the next attempt after the given number of attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.MinBackoff << (attempts - 1)
+	if delay <= 0 || (d.config.MaxBackoff > 0 && delay > d.config.MaxBackoff) {
+		delay = d.config.MaxBackoff
+	}
+	return delay
+}
+
+// Sign returns signature header value of body sent at timestamp.
+// Receivers compute it with their copy of the secret and compare with hmac.Equal.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func truncate(msg string) string {
+	if len(msg) > maxErrorLength {
+		return msg[:maxErrorLength]
+	}
+	return msg
+}
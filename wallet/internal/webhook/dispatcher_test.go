@@ -0,0 +1,394 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"events"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/storage"
+	"wallet/internal/storage/memory"
+)
+
+const testSecret = "0123456789abcdef"
+
+// receiver records deliveries and responds with status
+type receiver struct {
+	mu         sync.Mutex
+	status     int
+	deliveries []string //X-Wallet-Delivery of received requests
+	bad        int      //requests with invalid signature
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	timestamp, _ := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !hmac.Equal([]byte(req.Header.Get(SignatureHeader)), []byte(Sign(testSecret, timestamp, body))) {
+		r.bad++
+	}
+	r.deliveries = append(r.deliveries, req.Header.Get(DeliveryHeader))
+
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.deliveries...)
+}
+
+func newTestDispatcher(s storage.Storage, config Config) *Dispatcher {
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = 1
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	return New(s, NewClient(), config)
+}
+
+func createWebhook(t *testing.T, d *Dispatcher, url string) *storage.Webhook {
+	t.Helper()
+
+	webhook, err := d.Create(context.Background(), Subscription{URL: url, Secret: testSecret})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return webhook
+}
+
+func sendEvents(t *testing.T, d *Dispatcher, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		event := events.New(events.EventWalletDeposited, "wallet-1", "", events.WalletDepositedPayload{ID: "wallet-1", Amount: 1})
+		if err := d.SendEvent(event); err != nil {
+			t.Fatalf("SendEvent: %v", err)
+		}
+	}
+}
+
+func runOnce(t *testing.T, d *Dispatcher) {
+	t.Helper()
+
+	if err := d.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"event-1"}`)
+
+	signature := Sign(testSecret, 1700000000, body)
+	if len(signature) != len(signaturePrefix)+64 || signature[:len(signaturePrefix)] != signaturePrefix {
+		t.Fatalf("Sign = %q, want sha256=<64 hex digits>", signature)
+	}
+	if Sign(testSecret, 1700000000, body) != signature {
+		t.Errorf("Sign is not deterministic")
+	}
+
+	for name, other := range map[string]string{
+		"timestamp": Sign(testSecret, 1700000001, body),
+		"body":      Sign(testSecret, 1700000000, []byte(`{"id":"event-2"}`)),
+		"secret":    Sign("fedcba9876543210", 1700000000, body),
+	} {
+		if other == signature {
+			t.Errorf("signature doesn't depend on %s", name)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := New(memory.New(), nil, Config{MinBackoff: 10 * time.Second, MaxBackoff: time.Minute})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{10, time.Minute},
+		{100, time.Minute}, //shift overflow
+	}
+
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := context.Background()
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s := memory.New()
+	d := newTestDispatcher(s, Config{})
+	webhook := createWebhook(t, d, srv.URL)
+
+	sendEvents(t, d, 2)
+	runOnce(t, d)
+
+	if got := rcv.received(); len(got) != 2 || rcv.bad != 0 {
+		t.Fatalf("received %d deliveries, %d with bad signature, want 2 signed", len(got), rcv.bad)
+	}
+
+	deliveries, err := d.Deliveries(ctx, webhook.ID, 0)
+	if err != nil {
+		t.Fatalf("Deliveries: %v", err)
+	}
+	for _, delivery := range deliveries {
+		if delivery.Status != storage.DeliverySucceeded || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusOK {
+			t.Errorf("delivery = %+v, want succeeded with one attempt", delivery)
+		}
+	}
+
+	//delivered events are not sent again
+	runOnce(t, d)
+	if got := rcv.received(); len(got) != 2 {
+		t.Errorf("received %d deliveries after the second run, want 2", len(got))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	rcv := &receiver{status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s := memory.New()
+	d := newTestDispatcher(s, Config{MaxAttempts: 3, MinBackoff: time.Hour, MaxBackoff: time.Hour})
+	webhook := createWebhook(t, d, srv.URL)
+
+	sendEvents(t, d, 1)
+	runOnce(t, d)
+
+	deliveries, err := d.Deliveries(ctx, webhook.ID, 0)
+	if err != nil {
+		t.Fatalf("Deliveries: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+
+	delivery := deliveries[0]
+	if delivery.Status != storage.DeliveryPending || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusServiceUnavailable {
+		t.Errorf("delivery = %+v, want pending after one attempt", delivery)
+	}
+	if wait := time.Until(delivery.NextAttemptAt); wait < 59*time.Minute {
+		t.Errorf("next attempt in %v, want after backoff of 1h", wait)
+	}
+
+	//the retry is not due yet
+	runOnce(t, d)
+	if got := rcv.received(); len(got) != 1 {
+		t.Errorf("received %d attempts, want 1", len(got))
+	}
+}
+
+func TestDisableAfter(t *testing.T) {
+	ctx := context.Background()
+	rcv := &receiver{status: http.StatusInternalServerError}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s := memory.New()
+	d := newTestDispatcher(s, Config{DisableAfter: 3, CacheTTL: time.Hour})
+	webhook := createWebhook(t, d, srv.URL)
+
+	sendEvents(t, d, 2)
+	runOnce(t, d)
+
+	if got, err := d.Get(ctx, webhook.ID); err != nil || got.Status != storage.WebhookActive || got.Failures != 2 {
+		t.Fatalf("webhook after 2 failures = %+v, %v, want active", got, err)
+	}
+
+	sendEvents(t, d, 1)
+	runOnce(t, d)
+
+	got, err := d.Get(ctx, webhook.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != storage.WebhookDisabled || got.Failures != 3 {
+		t.Fatalf("webhook after 3 failures = %+v, want disabled", got)
+	}
+
+	//disabled webhook gets no new deliveries despite the cached list
+	sendEvents(t, d, 1)
+	runOnce(t, d)
+	if got := rcv.received(); len(got) != 3 {
+		t.Errorf("received %d deliveries, want 3", len(got))
+	}
+
+	//enabling resets failures
+	enabled := true
+	got, err = d.Update(ctx, webhook.ID, Patch{Enabled: &enabled})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got.Status != storage.WebhookActive || got.Failures != 0 {
+		t.Errorf("enabled webhook = %+v, want active without failures", got)
+	}
+}
+
+func TestSuccessResetsFailures(t *testing.T) {
+	ctx := context.Background()
+	rcv := &receiver{status: http.StatusBadGateway}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s := memory.New()
+	d := newTestDispatcher(s, Config{DisableAfter: 3})
+	webhook := createWebhook(t, d, srv.URL)
+
+	sendEvents(t, d, 2)
+	runOnce(t, d)
+
+	rcv.mu.Lock()
+	rcv.status = http.StatusNoContent
+	rcv.mu.Unlock()
+
+	sendEvents(t, d, 1)
+	runOnce(t, d)
+
+	if got, err := d.Get(ctx, webhook.ID); err != nil || got.Status != storage.WebhookActive || got.Failures != 0 {
+		t.Errorf("webhook after success = %+v, %v, want active without failures", got, err)
+	}
+}
+
+func TestRedirectFails(t *testing.T) {
+	ctx := context.Background()
+	rcv := &receiver{}
+	target := httptest.NewServer(rcv)
+	defer target.Close()
+
+	srv := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer srv.Close()
+
+	s := memory.New()
+	d := newTestDispatcher(s, Config{})
+	webhook := createWebhook(t, d, srv.URL)
+
+	sendEvents(t, d, 1)
+	runOnce(t, d)
+
+	if got := rcv.received(); len(got) != 0 {
+		t.Errorf("redirect was followed to %d deliveries", len(got))
+	}
+
+	deliveries, err := d.Deliveries(ctx, webhook.ID, 0)
+	if err != nil {
+		t.Fatalf("Deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != storage.DeliveryFailed || deliveries[0].ResponseStatus != http.StatusFound {
+		t.Errorf("deliveries = %+v, want one failed with status 302", deliveries)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s := memory.New()
+	d := newTestDispatcher(s, Config{})
+	webhook := createWebhook(t, d, srv.URL)
+
+	sendEvents(t, d, 1)
+	runOnce(t, d)
+
+	deliveries, err := d.Deliveries(ctx, webhook.ID, 0)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("Deliveries = %+v, %v, want one", deliveries, err)
+	}
+	original := deliveries[0]
+
+	replay, err := d.Replay(ctx, webhook.ID, original.ID)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if replay.ID == original.ID || replay.ReplayOf != original.ID || string(replay.Payload) != string(original.Payload) {
+		t.Errorf("replay = %+v, want new delivery of %s", replay, original.ID)
+	}
+
+	runOnce(t, d)
+
+	got := rcv.received()
+	if len(got) != 2 || got[0] != original.ID || got[1] != replay.ID {
+		t.Errorf("received %q, want %s, %s", got, original.ID, replay.ID)
+	}
+
+	if _, err := d.Replay(ctx, webhook.ID, "missing"); !errors.Is(err, storage.ErrDeliveryNotFound) {
+		t.Errorf("Replay of unknown delivery: got %v, want %v", err, storage.ErrDeliveryNotFound)
+	}
+
+	disabled := false
+	if _, err := d.Update(ctx, webhook.ID, Patch{Enabled: &disabled}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := d.Replay(ctx, webhook.ID, original.ID); !errors.Is(err, ErrWebhookDisabled) {
+		t.Errorf("Replay of disabled webhook: got %v, want %v", err, ErrWebhookDisabled)
+	}
+}
+
+// TestClaim checks that dispatchers sharing the storage send every delivery once
+func TestClaim(t *testing.T) {
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s := memory.New()
+	dispatchers := []*Dispatcher{
+		newTestDispatcher(s, Config{Workers: 2}),
+		newTestDispatcher(s, Config{Workers: 2}),
+		newTestDispatcher(s, Config{Workers: 2}),
+	}
+	createWebhook(t, dispatchers[0], srv.URL)
+
+	sendEvents(t, dispatchers[0], 50)
+
+	var wg sync.WaitGroup
+	for _, d := range dispatchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.RunOnce(context.Background()); err != nil {
+				t.Errorf("RunOnce: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, id := range rcv.received() {
+		if seen[id] {
+			t.Errorf("delivery %s is sent twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("received %d deliveries, want 50", len(seen))
+	}
+}
@@ -0,0 +1,282 @@
+// Package webhook pushes wallet events to partner endpoints.
+//
+// Every event sent by the service is stored as a delivery of each matching webhook and POSTed
+// to the webhook URL by the dispatcher. Requests are signed with HMAC-SHA256 of the webhook secret,
+// failed attempts are retried with exponential backoff and webhooks which keep failing are disabled.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"events"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+	"wallet/internal/domain"
+	"wallet/internal/storage"
+	"wallet/internal/utils/random"
+)
+
+const (
+	ID_LENGTH = 16
+	//MinSecretLength is a minimal length of secrets chosen by partners
+	MinSecretLength = 16
+	//MaxDeliveries limits the delivery log page
+	MaxDeliveries = 100
+)
+
+// EventTypes are events webhooks may subscribe to
+var EventTypes = events.Types
+
+var (
+	ErrInvalidURL       = domain.New(domain.ErrInvalidArgument, "invalid_webhook_url", "webhook URL must be an absolute http or https URL")
+	ErrUnknownEventType = domain.New(domain.ErrInvalidArgument, "unknown_event_type", "unknown event type")
+	ErrShortSecret      = domain.Errorf(domain.ErrInvalidArgument, "invalid_webhook_secret", "webhook secret must be at least %d characters long", MinSecretLength)
+	ErrWebhookDisabled  = domain.New(domain.ErrInactive, "webhook_disabled", "webhook is disabled, enable it to replay deliveries")
+)
+
+// Subscription describes a new webhook. Secret is generated when empty.
+type Subscription struct {
+	URL        string
+	EventTypes []string
+	WalletIDs  []string
+	Secret     string
+}
+
+// Patch changes webhook, nil fields are kept.
+// Enabling the webhook resets its failures.
+type Patch struct {
+	URL        *string
+	EventTypes *[]string
+	WalletIDs  *[]string
+	Secret     *string
+	Enabled    *bool
+}
+
+// Create subscribes webhook to events
+func (d *Dispatcher) Create(ctx context.Context, sub Subscription) (*storage.Webhook, error) {
+	const fn = "Dispatcher.Create"
+
+	if sub.Secret == "" {
+		sub.Secret = newSecret()
+	}
+
+	now := time.Now().UTC()
+	webhook := &storage.Webhook{
+		ID:         random.NewRandomString(ID_LENGTH),
+		URL:        sub.URL,
+		EventTypes: sub.EventTypes,
+		WalletIDs:  sub.WalletIDs,
+		Secret:     sub.Secret,
+		Status:     storage.WebhookActive,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := validate(webhook); err != nil {
+		return nil, err
+	}
+
+	if err := d.storage.SaveWebhook(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	d.invalidate()
+
+	return webhook, nil
+}
+
+func (d *Dispatcher) Get(ctx context.Context, webhookID string) (*storage.Webhook, error) {
+	return d.storage.GetWebhook(ctx, webhookID)
+}
+
+func (d *Dispatcher) List(ctx context.Context) ([]storage.Webhook, error) {
+	return d.storage.ListWebhooks(ctx)
+}
+
+func (d *Dispatcher) Update(ctx context.Context, webhookID string, patch Patch) (*storage.Webhook, error) {
+	const fn = "Dispatcher.Update"
+
+	//failures counted by a concurrent attempt may be overwritten, that only delays disabling the webhook
+	webhook, err := d.storage.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.URL != nil {
+		webhook.URL = *patch.URL
+	}
+	if patch.EventTypes != nil {
+		webhook.EventTypes = *patch.EventTypes
+	}
+	if patch.WalletIDs != nil {
+		webhook.WalletIDs = *patch.WalletIDs
+	}
+	if patch.Secret != nil {
+		webhook.Secret = *patch.Secret
+	}
+	if patch.Enabled != nil {
+		if *patch.Enabled {
+			webhook.Status = storage.WebhookActive
+			webhook.Failures = 0
+		} else {
+			webhook.Status = storage.WebhookDisabled
+		}
+	}
+	if err := validate(webhook); err != nil {
+		return nil, err
+	}
+
+	webhook.UpdatedAt = time.Now().UTC()
+
+	if err := d.storage.SaveWebhook(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	d.invalidate()
+
+	return webhook, nil
+}
+
+// Delete unsubscribes webhook, its delivery log is deleted as well
+func (d *Dispatcher) Delete(ctx context.Context, webhookID string) error {
+	if err := d.storage.DeleteWebhook(ctx, webhookID); err != nil {
+		return err
+	}
+	d.invalidate()
+
+	return nil
+}
+
+// Deliveries returns at most limit latest deliveries of webhook, newest first
+func (d *Dispatcher) Deliveries(ctx context.Context, webhookID string, limit int) ([]storage.Delivery, error) {
+	if _, err := d.storage.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > MaxDeliveries {
+		limit = MaxDeliveries
+	}
+
+	return d.storage.ListDeliveries(ctx, webhookID, limit)
+}
+
+// Replay sends the payload of delivery once again as a new delivery
+func (d *Dispatcher) Replay(ctx context.Context, webhookID, deliveryID string) (*storage.Delivery, error) {
+	const fn = "Dispatcher.Replay"
+
+	webhook, err := d.storage.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.Status != storage.WebhookActive {
+		return nil, ErrWebhookDisabled
+	}
+
+	original, err := d.storage.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := newDelivery(webhookID, original.EventType, original.Payload)
+	delivery.ReplayOf = original.ID
+
+	if err := d.storage.SaveDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	d.wakeUp()
+
+	return delivery, nil
+}
+
+func newDelivery(webhookID, eventType string, payload json.RawMessage) *storage.Delivery {
+	now := time.Now().UTC()
+
+	return &storage.Delivery{
+		ID:            random.NewRandomString(ID_LENGTH),
+		WebhookID:     webhookID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        storage.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+func validate(webhook *storage.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ErrInvalidURL
+	}
+
+	for _, eventType := range webhook.EventTypes {
+		if !slices.Contains(EventTypes, eventType) {
+			return domain.Errorf(domain.ErrInvalidArgument, ErrUnknownEventType.Code(), "unknown event type %q", eventType)
+		}
+	}
+
+	if len(webhook.Secret) < MinSecretLength {
+		return ErrShortSecret
+	}
+
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+	if webhook.WalletIDs == nil {
+		webhook.WalletIDs = []string{}
+	}
+
+	return nil
+}
+
+// matches reports whether webhook is subscribed to event of wallets.
+// Events without wallets, such as Ledger_Mismatch, match only webhooks without wallet filter.
+func matches(webhook *storage.Webhook, eventType string, walletIDs []string) bool {
+	if webhook.Status != storage.WebhookActive {
+		return false
+	}
+	if len(webhook.EventTypes) > 0 && !slices.Contains(webhook.EventTypes, eventType) {
+		return false
+	}
+	if len(webhook.WalletIDs) == 0 {
+		return true
+	}
+
+	for _, id := range walletIDs {
+		if slices.Contains(webhook.WalletIDs, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eventWallets returns IDs of wallets the event payload is about
+func eventWallets(payload json.RawMessage) []string {
+	var wallets struct {
+		ID         string `json:"id"`
+		TransferTo string `json:"transfer_to"`
+	}
+	if err := json.Unmarshal(payload, &wallets); err != nil {
+		return nil
+	}
+
+	var ids []string
+	for _, id := range []string{wallets.ID, wallets.TransferTo} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func newSecret() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,304 @@
+// Package client is a Go client of the wallet service HTTP API.
+//
+// Every method takes a context, which limits the call including retries.
+// Failed calls return *Error, which matches kind errors such as ErrNotFound or ErrInsufficientFunds with errors.Is.
+// Mutating requests are sent with Idempotency-Key header, so a retry gets the stored response of the request
+// instead of applying it again. Responses are kept in memory of the server instance for a limited time:
+// a retry which reaches another instance, or comes after a restart, may be applied twice.
+package client
+
+import (
+	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	userAgent         = "wallet-go-client"
+)
+
+// RetryPolicy configures retries of failed calls. Calls are retried on network errors,
+// 429, 502, 503, 504 responses and conflicts of concurrent updates.
+type RetryPolicy struct {
+	MaxAttempts int           //1 disables retries
+	MinBackoff  time.Duration //delay before the first retry, doubled for each next one
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, MinBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+// Client calls the wallet service. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retry      RetryPolicy
+	userAgent  string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets HTTP client used for calls, http.DefaultClient by default
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithUserAgent sets User-Agent header of requests
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New returns client of the service at baseURL, e.g. http://localhost:8081
+func New(baseURL string, opts ...Option) (*Client, error) {
+	const fn = "client.New"
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%s: base URL must be absolute: %q", fn, baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+		userAgent:  userAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+
+	return c, nil
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey sets key of the mutating call made with ctx.
+// By default every call gets a random key, set your own to make a call idempotent across process restarts.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// request describes a call. Body is either JSON encoded or streamed from reader.
+type request struct {
+	method      string
+	path        string //escaped
+	query       url.Values
+	body        []byte
+	reader      io.Reader
+	contentType string
+	accept      string
+}
+
+func jsonRequest(method, path string, body any) (*request, error) {
+	req := &request{method: method, path: path}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		req.body = data
+		req.contentType = "application/json"
+	}
+	return req, nil
+}
+
+// call sends request and decodes JSON response into out, when out is not nil
+func (c *Client) call(ctx context.Context, req *request, out any) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", req.method, req.path, err)
+	}
+
+	return nil
+}
+
+// do sends request with retries and returns successful response, its body must be closed
+func (c *Client) do(ctx context.Context, req *request) (*http.Response, error) {
+	key := ""
+	if req.method != http.MethodGet {
+		key, _ = ctx.Value(idempotencyKey{}).(string)
+		if key == "" {
+			key = newKey()
+		}
+	}
+
+	//streamed body can be sent again only when it can be rewound
+	attempts := c.retry.MaxAttempts
+	seeker, seekable := req.reader.(io.Seeker)
+	if req.reader != nil && !seekable {
+		attempts = 1
+	}
+
+	var lastErr error
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && seekable {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, lastErr
+			}
+		}
+
+		resp, err := c.send(ctx, req, key)
+		retryAfter := time.Duration(0)
+
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+		case resp.StatusCode < 300:
+			return resp, nil
+		default:
+			apiErr := readError(resp)
+			if !apiErr.temporary() {
+				return nil, apiErr
+			}
+			lastErr = apiErr
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		}
+
+		if attempt >= attempts {
+			return nil, lastErr
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req *request, key string) (*http.Response, error) {
+	u := *c.baseURL
+	u.RawPath = u.EscapedPath() + req.path
+	u.Path, _ = url.PathUnescape(u.RawPath)
+	u.RawQuery = req.query.Encode()
+
+	var body io.Reader
+	switch {
+	case req.reader != nil:
+		body = req.reader
+	case req.body != nil:
+		body = bytes.NewReader(req.body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if req.accept != "" {
+		httpReq.Header.Set("Accept", req.accept)
+	} else {
+		httpReq.Header.Set("Accept", "application/json")
+	}
+	if key != "" {
+		httpReq.Header.Set(idempotencyHeader, key)
+	}
+	httpReq.Header.Set("User-Agent", c.userAgent)
+
+	return c.httpClient.Do(httpReq)
+}
+
+// backoff returns exponential delay, randomized by half to spread retries of concurrent clients
+func (c *Client) backoff(attempt int) time.Duration {
+	if c.retry.MinBackoff <= 0 {
+		return 0
+	}
+
+	delay := c.retry.MinBackoff << (attempt - 1)
+	if delay <= 0 || (c.retry.MaxBackoff > 0 && delay > c.retry.MaxBackoff) {
+		delay = c.retry.MaxBackoff
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+func newKey() string {
+	var b [16]byte
+	if _, err := cryptorand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// timeParam formats non-zero time as RFC3339 query parameter
+func timeParam(query url.Values, name string, value time.Time) {
+	if !value.IsZero() {
+		query.Set(name, value.Format(time.RFC3339))
+	}
+}
+
+var errEmptyID = errors.New("wallet ID must not be empty")
+
+// walletPath returns escaped path of wallet resource, e.g. /wallets/{id}/deposit
+func walletPath(prefix, id string, suffix ...string) (string, error) {
+	if id == "" {
+		return "", errEmptyID
+	}
+	return prefix + "/" + url.PathEscape(id) + strings.Join(suffix, ""), nil
+}
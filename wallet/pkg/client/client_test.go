@@ -0,0 +1,406 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/accounting"
+	"wallet/internal/config"
+	"wallet/internal/http/idempotency"
+	"wallet/internal/http/openapi"
+	"wallet/internal/importer"
+	"wallet/internal/publisher"
+	"wallet/internal/reconcile"
+	chirouter "wallet/internal/router/chi"
+	"wallet/internal/service"
+	"wallet/internal/storage/memory"
+	"wallet/pkg/client"
+
+	"github.com/go-chi/chi"
+)
+
+// newRouter builds the router of app.Run on top of memory storage
+func newRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	storage := memory.New()
+	sender := publisher.NewMemory()
+	cfg := &config.Config{Currency: "USD"}
+
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("openapi.Load: %v", err)
+	}
+	validator, err := openapi.Validator(spec)
+	if err != nil {
+		t.Fatalf("openapi.Validator: %v", err)
+	}
+
+	router := chi.NewRouter()
+	chirouter.InitMiddleware(router, validator, idempotency.New(time.Hour, 0))
+	chirouter.InitWallet(router, service.New(storage, sender, nil), cfg)
+	chirouter.InitExport(router, accounting.NewExporter(storage, accounting.Accounts{WalletPrefix: "Assets:Wallets"}, cfg.Currency), cfg)
+	chirouter.InitImport(router, importer.New(storage, sender, nil, 100))
+	chirouter.InitReconcile(router, reconcile.New(storage, sender, 1e-9))
+	chirouter.InitOpenAPI(router, spec)
+
+	return router
+}
+
+func newClient(t *testing.T, handler http.Handler, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	opts = append([]client.Option{
+		client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}),
+	}, opts...)
+
+	c, err := client.New(srv.URL, opts...)
+	if err != nil {
+		t.Fatalf("client.New: %v", err)
+	}
+
+	return c
+}
+
+func TestWalletLifecycle(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newRouter(t))
+
+	alice, err := c.CreateWallet(ctx, "alice")
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	if alice.ID == "" || alice.Name != "alice" || alice.Status != client.StatusActive {
+		t.Fatalf("CreateWallet = %+v", alice)
+	}
+	bob, err := c.CreateWallet(ctx, "bob")
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	if err := c.Deposit(ctx, alice.ID, 100); err != nil {
+		t.Fatalf("Deposit: %v", err)
+	}
+	if err := c.Withdraw(ctx, alice.ID, 30); err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+	if err := c.Transfer(ctx, alice.ID, bob.ID, 20); err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	got, err := c.GetWallet(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+	if got.Balance != 50 {
+		t.Errorf("alice balance = %v, want 50", got.Balance)
+	}
+
+	balance, err := c.Balance(ctx, bob.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("Balance: %v", err)
+	}
+	if balance.Balance != 20 {
+		t.Errorf("bob balance = %v, want 20", balance.Balance)
+	}
+
+	balances, err := c.Balances(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("Balances: %v", err)
+	}
+	if len(balances) != 2 {
+		t.Errorf("Balances returned %d wallets, want 2", len(balances))
+	}
+
+	if err := c.RenameWallet(ctx, bob.ID, "robert"); err != nil {
+		t.Fatalf("RenameWallet: %v", err)
+	}
+
+	if _, err := c.PatchMetadata(ctx, alice.ID, map[string]any{"tier": "gold"}); err != nil {
+		t.Fatalf("PatchMetadata: %v", err)
+	}
+	tagged, err := c.PatchTags(ctx, alice.ID, []string{"VIP", "eu"}, nil)
+	if err != nil {
+		t.Fatalf("PatchTags: %v", err)
+	}
+	if strings.Join(tagged.Tags, ",") != "eu,vip" {
+		t.Errorf("tags = %v, want [eu vip]", tagged.Tags)
+	}
+
+	page, err := c.ListWallets(ctx, client.ListOptions{
+		Tags:      []string{"vip"},
+		Metadata:  map[string]string{"tier": "gold"},
+		Sort:      client.SortByName,
+		WithTotal: true,
+	})
+	if err != nil {
+		t.Fatalf("ListWallets: %v", err)
+	}
+	if len(page.Wallets) != 1 || page.Wallets[0].ID != alice.ID || page.Total == nil || *page.Total != 1 {
+		t.Errorf("ListWallets = %+v", page)
+	}
+
+	page, err = c.ListWallets(ctx, client.ListOptions{Sort: client.SortByName, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListWallets: %v", err)
+	}
+	if len(page.Wallets) != 1 || page.Wallets[0].Name != "alice" || page.NextCursor == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+	page, err = c.ListWallets(ctx, client.ListOptions{Sort: client.SortByName, Limit: 1, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("ListWallets: %v", err)
+	}
+	if len(page.Wallets) != 1 || page.Wallets[0].Name != "robert" {
+		t.Errorf("second page = %+v", page)
+	}
+
+	if err := c.DeactivateWallet(ctx, bob.ID); err != nil {
+		t.Fatalf("DeactivateWallet: %v", err)
+	}
+	if _, err := c.GetWallet(ctx, bob.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetWallet of inactive wallet error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newRouter(t))
+
+	wallet, err := c.CreateWallet(ctx, "alice")
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	err = c.Withdraw(ctx, wallet.ID, 10)
+	if !errors.Is(err, client.ErrInsufficientFunds) {
+		t.Fatalf("Withdraw error = %v, want ErrInsufficientFunds", err)
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Withdraw error %T is not *client.Error", err)
+	}
+	if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Code != "insufficient_funds" || apiErr.RequestID == "" {
+		t.Errorf("Withdraw error = %+v", apiErr)
+	}
+
+	if _, err := c.CreateWallet(ctx, "alice"); !errors.Is(err, client.ErrExists) {
+		t.Errorf("CreateWallet of taken name error = %v, want ErrExists", err)
+	}
+
+	if err := c.Deposit(ctx, "missing", 10); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Deposit to missing wallet error = %v, want ErrNotFound", err)
+	}
+
+	err = c.Deposit(ctx, wallet.ID, -5)
+	if !errors.Is(err, client.ErrInvalidArgument) {
+		t.Fatalf("Deposit of negative amount error = %v, want ErrInvalidArgument", err)
+	}
+	if !errors.As(err, &apiErr) || len(apiErr.Fields) == 0 || apiErr.Fields[0].Field != "amount" {
+		t.Errorf("Deposit of negative amount fields = %+v", apiErr.Fields)
+	}
+
+	if _, err := c.ListWallets(ctx, client.ListOptions{Cursor: "garbage"}); !errors.Is(err, client.ErrInvalidArgument) {
+		t.Errorf("ListWallets with invalid cursor error = %v, want ErrInvalidArgument", err)
+	}
+
+	if err := c.DeactivateWallet(ctx, wallet.ID); err != nil {
+		t.Fatalf("DeactivateWallet: %v", err)
+	}
+	if err := c.Deposit(ctx, wallet.ID, 10); !errors.Is(err, client.ErrInactive) {
+		t.Errorf("Deposit to inactive wallet error = %v, want ErrInactive", err)
+	}
+
+	if _, err := c.LatestReconciliation(ctx); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("LatestReconciliation error = %v, want ErrNotFound", err)
+	}
+}
+
+// flaky serves the first matching request and then drops its response, as if the connection was lost
+type flaky struct {
+	next  http.Handler
+	match string
+
+	mu       sync.Mutex
+	failed   bool
+	requests []*http.Request
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r)
+	fail := !f.failed && r.URL.Path == f.match
+	f.failed = f.failed || fail
+	f.mu.Unlock()
+
+	if !fail {
+		f.next.ServeHTTP(w, r)
+		return
+	}
+
+	f.next.ServeHTTP(httptest.NewRecorder(), r)
+	w.WriteHeader(http.StatusServiceUnavailable)
+}
+
+func (f *flaky) keys(path string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var keys []string
+	for _, r := range f.requests {
+		if r.URL.Path == path {
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+		}
+	}
+	return keys
+}
+
+func TestRetryIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	router := newRouter(t)
+
+	setup := newClient(t, router)
+	wallet, err := setup.CreateWallet(ctx, "alice")
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	path := "/wallets/" + wallet.ID + "/deposit"
+	handler := &flaky{next: router, match: path}
+	c := newClient(t, handler)
+
+	if err := c.Deposit(ctx, wallet.ID, 25); err != nil {
+		t.Fatalf("Deposit: %v", err)
+	}
+
+	keys := handler.keys(path)
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("idempotency keys of attempts = %q, want two equal keys", keys)
+	}
+
+	got, err := c.GetWallet(ctx, wallet.ID)
+	if err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+	if got.Balance != 25 {
+		t.Errorf("balance = %v, want 25: retried deposit is applied twice", got.Balance)
+	}
+
+	//the same key with another body is rejected
+	keyed := client.WithIdempotencyKey(ctx, keys[0])
+	if err := c.Deposit(keyed, wallet.ID, 30); !errors.Is(err, client.ErrInvalidArgument) {
+		t.Errorf("Deposit reusing key error = %v, want ErrInvalidArgument", err)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts int
+	var mu sync.Mutex
+	unavailable := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	c := newClient(t, unavailable)
+	_, err := c.GetWallet(ctx, "any")
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("GetWallet error = %v, want 503 error", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+
+	attempts = 0
+	c = newClient(t, unavailable, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1}))
+	c.GetWallet(ctx, "any")
+	if attempts != 1 {
+		t.Errorf("attempts with retries disabled = %d, want 1", attempts)
+	}
+
+	//client errors are final
+	counted := &flaky{next: newRouter(t), failed: true}
+	c = newClient(t, counted)
+	if _, err := c.GetWallet(ctx, "missing"); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("GetWallet error = %v, want ErrNotFound", err)
+	}
+	if n := len(counted.keys("/wallets/missing")); n != 1 {
+		t.Errorf("attempts of not found request = %d, want 1", n)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	c := newClient(t, slow)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.GetWallet(ctx, "any"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetWallet error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestImportAndStreams(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newRouter(t))
+
+	data := []byte("name,opening_balance,opened_at\nalice,100,2024-01-01T00:00:00Z\nbob,50,2024-01-02T00:00:00Z\n")
+
+	report, err := c.ImportWallets(ctx, client.ImportCSV, bytes.NewReader(data), false)
+	if err != nil {
+		t.Fatalf("ImportWallets: %v", err)
+	}
+	if report.Created != 2 || len(report.Errors) != 0 {
+		t.Fatalf("ImportWallets report = %+v", report)
+	}
+
+	statement, err := c.Statement(ctx, report.Wallets[0].ID, client.PeriodOptions{Format: client.StatementCSV})
+	if err != nil {
+		t.Fatalf("Statement: %v", err)
+	}
+	body, err := io.ReadAll(statement)
+	statement.Close()
+	if err != nil {
+		t.Fatalf("read statement: %v", err)
+	}
+	if !strings.Contains(string(body), "opening_balance") {
+		t.Errorf("statement has no opening balance:\n%s", body)
+	}
+
+	journal, err := c.ExportJournal(ctx, client.PeriodOptions{Format: client.JournalBeancount})
+	if err != nil {
+		t.Fatalf("ExportJournal: %v", err)
+	}
+	body, err = io.ReadAll(journal)
+	journal.Close()
+	if err != nil {
+		t.Fatalf("read journal: %v", err)
+	}
+	if !strings.Contains(string(body), "Assets:Wallets") {
+		t.Errorf("journal has no wallet accounts:\n%s", body)
+	}
+
+	if _, err := c.Statement(ctx, "missing", client.PeriodOptions{}); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Statement of missing wallet error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,441 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Wallet statuses
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+	StatusAll      = "all" //ListOptions status of both active and inactive wallets
+)
+
+// Sort fields of ListOptions
+const (
+	SortByName      = "name"
+	SortByBalance   = "balance"
+	SortByCreatedAt = "created_at"
+)
+
+type Wallet struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name,omitempty"`
+	Balance   float64        `json:"balance,omitempty"`
+	Status    string         `json:"status,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Tags      []string       `json:"tags,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// WalletPage is a page of ListWallets, NextCursor is empty on the last page
+type WalletPage struct {
+	Wallets    []Wallet `json:"wallets"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	Total      *int     `json:"total,omitempty"`
+}
+
+// ListOptions filters and pages ListWallets. Zero values are not applied.
+type ListOptions struct {
+	Tags       []string          //wallet must have every tag
+	Metadata   map[string]string //metadata key must be equal to value
+	Status     string            //active by default, inactive or all
+	NamePrefix string
+	MinBalance *float64
+	MaxBalance *float64
+	Sort       string //created_at by default
+	Desc       bool
+	Limit      int
+	Cursor     string //NextCursor of the previous page
+	WithTotal  bool   //count all wallets matching filters
+}
+
+func (o ListOptions) values() url.Values {
+	query := url.Values{}
+	for _, tag := range o.Tags {
+		query.Add("tag", tag)
+	}
+	for key, value := range o.Metadata {
+		query.Set("meta."+key, value)
+	}
+	if o.Status != "" {
+		query.Set("status", o.Status)
+	}
+	if o.NamePrefix != "" {
+		query.Set("name_prefix", o.NamePrefix)
+	}
+	if o.MinBalance != nil {
+		query.Set("min_balance", strconv.FormatFloat(*o.MinBalance, 'f', -1, 64))
+	}
+	if o.MaxBalance != nil {
+		query.Set("max_balance", strconv.FormatFloat(*o.MaxBalance, 'f', -1, 64))
+	}
+	if o.Sort != "" {
+		query.Set("sort", o.Sort)
+	}
+	if o.Desc {
+		query.Set("order", "desc")
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		query.Set("cursor", o.Cursor)
+	}
+	if o.WithTotal {
+		query.Set("total", "true")
+	}
+	return query
+}
+
+// Balance is a wallet balance computed from journal at the moment At
+type Balance struct {
+	WalletID string    `json:"wallet_id"`
+	Name     string    `json:"name,omitempty"`
+	Balance  float64   `json:"balance"`
+	At       time.Time `json:"at"`
+}
+
+// result is a response of operations which don't return resources
+type result struct {
+	ID     string  `json:"id,omitempty"`
+	Name   string  `json:"name,omitempty"`
+	Status string  `json:"status,omitempty"`
+	Amount float64 `json:"amount,omitempty"`
+}
+
+// CreateWallet creates active wallet with zero balance
+func (c *Client) CreateWallet(ctx context.Context, name string) (*Wallet, error) {
+	req, err := jsonRequest(http.MethodPost, "/wallet", map[string]string{"name": name})
+	if err != nil {
+		return nil, err
+	}
+
+	var res result
+	if err := c.call(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	return &Wallet{ID: res.ID, Name: res.Name, Status: res.Status}, nil
+}
+
+// GetWallet returns active wallet, inactive ones are not found
+func (c *Client) GetWallet(ctx context.Context, id string) (*Wallet, error) {
+	path, err := walletPath("/wallets", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var wallet Wallet
+	if err := c.call(ctx, &request{method: http.MethodGet, path: path}, &wallet); err != nil {
+		return nil, err
+	}
+
+	return &wallet, nil
+}
+
+// ListWallets returns a page of wallets
+func (c *Client) ListWallets(ctx context.Context, opts ListOptions) (*WalletPage, error) {
+	var page WalletPage
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/wallets", query: opts.values()}, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// RenameWallet changes name of active wallet
+func (c *Client) RenameWallet(ctx context.Context, id, name string) error {
+	path, err := walletPath("/wallets", id)
+	if err != nil {
+		return err
+	}
+	req, err := jsonRequest(http.MethodPut, path, map[string]string{"name": name})
+	if err != nil {
+		return err
+	}
+
+	return c.call(ctx, req, nil)
+}
+
+// DeactivateWallet makes wallet inactive, its balance can't be changed anymore
+func (c *Client) DeactivateWallet(ctx context.Context, id string) error {
+	path, err := walletPath("/wallet", id)
+	if err != nil {
+		return err
+	}
+
+	return c.call(ctx, &request{method: http.MethodDelete, path: path}, nil)
+}
+
+// PatchMetadata merges patch into wallet metadata, keys with nil value are removed
+func (c *Client) PatchMetadata(ctx context.Context, id string, patch map[string]any) (*Wallet, error) {
+	path, err := walletPath("/wallets", id, "/metadata")
+	if err != nil {
+		return nil, err
+	}
+	req, err := jsonRequest(http.MethodPatch, path, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var wallet Wallet
+	if err := c.call(ctx, req, &wallet); err != nil {
+		return nil, err
+	}
+
+	return &wallet, nil
+}
+
+// PatchTags adds and removes wallet tags
+func (c *Client) PatchTags(ctx context.Context, id string, add, remove []string) (*Wallet, error) {
+	path, err := walletPath("/wallets", id, "/tags")
+	if err != nil {
+		return nil, err
+	}
+	req, err := jsonRequest(http.MethodPatch, path, struct {
+		Add    []string `json:"add,omitempty"`
+		Remove []string `json:"remove,omitempty"`
+	}{add, remove})
+	if err != nil {
+		return nil, err
+	}
+
+	var wallet Wallet
+	if err := c.call(ctx, req, &wallet); err != nil {
+		return nil, err
+	}
+
+	return &wallet, nil
+}
+
+// Deposit adds amount to wallet balance
+func (c *Client) Deposit(ctx context.Context, id string, amount float64) error {
+	return c.operation(ctx, id, "/deposit", map[string]any{"amount": amount})
+}
+
+// Withdraw subtracts amount from wallet balance
+func (c *Client) Withdraw(ctx context.Context, id string, amount float64) error {
+	return c.operation(ctx, id, "/withdraw", map[string]any{"amount": amount})
+}
+
+// Transfer moves amount from one wallet to another
+func (c *Client) Transfer(ctx context.Context, fromID, toID string, amount float64) error {
+	return c.operation(ctx, fromID, "/transfer", map[string]any{"amount": amount, "transfer_to": toID})
+}
+
+func (c *Client) operation(ctx context.Context, id, suffix string, body any) error {
+	path, err := walletPath("/wallets", id, suffix)
+	if err != nil {
+		return err
+	}
+	req, err := jsonRequest(http.MethodPost, path, body)
+	if err != nil {
+		return err
+	}
+
+	return c.call(ctx, req, nil)
+}
+
+// Balance returns wallet balance at the moment at, zero at means now
+func (c *Client) Balance(ctx context.Context, id string, at time.Time) (*Balance, error) {
+	path, err := walletPath("/wallets", id, "/balance")
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	timeParam(query, "at", at)
+
+	var balance Balance
+	if err := c.call(ctx, &request{method: http.MethodGet, path: path, query: query}, &balance); err != nil {
+		return nil, err
+	}
+
+	return &balance, nil
+}
+
+// Balances returns balances of all wallets existed at the moment at, zero at means now
+func (c *Client) Balances(ctx context.Context, at time.Time) ([]Balance, error) {
+	query := url.Values{}
+	timeParam(query, "at", at)
+
+	var balances []Balance
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/wallets/balances", query: query}, &balances); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// Statement formats
+const (
+	StatementJSON = "json"
+	StatementCSV  = "csv"
+	StatementOFX  = "ofx"
+)
+
+// PeriodOptions limits statement or journal to operations made after From up to and including To.
+// Zero From means the beginning, zero To means now.
+type PeriodOptions struct {
+	From   time.Time
+	To     time.Time
+	Format string
+}
+
+func (o PeriodOptions) values() url.Values {
+	query := url.Values{}
+	timeParam(query, "from", o.From)
+	timeParam(query, "to", o.To)
+	if o.Format != "" {
+		query.Set("format", o.Format)
+	}
+	return query
+}
+
+// Statement streams wallet statement, JSON by default. The caller must close returned reader.
+func (c *Client) Statement(ctx context.Context, id string, opts PeriodOptions) (io.ReadCloser, error) {
+	path, err := walletPath("/wallets", id, "/statement")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: path, query: opts.values(), accept: "*/*"})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Journal formats
+const (
+	JournalBeancount = "beancount"
+	JournalLedger    = "ledger"
+)
+
+// ExportJournal streams double-entry journal of all operations, beancount by default.
+// The caller must close returned reader.
+func (c *Client) ExportJournal(ctx context.Context, opts PeriodOptions) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: "/export/journal", query: opts.values(), accept: "text/plain"})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Import formats
+const (
+	ImportCSV   = "csv"
+	ImportJSONL = "jsonl"
+)
+
+type ImportedWallet struct {
+	Line    int     `json:"line"`
+	ID      string  `json:"id,omitempty"`
+	Name    string  `json:"name"`
+	Balance float64 `json:"balance"`
+}
+
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportFailedBatch is a batch of lines which was not loaded
+type ImportFailedBatch struct {
+	FirstLine int    `json:"first_line"`
+	LastLine  int    `json:"last_line"`
+	Error     string `json:"error"`
+}
+
+// ImportReport describes import result. Nothing is created when Errors is not empty.
+// When Failed is set, only the wallets listed in Wallets are created.
+type ImportReport struct {
+	DryRun       bool               `json:"dry_run"`
+	Total        int                `json:"total"`
+	Created      int                `json:"created"`
+	EventsFailed int                `json:"events_failed,omitempty"`
+	Wallets      []ImportedWallet   `json:"wallets,omitempty"`
+	Errors       []ImportRowError   `json:"errors,omitempty"`
+	Failed       *ImportFailedBatch `json:"failed,omitempty"`
+}
+
+// ImportWallets creates wallets from CSV or JSON Lines file.
+// The call is retried only when data implements io.Seeker, e.g. *os.File or *bytes.Reader.
+func (c *Client) ImportWallets(ctx context.Context, format string, data io.Reader, dryRun bool) (*ImportReport, error) {
+	contentType := "text/csv"
+	if format == ImportJSONL {
+		contentType = "application/jsonl"
+	}
+
+	query := url.Values{}
+	query.Set("format", format)
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+
+	req := &request{
+		method:      http.MethodPost,
+		path:        "/wallets/import",
+		query:       query,
+		reader:      data,
+		contentType: contentType,
+	}
+
+	var report ImportReport
+	if err := c.call(ctx, req, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+type Discrepancy struct {
+	Kind       string  `json:"kind"`
+	WalletID   string  `json:"wallet_id,omitempty"`
+	Name       string  `json:"name,omitempty"`
+	Expected   float64 `json:"expected"`
+	Actual     float64 `json:"actual"`
+	Difference float64 `json:"difference"`
+}
+
+// Reconciliation is a report of the ledger reconciliation job
+type Reconciliation struct {
+	ID               string        `json:"id"`
+	StartedAt        time.Time     `json:"started_at"`
+	FinishedAt       time.Time     `json:"finished_at"`
+	Wallets          int           `json:"wallets"`
+	TotalBalance     float64       `json:"total_balance"`
+	ExternalInflows  float64       `json:"external_inflows"`
+	ExternalOutflows float64       `json:"external_outflows"`
+	OK               bool          `json:"ok"`
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+}
+
+// LatestReconciliation returns the last reconciliation report, ErrNotFound before the first run
+func (c *Client) LatestReconciliation(ctx context.Context) (*Reconciliation, error) {
+	var report Reconciliation
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/reconciliation/latest"}, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// OpenAPI returns OpenAPI document of the service
+func (c *Client) OpenAPI(ctx context.Context) (map[string]any, error) {
+	var doc map[string]any
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/openapi.json"}, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
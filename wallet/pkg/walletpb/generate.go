@@ -0,0 +1,4 @@
+// Package walletpb contains protobuf messages and gRPC stubs of the wallet service generated from api/proto
+package walletpb
+
+//go:generate sh -c "cd ../.. && buf generate"